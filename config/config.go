@@ -1,14 +1,27 @@
 package config
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Infura   InfuraConfig   `mapstructure:"infura"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	Infura     InfuraConfig     `mapstructure:"infura"`
+	Backfill   BackfillConfig   `mapstructure:"backfill"`
+	Ethereum   EthereumConfig   `mapstructure:"ethereum"`
+	Pools      []PoolConfig     `mapstructure:"pools"`
+	Chains     []ChainConfig    `mapstructure:"chains"`
+	Source     SourceConfig     `mapstructure:"source"`
+	Volume     VolumeConfig     `mapstructure:"volume"`
+	Pipeline   PipelineConfig   `mapstructure:"pipeline"`
+	Campaign   CampaignConfig   `mapstructure:"campaign"`
+	Settlement SettlementConfig `mapstructure:"settlement"`
+	Admin      AdminConfig      `mapstructure:"admin"`
 }
 
 type ServerConfig struct {
@@ -34,6 +47,153 @@ type InfuraConfig struct {
 	Key string `mapstructure:"key"`
 }
 
+type BackfillConfig struct {
+	ChunkSize uint64 `mapstructure:"chunk_size"`
+}
+
+type EthereumConfig struct {
+	// any ws(s):// or http(s):// endpoint, http endpoints are polled with eth_getLogs
+	RPCURL       string        `mapstructure:"rpc_url"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// blocks a swap log has to be buried under before its volume is credited
+	Confirmations uint64 `mapstructure:"confirmations"`
+}
+
+// ChainConfig is one chain to ingest swaps from, each chain runs its own ingestion loop.
+type ChainConfig struct {
+	// EIP-155 chain ID, stored with every swap and checkpoint of the chain
+	ID   int64  `mapstructure:"id"`
+	Name string `mapstructure:"name"`
+	// any ws(s):// or http(s):// endpoint, http endpoints are polled with eth_getLogs
+	RPCURL       string        `mapstructure:"rpc_url"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// blocks a swap log has to be buried under before its volume is credited
+	Confirmations uint64 `mapstructure:"confirmations"`
+	// recorded logs of this chain, read when source.type is "replay"
+	ReplayFile string       `mapstructure:"replay_file"`
+	Pools      []PoolConfig `mapstructure:"pools"`
+}
+
+type PoolConfig struct {
+	Address string `mapstructure:"address"`
+	// optional, seed the token registry when given with their address, the registry resolves them
+	// on chain otherwise
+	Token0 TokenConfig `mapstructure:"token0"`
+	Token1 TokenConfig `mapstructure:"token1"`
+	// the quote stablecoin of the pair: "token0", "token1", or the symbol or address of the token
+	Quote string `mapstructure:"quote"`
+	// who is credited for a swap: "sender" (default), "recipient" or "origin" (transaction from)
+	Attribution string `mapstructure:"attribution"`
+}
+
+type TokenConfig struct {
+	Address  string `mapstructure:"address"`
+	Symbol   string `mapstructure:"symbol"`
+	Decimals int64  `mapstructure:"decimals"`
+}
+
+type SourceConfig struct {
+	// "rpc" (default) reads from the node, "replay" reads recorded logs from ReplayFile
+	Type       string `mapstructure:"type"`
+	ReplayFile string `mapstructure:"replay_file"`
+	// replay speed relative to real time, 0 replays as fast as possible
+	ReplaySpeed float64 `mapstructure:"replay_speed"`
+}
+
+type VolumeConfig struct {
+	// how a swap is turned into USD volume: "quote" (default), "both" or "input"
+	Accounting string `mapstructure:"accounting"`
+}
+
+type PipelineConfig struct {
+	// number of workers crediting decoded swaps, swaps of one address always go to the same worker
+	Workers int `mapstructure:"workers"`
+	// capacity of each worker's queue, the log reader blocks once it is full
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+type CampaignConfig struct {
+	// the campaign started by GET /campaign/start, the built in campaign when it declares no tasks
+	Definition CampaignDefinition `mapstructure:"definition"`
+}
+
+// CampaignDefinition declares the tasks of a campaign. Every task starts with the campaign and runs
+// its periods back to back, the campaign ends with its last task.
+type CampaignDefinition struct {
+	Name  string                   `mapstructure:"name"`
+	Tasks []CampaignTaskDefinition `mapstructure:"tasks"`
+	// the tracked pools whose swaps count, every tracked pool when empty
+	Pools []CampaignPoolDefinition `mapstructure:"pools"`
+}
+
+type CampaignPoolDefinition struct {
+	// chain of the pool, mainnet when 0
+	ChainID int64  `mapstructure:"chain_id"`
+	Address string `mapstructure:"address"`
+}
+
+type CampaignTaskDefinition struct {
+	// "OnboardingTask" or "SharePoolTask"
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	// decimal points of each period, a share pool splits them between the traders of the period
+	Points string `mapstructure:"points"`
+	// decimal USD volume within a period that completes an onboarding task
+	TargetAmount string `mapstructure:"target_amount"`
+	// number of periods, 1 when 0
+	Periods      int           `mapstructure:"periods"`
+	PeriodLength time.Duration `mapstructure:"period_length"`
+	// share pool only, false (default) adds up the swaps of every chain in one share pool, true runs
+	// a separate share pool per chain
+	PerChain bool `mapstructure:"per_chain"`
+}
+
+type SettlementConfig struct {
+	// how often due settlements are looked for, one minute when 0
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// time left after a period ends for late swaps to be ingested before it is settled
+	GracePeriod time.Duration `mapstructure:"grace_period"`
+	// failed attempts after which a settlement is given up, 10 when 0
+	MaxAttempts int `mapstructure:"max_attempts"`
+}
+
+type AdminConfig struct {
+	// bearer token required by the /admin endpoints, they are disabled while it is empty
+	Token string `mapstructure:"token"`
+}
+
+// MainnetChainID is the chain of configs written before chains could be configured.
+const MainnetChainID int64 = 1
+
+// ChainConfigs returns the chains to ingest. Configs without a chains section ingest the top level
+// pools from Ethereum mainnet.
+func (c *Config) ChainConfigs() []ChainConfig {
+	if len(c.Chains) == 0 {
+		return []ChainConfig{
+			{
+				ID:            MainnetChainID,
+				Name:          "ethereum",
+				RPCURL:        c.Ethereum.RPCURL,
+				PollInterval:  c.Ethereum.PollInterval,
+				Confirmations: c.Ethereum.Confirmations,
+				ReplayFile:    c.Source.ReplayFile,
+				Pools:         c.Pools,
+			},
+		}
+	}
+
+	chains := make([]ChainConfig, len(c.Chains))
+	for i, chain := range c.Chains {
+		if chain.Name == "" {
+			chain.Name = fmt.Sprintf("chain-%d", chain.ID)
+		}
+
+		chains[i] = chain
+	}
+
+	return chains
+}
+
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.AddConfigPath("./config")
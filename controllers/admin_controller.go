@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"trading-ace/config"
+	"trading-ace/dtos"
+	"trading-ace/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IAdminController interface {
+	GetFailedSwapLogs(ctx *gin.Context)
+	RetryFailedSwapLog(ctx *gin.Context)
+	DiscardFailedSwapLog(ctx *gin.Context)
+	CreateCampaign(ctx *gin.Context)
+}
+
+const defaultFailedSwapLogsPageSize = 20
+const maxFailedSwapLogsPageSize = 100
+
+type AdminController struct {
+	config          *config.Config
+	ethereumService services.IEthereumService
+	campaignService services.ICampaignService
+}
+
+func NewAdminController(config *config.Config, ethereumService services.IEthereumService, campaignService services.ICampaignService) IAdminController {
+	return &AdminController{
+		config:          config,
+		ethereumService: ethereumService,
+		campaignService: campaignService,
+	}
+}
+
+// GetFailedSwapLogs lists the swap logs that failed to decode or credit
+// @Summary Get failed swap logs
+// @Description Retrieves a page of the swap logs that failed to decode or credit, oldest first, with the error, attempt count and raw log.
+// @Tags Admin
+// @Produce  json
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param page_size query int false "Logs per page, at most 100" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/failed-swap-logs [get]
+func (h *AdminController) GetFailedSwapLogs(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(400, gin.H{"status": "error", "message": "page must be a positive integer"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(defaultFailedSwapLogsPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxFailedSwapLogsPageSize {
+		ctx.JSON(400, gin.H{"status": "error", "message": fmt.Sprintf("page_size must be between 1 and %d", maxFailedSwapLogsPageSize)})
+		return
+	}
+
+	failedLogs, total, err := h.ethereumService.GetFailedSwapLogs(page, pageSize)
+	if err != nil {
+		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	results := []*dtos.FailedSwapLogDTO{}
+	for _, v := range failedLogs {
+		results = append(results, dtos.ConvertFailedSwapLogToDTO(v))
+	}
+
+	ctx.JSON(200, gin.H{
+		"status": "ok",
+		"result": results,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+// RetryFailedSwapLog decodes and credits a failed swap log again
+// @Summary Retry a failed swap log
+// @Description Runs a failed swap log through decoding and crediting again. The log is removed once credited, a failed retry counts one more attempt.
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "Failed swap log ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/failed-swap-logs/{id}/retry [post]
+func (h *AdminController) RetryFailedSwapLog(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(400, gin.H{"status": "error", "message": "id must be an integer"})
+		return
+	}
+
+	if err := h.ethereumService.RetryFailedSwapLog(id); err != nil {
+		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"status": "ok"})
+}
+
+// DiscardFailedSwapLog drops a failed swap log without crediting it
+// @Summary Discard a failed swap log
+// @Description Removes a failed swap log for good, its swap is never credited.
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "Failed swap log ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/failed-swap-logs/{id} [delete]
+func (h *AdminController) DiscardFailedSwapLog(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(400, gin.H{"status": "error", "message": "id must be an integer"})
+		return
+	}
+
+	if err := h.ethereumService.DiscardFailedSwapLog(id); err != nil {
+		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"status": "ok"})
+}
+
+// CreateCampaign starts a campaign from a posted definition
+// @Summary Start a campaign from a definition
+// @Description Starts a campaign with the declared tasks, points, onboarding target, period count, period length and pools. Campaigns run side by side, but not two of the same name.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param definition body dtos.CampaignDefinitionDTO true "Campaign definition"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /admin/campaigns [post]
+func (h *AdminController) CreateCampaign(ctx *gin.Context) {
+	var definitionDTO dtos.CampaignDefinitionDTO
+	if err := ctx.ShouldBindJSON(&definitionDTO); err != nil {
+		ctx.JSON(400, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	definition, err := definitionDTO.ToCampaignDefinition()
+	if err != nil {
+		ctx.JSON(400, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	campaign, err := h.campaignService.CreateCampaign(definition)
+	if err != nil {
+		ctx.JSON(400, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"status": "ok", "result": dtos.ConvertCampaignToDTO(campaign)})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"fmt"
 	"strconv"
 	"trading-ace/config"
 	"trading-ace/dtos"
@@ -11,11 +12,16 @@ import (
 
 type ICampaignController interface {
 	StartCampaign(ctx *gin.Context)
+	ListCampaigns(ctx *gin.Context)
 	GetPointHistories(ctx *gin.Context)
 	GetTaskStatus(ctx *gin.Context)
 	GetLeaderboard(ctx *gin.Context)
+	GetSwapActivities(ctx *gin.Context)
 }
 
+const defaultSwapActivitiesPageSize = 20
+const maxSwapActivitiesPageSize = 100
+
 type CampaignController struct {
 	config          *config.Config
 	campaignService services.ICampaignService
@@ -46,6 +52,29 @@ func (h *CampaignController) StartCampaign(ctx *gin.Context) {
 	ctx.JSON(200, gin.H{"status": "ok"})
 }
 
+// ListCampaigns lists the campaigns
+// @Summary List campaigns
+// @Description Retrieves every campaign, active or ended, the latest first, with the pools it counts. A campaign without pools counts every tracked pool.
+// @Tags Campaign
+// @Produce  json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /campaign/list [get]
+func (h *CampaignController) ListCampaigns(ctx *gin.Context) {
+	campaigns, err := h.campaignService.ListCampaigns()
+	if err != nil {
+		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	results := []*dtos.CampaignDTO{}
+	for _, campaign := range campaigns {
+		results = append(results, dtos.ConvertCampaignToDTO(campaign))
+	}
+
+	ctx.JSON(200, gin.H{"status": "ok", "result": results})
+}
+
 // GetPointHistories retrieves the point histories for a given address
 // @Summary Get point histories
 // @Description Retrieves the list of point histories for a given address.
@@ -53,13 +82,20 @@ func (h *CampaignController) StartCampaign(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param address path string true "User Address"
+// @Param campaign_id query int false "Campaign ID, every campaign when omitted"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /campaign/points/{address} [get]
 func (h *CampaignController) GetPointHistories(ctx *gin.Context) {
 	address := ctx.Param("address")
 
-	pointHistories, err := h.campaignService.GetPointHistories(address)
+	campaignID, ok := campaignIDQuery(ctx)
+	if !ok {
+		return
+	}
+
+	pointHistories, err := h.campaignService.GetPointHistories(address, campaignID)
 	if err != nil {
 		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
 		return
@@ -85,13 +121,20 @@ func (h *CampaignController) GetPointHistories(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param address path string true "User Address"
+// @Param campaign_id query int false "Campaign ID, every campaign when omitted"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /campaign/task-status/{address} [get]
 func (h *CampaignController) GetTaskStatus(ctx *gin.Context) {
 	address := ctx.Param("address")
 
-	taskStatus, err := h.campaignService.GetTaskStatus(address)
+	campaignID, ok := campaignIDQuery(ctx)
+	if !ok {
+		return
+	}
+
+	taskStatus, err := h.campaignService.GetTaskStatus(address, campaignID)
 	if err != nil {
 		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
 		return
@@ -107,13 +150,16 @@ func (h *CampaignController) GetTaskStatus(ctx *gin.Context) {
 
 // GetLeaderboard retrieves the leaderboard for a given task and period
 // @Summary Get leaderboard
-// @Description Retrieves the leaderboard for a specific task and period.
+// @Description Retrieves the leaderboard for a specific task and period. Share pools run per chain are selected by chain_id.
 // @Tags Campaign
 // @Accept  json
 // @Produce  json
 // @Param taskName path string true "Task Name"
 // @Param period path int true "Period"
+// @Param chain_id query int false "Chain ID of a per chain share pool, the pool of every chain when omitted"
+// @Param campaign_id query int false "Campaign ID, the latest campaign when omitted"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /campaign/leaderboard/{taskName}/{period} [get]
 func (h *CampaignController) GetLeaderboard(ctx *gin.Context) {
@@ -125,7 +171,18 @@ func (h *CampaignController) GetLeaderboard(ctx *gin.Context) {
 		return
 	}
 
-	leaderboardEntries, err := h.campaignService.GetLeaderboard(taskName, int(period))
+	chainID, err := strconv.ParseInt(ctx.DefaultQuery("chain_id", "0"), 10, 64)
+	if err != nil || chainID < 0 {
+		ctx.JSON(400, gin.H{"status": "error", "message": "chain_id must be a positive integer"})
+		return
+	}
+
+	campaignID, ok := campaignIDQuery(ctx)
+	if !ok {
+		return
+	}
+
+	leaderboardEntries, err := h.campaignService.GetLeaderboard(campaignID, taskName, int(period), chainID)
 	if err != nil {
 		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
 		return
@@ -133,3 +190,78 @@ func (h *CampaignController) GetLeaderboard(ctx *gin.Context) {
 
 	ctx.JSON(200, gin.H{"status": "ok", "result": leaderboardEntries})
 }
+
+// GetSwapActivities retrieves the swaps credited to a given address
+// @Summary Get swap activities
+// @Description Retrieves a page of the swaps credited to a given address, newest first, optionally filtered by share pool period.
+// @Tags Campaign
+// @Accept  json
+// @Produce  json
+// @Param address path string true "User Address"
+// @Param campaign_id query int false "Campaign ID, the latest campaign when omitted"
+// @Param period query int false "Share pool period, all periods when omitted"
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param page_size query int false "Swaps per page, at most 100" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /campaign/swaps/{address} [get]
+func (h *CampaignController) GetSwapActivities(ctx *gin.Context) {
+	address := ctx.Param("address")
+
+	campaignID, ok := campaignIDQuery(ctx)
+	if !ok {
+		return
+	}
+
+	period, err := strconv.Atoi(ctx.DefaultQuery("period", "0"))
+	if err != nil || period < 0 {
+		ctx.JSON(400, gin.H{"status": "error", "message": "period must be a non-negative integer"})
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(400, gin.H{"status": "error", "message": "page must be a positive integer"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(defaultSwapActivitiesPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxSwapActivitiesPageSize {
+		ctx.JSON(400, gin.H{"status": "error", "message": fmt.Sprintf("page_size must be between 1 and %d", maxSwapActivitiesPageSize)})
+		return
+	}
+
+	swapActivities, total, err := h.campaignService.GetSwapActivities(address, campaignID, period, page, pageSize)
+	if err != nil {
+		ctx.JSON(500, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	results := []*dtos.SwapActivityDTO{}
+	for _, v := range swapActivities {
+		results = append(results, dtos.ConvertSwapActivityToDTO(v))
+	}
+
+	ctx.JSON(200, gin.H{
+		"status": "ok",
+		"result": results,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+// campaignIDQuery parses the optional campaign_id query parameter, 0 when omitted. An invalid value is
+// answered with 400 and reported as not ok.
+func campaignIDQuery(ctx *gin.Context) (int64, bool) {
+	campaignID, err := strconv.ParseInt(ctx.DefaultQuery("campaign_id", "0"), 10, 64)
+	if err != nil || campaignID < 0 {
+		ctx.JSON(400, gin.H{"status": "error", "message": "campaign_id must be a positive integer"})
+		return 0, false
+	}
+
+	return campaignID, true
+}
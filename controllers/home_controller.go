@@ -2,24 +2,43 @@ package controllers
 
 import (
 	"trading-ace/config"
+	"trading-ace/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type IHomeController interface {
 	Home(ctx *gin.Context)
+	Status(ctx *gin.Context)
 }
 
 type HomeController struct {
-	config *config.Config
+	config          *config.Config
+	ethereumService services.IEthereumService
 }
 
-func NewHomeController(config *config.Config) IHomeController {
+func NewHomeController(config *config.Config, ethereumService services.IEthereumService) IHomeController {
 	return &HomeController{
-		config: config,
+		config:          config,
+		ethereumService: ethereumService,
 	}
 }
 
 func (h *HomeController) Home(ctx *gin.Context) {
 	ctx.JSON(200, gin.H{"hello": "world"})
 }
+
+// Status reports the swap processing queue
+// @Summary Get service status
+// @Description Returns the number of decoded swaps waiting to be credited and the queue capacity.
+// @Tags Home
+// @Produce  json
+// @Success 200 {object} map[string]interface{}
+// @Router /status [get]
+func (h *HomeController) Status(ctx *gin.Context) {
+	ctx.JSON(200, gin.H{
+		"status":              "ok",
+		"swap_queue_depth":    h.ethereumService.QueueDepth(),
+		"swap_queue_capacity": h.ethereumService.QueueCapacity(),
+	})
+}
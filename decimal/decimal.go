@@ -0,0 +1,245 @@
+package decimal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Places is the number of fractional digits every Decimal carries. USD amounts and points are
+// exact to one millionth, the precision of USDC, and fit a Redis integer counter in that unit.
+const Places = 6
+
+var unitsPerOne = new(big.Int).Exp(big.NewInt(10), big.NewInt(Places), nil)
+
+// Decimal is an exact fixed point number stored as an integer count of 10^-Places units.
+// The zero value is 0.
+//
+// Operations that can produce more than Places fractional digits (FromRat, FromTokenAmount,
+// Parse) round half away from zero to the nearest unit.
+type Decimal struct {
+	units *big.Int
+}
+
+// New returns the Decimal made of units 10^-Places units.
+func New(units *big.Int) Decimal {
+	return Decimal{units: new(big.Int).Set(units)}
+}
+
+// NewFromInt returns the Decimal of a whole number.
+func NewFromInt(value int64) Decimal {
+	return Decimal{units: new(big.Int).Mul(big.NewInt(value), unitsPerOne)}
+}
+
+// FromRat rounds an exact rational to the nearest unit.
+func FromRat(value *big.Rat) Decimal {
+	numerator := new(big.Int).Mul(value.Num(), unitsPerOne)
+	quotient, remainder := new(big.Int).QuoRem(numerator, value.Denom(), new(big.Int))
+
+	// round half away from zero
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(value.Denom()) >= 0 {
+		if numerator.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return Decimal{units: quotient}
+}
+
+// FromTokenAmount converts a raw token amount with the token's decimals into whole tokens.
+func FromTokenAmount(amount *big.Int, decimals int64) Decimal {
+	return FromRat(TokenAmount(amount, decimals))
+}
+
+// TokenAmount returns a raw token amount in whole tokens, without rounding.
+func TokenAmount(amount *big.Int, decimals int64) *big.Rat {
+	return new(big.Rat).SetFrac(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil))
+}
+
+// Parse reads a decimal string such as "1000", "-0.5" or "12.345678".
+func Parse(value string) (Decimal, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", value)
+	}
+
+	return FromRat(rat), nil
+}
+
+// RequireFromString is Parse for constants, it panics on invalid input.
+func RequireFromString(value string) Decimal {
+	d, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// Units returns the number of 10^-Places units.
+func (d Decimal) Units() *big.Int {
+	if d.units == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(d.units)
+}
+
+// Rat returns the exact value as a rational.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Units(), unitsPerOne)
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{units: new(big.Int).Add(d.Units(), other.Units())}
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{units: new(big.Int).Sub(d.Units(), other.Units())}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: new(big.Int).Neg(d.Units())}
+}
+
+// Cmp compares d and other and returns -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	return d.Units().Cmp(other.Units())
+}
+
+func (d Decimal) Sign() int {
+	return d.Units().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 returns the nearest float64, for consumers that can only hold floats such as Redis
+// sorted set scores.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String formats d without trailing fractional zeros, e.g. "1000" or "0.25".
+func (d Decimal) String() string {
+	s := d.Rat().FloatString(Places)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+
+	return s
+}
+
+// Value stores d in a NUMERIC column.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads a NUMERIC column.
+func (d *Decimal) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		parsed, err := Parse(string(value))
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	case string:
+		parsed, err := Parse(value)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	case int64:
+		*d = NewFromInt(value)
+		return nil
+	case float64:
+		rat, ok := new(big.Rat).SetString(fmt.Sprintf("%v", value))
+		if !ok {
+			return fmt.Errorf("invalid decimal %v", value)
+		}
+		*d = FromRat(rat)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into decimal", src)
+	}
+}
+
+// MarshalJSON writes d as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number or a quoted decimal string.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	parsed, err := Parse(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+// Allocate splits total in proportion to weights so that the shares add up to exactly total.
+//
+// Rounding rule (largest remainder): every share is first rounded down to a whole unit, the units
+// left over are then handed out one at a time to the shares with the largest discarded remainder.
+// Equal remainders go to the lower index, so callers should pass weights in a stable order.
+// Negative weights count as zero, and all shares are zero when no weight is positive.
+func Allocate(total Decimal, weights []Decimal) []Decimal {
+	shares := make([]Decimal, len(weights))
+
+	weightUnits := make([]*big.Int, len(weights))
+	weightSum := new(big.Int)
+	for i, weight := range weights {
+		weightUnits[i] = weight.Units()
+		if weightUnits[i].Sign() < 0 {
+			weightUnits[i].SetInt64(0)
+		}
+		weightSum.Add(weightSum, weightUnits[i])
+	}
+
+	if weightSum.Sign() == 0 || total.Sign() <= 0 {
+		return shares
+	}
+
+	totalUnits := total.Units()
+	remainders := make([]*big.Int, len(weights))
+	allocated := new(big.Int)
+	for i := range weights {
+		quotient, remainder := new(big.Int).QuoRem(new(big.Int).Mul(totalUnits, weightUnits[i]), weightSum, new(big.Int))
+		shares[i] = Decimal{units: quotient}
+		remainders[i] = remainder
+		allocated.Add(allocated, quotient)
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+
+	leftover := new(big.Int).Sub(totalUnits, allocated).Int64()
+	for i := int64(0); i < leftover; i++ {
+		index := order[i]
+		shares[index] = Decimal{units: new(big.Int).Add(shares[index].Units(), big.NewInt(1))}
+	}
+
+	return shares
+}
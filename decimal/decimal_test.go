@@ -0,0 +1,114 @@
+package decimal
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAndString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1000", "1000"},
+		{"0.25", "0.25"},
+		{"-0.5", "-0.5"},
+		{"12.3456785", "12.345679"},
+		{"-12.3456785", "-12.345679"},
+		{"0.0000004", "0"},
+		{"100.000000", "100"},
+	}
+
+	for _, tt := range tests {
+		d, err := Parse(tt.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, d.String(), "input %q", tt.input)
+	}
+
+	_, err := Parse("abc")
+	assert.Error(t, err)
+}
+
+func TestFromTokenAmount(t *testing.T) {
+	// 1,234.567891 USDC
+	assert.Equal(t, "1234.567891", FromTokenAmount(big.NewInt(1234567891), 6).String())
+
+	// 1.5 WETH rounds exactly, 1 wei rounds to zero
+	weth, _ := new(big.Int).SetString("1500000000000000000", 10)
+	assert.Equal(t, "1.5", FromTokenAmount(weth, 18).String())
+	assert.Equal(t, "0", FromTokenAmount(big.NewInt(1), 18).String())
+}
+
+func TestArithmetic(t *testing.T) {
+	a := RequireFromString("0.1")
+	b := RequireFromString("0.2")
+
+	// the float64 classic, exact here
+	assert.Equal(t, "0.3", a.Add(b).String())
+	assert.Equal(t, "-0.1", a.Sub(b).String())
+	assert.Equal(t, 0, a.Add(b).Cmp(RequireFromString("0.3")))
+	assert.True(t, Decimal{}.IsZero())
+	assert.Equal(t, int64(100000), a.Units().Int64())
+}
+
+func TestScanAndValue(t *testing.T) {
+	var d Decimal
+
+	assert.NoError(t, d.Scan([]byte("10.5")))
+	assert.Equal(t, "10.5", d.String())
+
+	assert.NoError(t, d.Scan(int64(7)))
+	assert.Equal(t, "7", d.String())
+
+	assert.NoError(t, d.Scan(nil))
+	assert.True(t, d.IsZero())
+
+	assert.Error(t, d.Scan(true))
+
+	value, err := RequireFromString("10.5").Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.5", value)
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Points Decimal `json:"points"`
+	}{RequireFromString("33.333334")})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"points":33.333334}`, string(data))
+
+	var decoded struct {
+		Points Decimal `json:"points"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "33.333334", decoded.Points.String())
+}
+
+func TestAllocate(t *testing.T) {
+	total := NewFromInt(10000)
+
+	// three equal weights: 3333.333333 each, the leftover unit goes to the first
+	shares := Allocate(total, []Decimal{NewFromInt(1), NewFromInt(1), NewFromInt(1)})
+	assert.Equal(t, "3333.333334", shares[0].String())
+	assert.Equal(t, "3333.333333", shares[1].String())
+	assert.Equal(t, "3333.333333", shares[2].String())
+
+	// the largest remainders get the leftover units, and the shares always add up to total
+	weights := []Decimal{RequireFromString("123.456789"), RequireFromString("0.000001"), RequireFromString("9876.5"), NewFromInt(-5)}
+	shares = Allocate(total, weights)
+
+	sum := Decimal{}
+	for _, share := range shares {
+		sum = sum.Add(share)
+	}
+	assert.Equal(t, total.String(), sum.String())
+	assert.True(t, shares[3].IsZero())
+
+	// nothing to split between
+	shares = Allocate(total, []Decimal{{}, {}})
+	assert.True(t, shares[0].IsZero())
+	assert.True(t, shares[1].IsZero())
+}
@@ -0,0 +1,85 @@
+package dtos
+
+import (
+	"fmt"
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
+)
+
+type CampaignDTO struct {
+	ID        int64                       `json:"id"`
+	Name      string                      `json:"name"`
+	StartedAt time.Time                   `json:"started_at"`
+	EndAt     time.Time                   `json:"end_at"`
+	CreatedAt time.Time                   `json:"created_at"`
+	Pools     []CampaignPoolDefinitionDTO `json:"pools"`
+}
+
+func ConvertCampaignToDTO(campaign *entities.Campaign) *CampaignDTO {
+	pools := []CampaignPoolDefinitionDTO{}
+	for _, pool := range campaign.Pools {
+		pools = append(pools, CampaignPoolDefinitionDTO{ChainID: pool.ChainID, Address: pool.PoolAddress})
+	}
+
+	return &CampaignDTO{
+		ID:        campaign.ID,
+		Name:      campaign.Name,
+		StartedAt: campaign.StartedAt,
+		EndAt:     campaign.EndAt,
+		CreatedAt: campaign.CreatedAt,
+		Pools:     pools,
+	}
+}
+
+// CampaignDefinitionDTO is the definition of a campaign started through the admin API, see
+// config.CampaignDefinition.
+type CampaignDefinitionDTO struct {
+	Name  string                      `json:"name" example:"winter"`
+	Tasks []CampaignTaskDefinitionDTO `json:"tasks"`
+	Pools []CampaignPoolDefinitionDTO `json:"pools"`
+}
+
+type CampaignTaskDefinitionDTO struct {
+	Name         string          `json:"name" example:"SharePoolTask"`
+	Description  string          `json:"description"`
+	Points       decimal.Decimal `json:"points" swaggertype:"number" example:"10000"`
+	TargetAmount decimal.Decimal `json:"target_amount" swaggertype:"number" example:"1000"`
+	Periods      int             `json:"periods" example:"4"`
+	PeriodLength string          `json:"period_length" example:"168h"`
+	PerChain     bool            `json:"per_chain" example:"false"`
+}
+
+// CampaignPoolDefinitionDTO is a tracked pool whose swaps count towards a campaign.
+type CampaignPoolDefinitionDTO struct {
+	ChainID int64  `json:"chain_id" example:"1"`
+	Address string `json:"address" example:"0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"`
+}
+
+// ToCampaignDefinition converts the posted definition, period lengths are Go durations such as "168h".
+func (d *CampaignDefinitionDTO) ToCampaignDefinition() (config.CampaignDefinition, error) {
+	definition := config.CampaignDefinition{Name: d.Name}
+	for _, task := range d.Tasks {
+		periodLength, err := time.ParseDuration(task.PeriodLength)
+		if err != nil {
+			return config.CampaignDefinition{}, fmt.Errorf("invalid period_length of task %s: %w", task.Name, err)
+		}
+
+		definition.Tasks = append(definition.Tasks, config.CampaignTaskDefinition{
+			Name:         task.Name,
+			Description:  task.Description,
+			Points:       task.Points.String(),
+			TargetAmount: task.TargetAmount.String(),
+			Periods:      task.Periods,
+			PeriodLength: periodLength,
+			PerChain:     task.PerChain,
+		})
+	}
+
+	for _, pool := range d.Pools {
+		definition.Pools = append(definition.Pools, config.CampaignPoolDefinition{ChainID: pool.ChainID, Address: pool.Address})
+	}
+
+	return definition, nil
+}
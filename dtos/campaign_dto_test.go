@@ -0,0 +1,64 @@
+package dtos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertCampaignToDTO(t *testing.T) {
+	startedAt := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	endAt := startedAt.Add(28 * 24 * time.Hour)
+
+	result := ConvertCampaignToDTO(&entities.Campaign{
+		ID:        2,
+		Name:      "winter",
+		StartedAt: startedAt,
+		EndAt:     endAt,
+		CreatedAt: startedAt,
+		Pools:     []*entities.CampaignPool{{CampaignID: 2, ChainID: 42161, PoolAddress: "0xPool"}},
+	})
+
+	assert.Equal(t, &CampaignDTO{
+		ID:        2,
+		Name:      "winter",
+		StartedAt: startedAt,
+		EndAt:     endAt,
+		CreatedAt: startedAt,
+		Pools:     []CampaignPoolDefinitionDTO{{ChainID: 42161, Address: "0xPool"}},
+	}, result)
+}
+
+func TestCampaignDefinitionDTOToCampaignDefinition(t *testing.T) {
+	var dto CampaignDefinitionDTO
+	err := json.Unmarshal([]byte(`{
+		"name": "winter",
+		"tasks": [
+			{"name": "OnboardingTask", "points": 50, "target_amount": "500.5", "period_length": "336h"},
+			{"name": "SharePoolTask", "points": 20000, "periods": 2, "period_length": "168h", "per_chain": true}
+		],
+		"pools": [{"chain_id": 42161, "address": "0xPool"}]
+	}`), &dto)
+	assert.NoError(t, err)
+
+	definition, err := dto.ToCampaignDefinition()
+
+	assert.NoError(t, err)
+	assert.Equal(t, config.CampaignDefinition{
+		Name: "winter",
+		Tasks: []config.CampaignTaskDefinition{
+			{Name: "OnboardingTask", Points: "50", TargetAmount: "500.5", PeriodLength: 14 * 24 * time.Hour},
+			{Name: "SharePoolTask", Points: "20000", TargetAmount: "0", Periods: 2, PeriodLength: 7 * 24 * time.Hour, PerChain: true},
+		},
+		Pools: []config.CampaignPoolDefinition{{ChainID: 42161, Address: "0xPool"}},
+	}, definition)
+
+	// period lengths are Go durations
+	dto.Tasks[1].PeriodLength = "1w"
+	_, err = dto.ToCampaignDefinition()
+	assert.Error(t, err)
+}
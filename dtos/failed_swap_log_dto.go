@@ -0,0 +1,41 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+	"trading-ace/entities"
+)
+
+type FailedSwapLogDTO struct {
+	ID              int64           `json:"id"`
+	ChainID         int64           `json:"chain_id"`
+	TxHash          string          `json:"tx_hash"`
+	LogIndex        uint            `json:"log_index"`
+	BlockNumber     uint64          `json:"block_number"`
+	BlockTimestamp  *time.Time      `json:"block_timestamp"`
+	PoolAddress     string          `json:"pool_address"`
+	Log             json.RawMessage `json:"log" swaggertype:"object"`
+	Stage           string          `json:"stage"`
+	Error           string          `json:"error"`
+	Attempts        int             `json:"attempts"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastAttemptedAt time.Time       `json:"last_attempted_at"`
+}
+
+func ConvertFailedSwapLogToDTO(failedLog *entities.FailedSwapLog) *FailedSwapLogDTO {
+	return &FailedSwapLogDTO{
+		ID:              failedLog.ID,
+		ChainID:         failedLog.ChainID,
+		TxHash:          failedLog.TxHash,
+		LogIndex:        failedLog.LogIndex,
+		BlockNumber:     failedLog.BlockNumber,
+		BlockTimestamp:  failedLog.BlockTimestamp,
+		PoolAddress:     failedLog.ContractAddress,
+		Log:             json.RawMessage(failedLog.Payload),
+		Stage:           failedLog.Stage,
+		Error:           failedLog.Error,
+		Attempts:        failedLog.Attempts,
+		CreatedAt:       failedLog.CreatedAt,
+		LastAttemptedAt: failedLog.UpdatedAt,
+	}
+}
@@ -0,0 +1,54 @@
+package dtos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"trading-ace/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertFailedSwapLogToDTO(t *testing.T) {
+	// Arrange
+	blockTimestamp := time.Now().Add(-time.Hour)
+	createdAt := time.Now().Add(-30 * time.Minute)
+	updatedAt := time.Now()
+	failedLog := &entities.FailedSwapLog{
+		ID:              3,
+		ChainID:         42161,
+		TxHash:          "0xabc",
+		LogIndex:        7,
+		BlockNumber:     21000000,
+		BlockTimestamp:  &blockTimestamp,
+		ContractAddress: "0xPair",
+		Payload:         `{"address":"0xpair","logIndex":"0x7"}`,
+		Stage:           "credit",
+		Error:           "redis: connection refused",
+		Attempts:        2,
+		CreatedAt:       createdAt,
+		UpdatedAt:       updatedAt,
+	}
+
+	// Act
+	result := ConvertFailedSwapLogToDTO(failedLog)
+
+	// Assert
+	assert.Equal(t, int64(3), result.ID, "ID should match")
+	assert.Equal(t, int64(42161), result.ChainID, "ChainID should match")
+	assert.Equal(t, "0xabc", result.TxHash, "TxHash should match")
+	assert.Equal(t, uint(7), result.LogIndex, "LogIndex should match")
+	assert.Equal(t, &blockTimestamp, result.BlockTimestamp, "BlockTimestamp should match")
+	assert.Equal(t, "0xPair", result.PoolAddress, "PoolAddress should match")
+	assert.Equal(t, "credit", result.Stage, "Stage should match")
+	assert.Equal(t, "redis: connection refused", result.Error, "Error should match")
+	assert.Equal(t, 2, result.Attempts, "Attempts should match")
+	assert.Equal(t, createdAt, result.CreatedAt, "CreatedAt should match")
+	assert.Equal(t, updatedAt, result.LastAttemptedAt, "LastAttemptedAt should match")
+
+	// the raw log is embedded as JSON, not as a string
+	encoded, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), `"log":{"address":"0xpair","logIndex":"0x7"}`)
+}
@@ -2,19 +2,22 @@ package dtos
 
 import (
 	"time"
+	"trading-ace/decimal"
 	"trading-ace/models"
 )
 
 type TaskWithTaskHistoryDTO struct {
+	TaskCampaignID         *int64     // Mapping to tasks.campaign_id, nil for tasks created before campaigns were stored
 	TaskName               string     // Mapping to tasks.name
 	TaskStartedAt          *time.Time // Mapping to tasks.started_at
 	TaskEndAt              *time.Time // Mapping to tasks.end_at
 	TaskPeriod             int        // Mapping to tasks.period
+	TaskChainID            *int64     // Mapping to tasks.chain_id, nil when every chain counts
 	Status                 string     // task status according to start and end time
 	IsCompleted            bool
-	RewardPoints           float64    // Mapping to task_histories.reward_points
-	Amount                 *float64   // Mapping to task_histories.amount
-	TaskHistoryCompletedAt *time.Time // Mapping to task_histories.completed_at
+	RewardPoints           decimal.Decimal  // Mapping to task_histories.reward_points
+	Amount                 *decimal.Decimal // Mapping to task_histories.amount
+	TaskHistoryCompletedAt *time.Time       // Mapping to task_histories.completed_at
 }
 
 const NotStarted string = "Not Started"
@@ -35,22 +38,24 @@ func CovertTaskWithTaskHistoryToDTO(model *models.TaskWithTaskHistory) *TaskWith
 	}
 
 	return &TaskWithTaskHistoryDTO{
-		TaskName:      model.TaskName,
-		TaskStartedAt: model.TaskStartedAt,
-		TaskEndAt:     model.TaskEndAt,
-		TaskPeriod:    model.TaskPeriod,
-		Status:        status,
-		IsCompleted:   model.TaskHistoryID != nil,
-		RewardPoints: func() float64 {
+		TaskCampaignID: model.TaskCampaignID,
+		TaskName:       model.TaskName,
+		TaskStartedAt:  model.TaskStartedAt,
+		TaskEndAt:      model.TaskEndAt,
+		TaskPeriod:     model.TaskPeriod,
+		TaskChainID:    model.TaskChainID,
+		Status:         status,
+		IsCompleted:    model.TaskHistoryID != nil,
+		RewardPoints: func() decimal.Decimal {
 			if model.TaskHistoryRewardPoints == nil {
-				return 0
+				return decimal.Decimal{}
 			}
 
 			return *model.TaskHistoryRewardPoints
 		}(),
-		Amount: func() *float64 {
+		Amount: func() *decimal.Decimal {
 			if model.TaskHistoryAmount == nil {
-				var result float64 = 0
+				result := decimal.Decimal{}
 				return &result
 			}
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"trading-ace/decimal"
 	"trading-ace/models"
 
 	"github.com/stretchr/testify/assert"
@@ -18,29 +19,33 @@ func TestCovertTaskWithTaskHistoryToDTO(t *testing.T) {
 	updatedAt := time.Now()
 	taskWithHistory := &models.TaskWithTaskHistory{
 		TaskID:          1,
+		TaskCampaignID:  newInt64Ptr(2),
 		TaskName:        "Test Task",
 		TaskDescription: "This is a test task",
-		TaskPoints:      50.5,
+		TaskPoints:      decimal.RequireFromString("50.5"),
 		TaskStartedAt:   &startedAt,
 		TaskEndAt:       &endAt,
 		TaskPeriod:      7,
+		TaskChainID:     newInt64Ptr(10),
 		TaskCreatedAt:   createdAt,
 		TaskUpdatedAt:   updatedAt,
 
 		TaskHistoryID:           newInt64Ptr(1),
 		TaskHistoryAddress:      newStringPtr("123"),
-		TaskHistoryRewardPoints: newFloat64Ptr(1000),
-		TaskHistoryAmount:       newFloat64Ptr(10),
+		TaskHistoryRewardPoints: newDecimalPtr(1000),
+		TaskHistoryAmount:       newDecimalPtr(10),
 		TaskHistoryCompletedAt:  &completedAt,
 		TaskHistoryCreatedAt:    &createdAt,
 		TaskHistoryUpdatedAt:    &updatedAt,
 	}
 
 	expectedTaskWithHistory := &TaskWithTaskHistoryDTO{
+		taskWithHistory.TaskCampaignID,
 		taskWithHistory.TaskName,
 		taskWithHistory.TaskStartedAt,
 		taskWithHistory.TaskEndAt,
 		taskWithHistory.TaskPeriod,
+		taskWithHistory.TaskChainID,
 		InProgress,
 		true,
 		*taskWithHistory.TaskHistoryRewardPoints,
@@ -52,10 +57,12 @@ func TestCovertTaskWithTaskHistoryToDTO(t *testing.T) {
 	result := CovertTaskWithTaskHistoryToDTO(taskWithHistory)
 
 	// Assert
+	assert.Equal(t, expectedTaskWithHistory.TaskCampaignID, result.TaskCampaignID, "TaskCampaignID should match")
 	assert.Equal(t, expectedTaskWithHistory.TaskName, result.TaskName, "TaskName should match")
 	assert.Equal(t, expectedTaskWithHistory.TaskStartedAt, result.TaskStartedAt, "TaskStartedAt should match")
 	assert.Equal(t, expectedTaskWithHistory.TaskEndAt, result.TaskEndAt, "TaskEndAt should match")
 	assert.Equal(t, expectedTaskWithHistory.TaskPeriod, result.TaskPeriod, "TaskPeriod should match")
+	assert.Equal(t, expectedTaskWithHistory.TaskChainID, result.TaskChainID, "TaskChainID should match")
 	assert.Equal(t, expectedTaskWithHistory.Status, result.Status, "Status should match")
 	assert.Equal(t, expectedTaskWithHistory.IsCompleted, result.IsCompleted, "IsCompleted should match")
 	assert.Equal(t, expectedTaskWithHistory.RewardPoints, result.RewardPoints, "RewardPoints should match")
@@ -68,8 +75,9 @@ func newInt64Ptr(a int64) *int64 {
 	return &a
 }
 
-func newFloat64Ptr(a float64) *float64 {
-	return &a
+func newDecimalPtr(a int64) *decimal.Decimal {
+	d := decimal.NewFromInt(a)
+	return &d
 }
 
 func newStringPtr(a string) *string {
@@ -0,0 +1,47 @@
+package dtos
+
+import (
+	"time"
+	"trading-ace/decimal"
+	"trading-ace/models"
+)
+
+type SwapActivityDTO struct {
+	ChainID        int64            `json:"chain_id"`
+	TxHash         string           `json:"tx_hash"`
+	LogIndex       uint             `json:"log_index"`
+	BlockNumber    uint64           `json:"block_number"`
+	BlockTimestamp *time.Time       `json:"block_timestamp"`
+	PoolAddress    string           `json:"pool_address"`
+	Address        string           `json:"address"`
+	Amount0In      string           `json:"amount0_in"`
+	Amount1In      string           `json:"amount1_in"`
+	Amount0Out     string           `json:"amount0_out"`
+	Amount1Out     string           `json:"amount1_out"`
+	PriceUSD       *decimal.Decimal `json:"price_usd" swaggertype:"number"`
+	VolumeUSD      *decimal.Decimal `json:"volume_usd" swaggertype:"number"`
+	Period         *int             `json:"period"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+func ConvertSwapActivityToDTO(swapActivity *models.SwapActivity) *SwapActivityDTO {
+	swapEvent := swapActivity.SwapEvent
+
+	return &SwapActivityDTO{
+		ChainID:        swapEvent.ChainID,
+		TxHash:         swapEvent.TxHash,
+		LogIndex:       swapEvent.LogIndex,
+		BlockNumber:    swapEvent.BlockNumber,
+		BlockTimestamp: swapEvent.BlockTimestamp,
+		PoolAddress:    swapEvent.ContractAddress,
+		Address:        swapEvent.SenderAddress,
+		Amount0In:      swapEvent.Amount0In,
+		Amount1In:      swapEvent.Amount1In,
+		Amount0Out:     swapEvent.Amount0Out,
+		Amount1Out:     swapEvent.Amount1Out,
+		PriceUSD:       swapEvent.PriceUSD,
+		VolumeUSD:      swapEvent.VolumeUSD,
+		Period:         swapActivity.TaskPeriod,
+		CreatedAt:      swapEvent.CreatedAt,
+	}
+}
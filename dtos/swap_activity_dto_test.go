@@ -0,0 +1,56 @@
+package dtos
+
+import (
+	"testing"
+	"time"
+
+	"trading-ace/decimal"
+	"trading-ace/entities"
+	"trading-ace/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertSwapActivityToDTO(t *testing.T) {
+	// Arrange
+	blockTimestamp := time.Now().Add(-time.Hour)
+	createdAt := time.Now()
+	period := 2
+	swapActivity := &models.SwapActivity{
+		SwapEvent: &entities.SwapEvent{
+			ID:              1,
+			ChainID:         8453,
+			TxHash:          "0xabc",
+			LogIndex:        7,
+			BlockNumber:     21000000,
+			BlockTimestamp:  &blockTimestamp,
+			ContractAddress: "0xPair",
+			SenderAddress:   "0x123",
+			Amount0In:       "3000000000",
+			Amount1In:       "0",
+			Amount0Out:      "0",
+			Amount1Out:      "1000000000000000000",
+			PriceUSD:        newDecimalPtr(3000),
+			VolumeUSD:       newDecimalPtr(3000),
+			CreatedAt:       createdAt,
+		},
+		TaskPeriod: &period,
+	}
+
+	// Act
+	result := ConvertSwapActivityToDTO(swapActivity)
+
+	// Assert
+	assert.Equal(t, int64(8453), result.ChainID, "ChainID should match")
+	assert.Equal(t, "0xabc", result.TxHash, "TxHash should match")
+	assert.Equal(t, uint(7), result.LogIndex, "LogIndex should match")
+	assert.Equal(t, uint64(21000000), result.BlockNumber, "BlockNumber should match")
+	assert.Equal(t, &blockTimestamp, result.BlockTimestamp, "BlockTimestamp should match")
+	assert.Equal(t, "0xPair", result.PoolAddress, "PoolAddress should match")
+	assert.Equal(t, "0x123", result.Address, "Address should match")
+	assert.Equal(t, "3000000000", result.Amount0In, "Amount0In should match")
+	assert.Equal(t, "1000000000000000000", result.Amount1Out, "Amount1Out should match")
+	assert.Equal(t, decimal.RequireFromString("3000"), *result.VolumeUSD, "VolumeUSD should match")
+	assert.Equal(t, &period, result.Period, "Period should match")
+	assert.Equal(t, createdAt, result.CreatedAt, "CreatedAt should match")
+}
@@ -2,31 +2,38 @@ package dtos
 
 import (
 	"time"
+	"trading-ace/decimal"
 	"trading-ace/entities"
 )
 
 type TaskDTO struct {
-	ID          int64      `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Points      float64    `json:"points"`
-	StartedAt   *time.Time `json:"started_at"`
-	EndAt       *time.Time `json:"end_at"`
-	Period      int        `json:"period"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID           int64           `json:"id"`
+	CampaignID   *int64          `json:"campaign_id"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Points       decimal.Decimal `json:"points" swaggertype:"number"`
+	TargetAmount decimal.Decimal `json:"target_amount" swaggertype:"number"`
+	StartedAt    *time.Time      `json:"started_at"`
+	EndAt        *time.Time      `json:"end_at"`
+	Period       int             `json:"period"`
+	ChainID      *int64          `json:"chain_id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
 }
 
 func ConvertTaskToDTO(task *entities.Task) *TaskDTO {
 	return &TaskDTO{
-		ID:          task.ID,
-		Name:        task.Name,
-		Description: task.Description,
-		Points:      task.Points,
-		StartedAt:   task.StartedAt,
-		EndAt:       task.EndAt,
-		Period:      task.Period,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
+		ID:           task.ID,
+		CampaignID:   task.CampaignID,
+		Name:         task.Name,
+		Description:  task.Description,
+		Points:       task.Points,
+		TargetAmount: task.TargetAmount,
+		StartedAt:    task.StartedAt,
+		EndAt:        task.EndAt,
+		Period:       task.Period,
+		ChainID:      task.ChainID,
+		CreatedAt:    task.CreatedAt,
+		UpdatedAt:    task.UpdatedAt,
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"trading-ace/decimal"
 	"trading-ace/entities"
 
 	"github.com/stretchr/testify/assert"
@@ -12,19 +13,24 @@ import (
 func TestConvertTaskToDTO(t *testing.T) {
 	// Arrange
 	startedAt := time.Now().Add(-72 * time.Hour) // 3 days ago
-	endAt := time.Now().Add(24 * time.Hour)     // 1 day in the future
+	endAt := time.Now().Add(24 * time.Hour)      // 1 day in the future
 	createdAt := time.Now().Add(-96 * time.Hour) // 4 days ago
 	updatedAt := time.Now()
+	chainID := int64(42161)
+	campaignID := int64(2)
 	task := &entities.Task{
-		ID:          1,
-		Name:        "Test Task",
-		Description: "This is a test task",
-		Points:      50.5,
-		StartedAt:   &startedAt,
-		EndAt:       &endAt,
-		Period:      7,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
+		ID:           1,
+		CampaignID:   &campaignID,
+		Name:         "Test Task",
+		Description:  "This is a test task",
+		Points:       decimal.RequireFromString("50.5"),
+		TargetAmount: decimal.NewFromInt(1000),
+		StartedAt:    &startedAt,
+		EndAt:        &endAt,
+		Period:       7,
+		ChainID:      &chainID,
+		CreatedAt:    createdAt,
+		UpdatedAt:    updatedAt,
 	}
 
 	// Act
@@ -35,9 +41,12 @@ func TestConvertTaskToDTO(t *testing.T) {
 	assert.Equal(t, task.Name, result.Name, "Name should match")
 	assert.Equal(t, task.Description, result.Description, "Description should match")
 	assert.Equal(t, task.Points, result.Points, "Points should match")
+	assert.Equal(t, task.TargetAmount, result.TargetAmount, "TargetAmount should match")
+	assert.Equal(t, &campaignID, result.CampaignID, "CampaignID should match")
 	assert.Equal(t, task.StartedAt, result.StartedAt, "StartedAt should match")
 	assert.Equal(t, task.EndAt, result.EndAt, "EndAt should match")
 	assert.Equal(t, task.Period, result.Period, "Period should match")
+	assert.Equal(t, &chainID, result.ChainID, "ChainID should match")
 	assert.Equal(t, task.CreatedAt, result.CreatedAt, "CreatedAt should match")
 	assert.Equal(t, task.UpdatedAt, result.UpdatedAt, "UpdatedAt should match")
 }
@@ -2,18 +2,19 @@ package dtos
 
 import (
 	"time"
+	"trading-ace/decimal"
 	"trading-ace/entities"
 )
 
 type TaskHistoryDTO struct {
-	ID           int64      `json:"id"`
-	Address      string     `json:"address"`
-	TaskID       int64      `json:"task_id"`
-	RewardPoints float64    `json:"reward_points"`
-	Amount       float64    `json:"amount"`
-	CompletedAt  *time.Time `json:"completed_at"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID           int64           `json:"id"`
+	Address      string          `json:"address"`
+	TaskID       int64           `json:"task_id"`
+	RewardPoints decimal.Decimal `json:"reward_points" swaggertype:"number"`
+	Amount       decimal.Decimal `json:"amount" swaggertype:"number"`
+	CompletedAt  *time.Time      `json:"completed_at"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
 }
 
 func ConvertTaskHistoryToDTO(taskHistory *entities.TaskHistory) *TaskHistoryDTO {
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"trading-ace/decimal"
 	"trading-ace/entities"
 
 	"github.com/stretchr/testify/assert"
@@ -18,8 +19,8 @@ func TestConvertTaskHistoryToDTO(t *testing.T) {
 		ID:           1,
 		Address:      "0x123",
 		TaskID:       10,
-		RewardPoints: 100.5,
-		Amount:       200.75,
+		RewardPoints: decimal.RequireFromString("100.5"),
+		Amount:       decimal.RequireFromString("200.75"),
 		CompletedAt:  &completedAt,
 		CreatedAt:    createdAt,
 		UpdatedAt:    updatedAt,
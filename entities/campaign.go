@@ -0,0 +1,21 @@
+package entities
+
+import "time"
+
+type Campaign struct {
+	ID        int64     `db:"id"`         // SERIAL PRIMARY KEY
+	Name      string    `db:"name"`       // VARCHAR(255) NOT NULL
+	StartedAt time.Time `db:"started_at"` // TIMESTAMP NOT NULL
+	EndAt     time.Time `db:"end_at"`     // TIMESTAMP NOT NULL
+	CreatedAt time.Time `db:"created_at"` // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+
+	// kept in campaign_pools
+	Pools []*CampaignPool
+}
+
+// CampaignPool is a pool a campaign counts swaps of, a campaign without pools counts every pool.
+type CampaignPool struct {
+	CampaignID  int64  `db:"campaign_id"`  // INT NOT NULL REFERENCES campaigns(id)
+	ChainID     int64  `db:"chain_id"`     // BIGINT NOT NULL
+	PoolAddress string `db:"pool_address"` // VARCHAR(42) NOT NULL
+}
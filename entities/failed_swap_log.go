@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+type FailedSwapLog struct {
+	ID              int64      `db:"id"`               // SERIAL PRIMARY KEY
+	ChainID         int64      `db:"chain_id"`         // BIGINT NOT NULL
+	TxHash          string     `db:"tx_hash"`          // VARCHAR(66) NOT NULL
+	LogIndex        uint       `db:"log_index"`        // INT NOT NULL
+	BlockNumber     uint64     `db:"block_number"`     // BIGINT NOT NULL
+	BlockTimestamp  *time.Time `db:"block_timestamp"`  // TIMESTAMP NULL
+	ContractAddress string     `db:"contract_address"` // VARCHAR(255) NOT NULL
+	Payload         string     `db:"payload"`          // TEXT NOT NULL
+	Stage           string     `db:"stage"`            // VARCHAR(16) NOT NULL
+	Error           string     `db:"error"`            // TEXT NOT NULL
+	Attempts        int        `db:"attempts"`         // INT NOT NULL DEFAULT 1
+	CreatedAt       time.Time  `db:"created_at"`       // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	UpdatedAt       time.Time  `db:"updated_at"`       // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+}
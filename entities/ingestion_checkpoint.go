@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+type IngestionCheckpoint struct {
+	ID              int64     `db:"id"`               // SERIAL PRIMARY KEY
+	ChainID         int64     `db:"chain_id"`         // BIGINT NOT NULL DEFAULT 1
+	Chain           string    `db:"chain"`            // VARCHAR(64) NOT NULL
+	ContractAddress string    `db:"contract_address"` // VARCHAR(255) NOT NULL
+	BlockNumber     uint64    `db:"block_number"`     // BIGINT NOT NULL
+	LogIndex        uint      `db:"log_index"`        // INT NOT NULL
+	CreatedAt       time.Time `db:"created_at"`       // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	UpdatedAt       time.Time `db:"updated_at"`       // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+}
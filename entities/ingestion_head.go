@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// IngestionHead is the safe head block up to which ingestion of a chain has credited every swap.
+type IngestionHead struct {
+	ChainID        int64     `db:"chain_id"`        // BIGINT PRIMARY KEY
+	Chain          string    `db:"chain"`           // VARCHAR(64) NOT NULL
+	BlockNumber    uint64    `db:"block_number"`    // BIGINT NOT NULL
+	BlockTimestamp time.Time `db:"block_timestamp"` // TIMESTAMP NOT NULL
+	CreatedAt      time.Time `db:"created_at"`      // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	UpdatedAt      time.Time `db:"updated_at"`      // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+}
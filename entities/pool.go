@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+type Pool struct {
+	ID            int64     `db:"id"`             // SERIAL PRIMARY KEY
+	ChainID       int64     `db:"chain_id"`       // BIGINT NOT NULL
+	Address       string    `db:"address"`        // VARCHAR(42) NOT NULL
+	Token0Address string    `db:"token0_address"` // VARCHAR(42) NOT NULL
+	Token1Address string    `db:"token1_address"` // VARCHAR(42) NOT NULL
+	CreatedAt     time.Time `db:"created_at"`     // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+}
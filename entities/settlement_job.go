@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// SettlementJob is the settlement of a share pool task, Status is "pending" until it is "settled",
+// or "failed" once it ran out of attempts. FencingToken is that of the settlement lock holder that
+// claimed it last.
+type SettlementJob struct {
+	ID           int64      `db:"id"`            // SERIAL PRIMARY KEY
+	TaskID       int64      `db:"task_id"`       // INT NOT NULL REFERENCES tasks(id)
+	DueAt        time.Time  `db:"due_at"`        // TIMESTAMP NOT NULL
+	Status       string     `db:"status"`        // VARCHAR(16) NOT NULL DEFAULT 'pending'
+	Attempts     int        `db:"attempts"`      // INT NOT NULL DEFAULT 0
+	Error        string     `db:"error"`         // TEXT NOT NULL DEFAULT ''
+	SettledAt    *time.Time `db:"settled_at"`    // TIMESTAMP NULL
+	FencingToken int64      `db:"fencing_token"` // BIGINT NOT NULL DEFAULT 0
+	CreatedAt    time.Time  `db:"created_at"`    // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	UpdatedAt    time.Time  `db:"updated_at"`    // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+}
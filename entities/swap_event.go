@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+	"trading-ace/decimal"
+)
+
+type SwapEvent struct {
+	ID              int64            `db:"id"`               // SERIAL PRIMARY KEY
+	ChainID         int64            `db:"chain_id"`         // BIGINT NOT NULL DEFAULT 1
+	TxHash          string           `db:"tx_hash"`          // VARCHAR(66) NOT NULL
+	LogIndex        uint             `db:"log_index"`        // INT NOT NULL
+	BlockNumber     uint64           `db:"block_number"`     // BIGINT NOT NULL
+	BlockTimestamp  *time.Time       `db:"block_timestamp"`  // TIMESTAMP NULL
+	ContractAddress string           `db:"contract_address"` // VARCHAR(255) NOT NULL
+	SenderAddress   string           `db:"sender_address"`   // VARCHAR(255) NOT NULL
+	Amount0In       string           `db:"amount0_in"`       // NUMERIC(78, 0) NOT NULL
+	Amount1In       string           `db:"amount1_in"`       // NUMERIC(78, 0) NOT NULL
+	Amount0Out      string           `db:"amount0_out"`      // NUMERIC(78, 0) NOT NULL
+	Amount1Out      string           `db:"amount1_out"`      // NUMERIC(78, 0) NOT NULL
+	PriceUSD        *decimal.Decimal `db:"price_usd"`        // NUMERIC NULL
+	VolumeUSD       *decimal.Decimal `db:"volume_usd"`       // NUMERIC NULL
+	CreatedAt       time.Time        `db:"created_at"`       // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+
+	// the share pool tasks the swap is credited to, kept in swap_event_tasks
+	TaskIDs []int64
+}
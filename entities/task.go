@@ -1,15 +1,21 @@
 package entities
 
-import "time"
+import (
+	"time"
+	"trading-ace/decimal"
+)
 
 type Task struct {
-	ID          int64      `db:"id"`          // SERIAL PRIMARY KEY
-	Name        string     `db:"name"`        // VARCHAR(255) NOT NULL
-	Description string     `db:"description"` // TEXT
-	Points      float64    `db:"points"`      // BIGINT NOT NULL
-	StartedAt   *time.Time `db:"started_at"`  // TIMESTAMP NULL
-	EndAt       *time.Time `db:"end_at"`      // TIMESTAMP NULL
-	Period      int        `db:"period"`      // INT DEFAULT 1
-	CreatedAt   time.Time  `db:"created_at"`  // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	UpdatedAt   time.Time  `db:"updated_at"`  // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	ID           int64           `db:"id"`            // SERIAL PRIMARY KEY
+	CampaignID   *int64          `db:"campaign_id"`   // INT NULL REFERENCES campaigns(id)
+	Name         string          `db:"name"`          // VARCHAR(255) NOT NULL
+	Description  string          `db:"description"`   // TEXT
+	Points       decimal.Decimal `db:"points"`        // DECIMAL NOT NULL
+	TargetAmount decimal.Decimal `db:"target_amount"` // DECIMAL NOT NULL DEFAULT 0
+	StartedAt    *time.Time      `db:"started_at"`    // TIMESTAMP NULL
+	EndAt        *time.Time      `db:"end_at"`        // TIMESTAMP NULL
+	Period       int             `db:"period"`        // INT DEFAULT 1
+	ChainID      *int64          `db:"chain_id"`      // BIGINT NULL
+	CreatedAt    time.Time       `db:"created_at"`    // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	UpdatedAt    time.Time       `db:"updated_at"`    // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 }
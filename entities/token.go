@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+type Token struct {
+	ID        int64     `db:"id"`         // SERIAL PRIMARY KEY
+	ChainID   int64     `db:"chain_id"`   // BIGINT NOT NULL
+	Address   string    `db:"address"`    // VARCHAR(42) NOT NULL
+	Symbol    string    `db:"symbol"`     // VARCHAR(64) NOT NULL
+	Decimals  int64     `db:"decimals"`   // INT NOT NULL
+	CreatedAt time.Time `db:"created_at"` // TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+}
@@ -18,12 +18,18 @@ type IRedisHelper interface {
 	HGet(key string, field string) (string, error)
 	HGetAll(key string) (map[string]string, error)
 	HIncrFloat(key string, field string, value float64) error
+	HIncrByWithTotal(key string, field string, totalKey string, value int64) (int64, error)
+	ScaleToIntegers(key string, totalKey string, markerKey string, factor int64) (bool, error)
 	ZAdd(key string, members ...*redis.Z) error
 	ZRange(key string, start, stop int64) ([]string, error)
 	ZRangeWithScores(key string, start, stop int64) ([]string, []float64, error)
 	ZRevRange(key string, start, stop int64) ([]string, error)
 	ZRevRangeWithScores(key string, start, stop int64) ([]string, []float64, error)
 	SetTTL(key string, expiration time.Duration) error
+	Incr(key string) (int64, error)
+	SetNX(key string, value string, expiration time.Duration) (bool, error)
+	ExpireIfValue(key string, value string, expiration time.Duration) (bool, error)
+	DeleteIfValue(key string, value string) (bool, error)
 }
 
 type RedisHelper struct {
@@ -118,6 +124,57 @@ func (r *RedisHelper) HIncrFloat(key string, field string, value float64) error
 	return nil
 }
 
+var hIncrByWithTotalScript = redis.NewScript(`
+local total = redis.call("GET", KEYS[2])
+if total and not string.match(total, "^-?%d+$") then
+	return redis.error_reply("ERR total value is not an integer")
+end
+local value = redis.call("HINCRBY", KEYS[1], ARGV[1], ARGV[2])
+redis.call("INCRBY", KEYS[2], ARGV[2])
+return value
+`)
+
+// HIncrByWithTotal increments an integer hash field and an integer total key in one script and
+// returns the new value of the hash field. Integer counters keep sums exact. The total is checked
+// before anything is written, so a value that is not an integer fails both increments.
+func (r *RedisHelper) HIncrByWithTotal(key string, field string, totalKey string, value int64) (int64, error) {
+	val, err := hIncrByWithTotalScript.Run(context.Background(), r.redisClient, []string{r.prefix + key, r.prefix + totalKey}, field, value).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to HIncrBy field %s in key %s with total %s: %w", field, key, totalKey, err)
+	}
+
+	return val, nil
+}
+
+var scaleToIntegersScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[3]) == 1 then
+	return 0
+end
+local factor = tonumber(ARGV[1])
+local values = redis.call("HGETALL", KEYS[1])
+for i = 1, #values, 2 do
+	redis.call("HSET", KEYS[1], values[i], string.format("%.0f", tonumber(values[i + 1]) * factor))
+end
+local total = redis.call("GET", KEYS[2])
+if total then
+	redis.call("SET", KEYS[2], string.format("%.0f", tonumber(total) * factor), "KEEPTTL")
+end
+redis.call("SET", KEYS[3], "1")
+return 1
+`)
+
+// ScaleToIntegers multiplies the float fields of the hash key and the float totalKey by factor and
+// rounds them to integers. It runs once per markerKey, which is set in the same script, and reports
+// whether the values were converted by this call.
+func (r *RedisHelper) ScaleToIntegers(key string, totalKey string, markerKey string, factor int64) (bool, error) {
+	n, err := scaleToIntegersScript.Run(context.Background(), r.redisClient, []string{r.prefix + key, r.prefix + totalKey, r.prefix + markerKey}, factor).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to scale key %s with total %s: %w", key, totalKey, err)
+	}
+
+	return n == 1, nil
+}
+
 func (r *RedisHelper) ZAdd(key string, members ...*redis.Z) error {
 	err := r.redisClient.ZAdd(context.Background(), r.prefix+key, members...).Err()
 	if err != nil {
@@ -187,3 +244,57 @@ func (r *RedisHelper) SetTTL(key string, expiration time.Duration) error {
 
 	return nil
 }
+
+func (r *RedisHelper) Incr(key string) (int64, error) {
+	val, err := r.redisClient.Incr(context.Background(), r.prefix+key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to INCR key %s: %w", key, err)
+	}
+
+	return val, nil
+}
+
+// SetNX sets the key only when it does not exist and reports whether it was set.
+func (r *RedisHelper) SetNX(key string, value string, expiration time.Duration) (bool, error) {
+	ok, err := r.redisClient.SetNX(context.Background(), r.prefix+key, value, expiration).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to SETNX key %s: %w", key, err)
+	}
+
+	return ok, nil
+}
+
+var expireIfValueScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// ExpireIfValue resets the expiration of the key only while it still holds the given value, in one
+// script so a key taken over by another value in between is left alone.
+func (r *RedisHelper) ExpireIfValue(key string, value string, expiration time.Duration) (bool, error) {
+	n, err := expireIfValueScript.Run(context.Background(), r.redisClient, []string{r.prefix + key}, value, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to expire key %s: %w", key, err)
+	}
+
+	return n == 1, nil
+}
+
+var deleteIfValueScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// DeleteIfValue deletes the key only while it still holds the given value.
+func (r *RedisHelper) DeleteIfValue(key string, value string) (bool, error) {
+	n, err := deleteIfValueScript.Run(context.Background(), r.redisClient, []string{r.prefix + key}, value).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+
+	return n == 1, nil
+}
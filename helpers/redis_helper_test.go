@@ -162,6 +162,53 @@ func TestRedisHelper_HIncrFloat(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRedisHelper_HIncrByWithTotal(t *testing.T) {
+	r, mock := setupRedisHelper()
+
+	key := "key"
+	field := "field"
+	totalKey := "key_total"
+	var increment int64 = 1500000
+
+	keys := []string{"test:" + key, "test:" + totalKey}
+	mock.ExpectEvalSha(hIncrByWithTotalScript.Hash(), keys, field, increment).SetVal(int64(2500000))
+
+	val, err := r.HIncrByWithTotal(key, field, totalKey, increment)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2500000), val)
+
+	// a float left by an older release fails both increments
+	mock.ExpectEvalSha(hIncrByWithTotalScript.Hash(), keys, field, increment).SetErr(errors.New("ERR total value is not an integer"))
+
+	_, err = r.HIncrByWithTotal(key, field, totalKey, increment)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisHelper_ScaleToIntegers(t *testing.T) {
+	r, mock := setupRedisHelper()
+
+	keys := []string{"test:key", "test:key_total", "test:key_units"}
+	mock.ExpectEvalSha(scaleToIntegersScript.Hash(), keys, int64(1000000)).SetVal(int64(1))
+
+	converted, err := r.ScaleToIntegers("key", "key_total", "key_units", 1000000)
+	assert.NoError(t, err)
+	assert.True(t, converted)
+
+	// the marker is set, so a second call leaves the values alone
+	mock.ExpectEvalSha(scaleToIntegersScript.Hash(), keys, int64(1000000)).SetVal(int64(0))
+
+	converted, err = r.ScaleToIntegers("key", "key_total", "key_units", 1000000)
+	assert.NoError(t, err)
+	assert.False(t, converted)
+
+	mock.ExpectEvalSha(scaleToIntegersScript.Hash(), keys, int64(1000000)).SetErr(errors.New("redis error"))
+
+	_, err = r.ScaleToIntegers("key", "key_total", "key_units", 1000000)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRedisHelper_ZAdd(t *testing.T) {
 	r, mock := setupRedisHelper()
 
@@ -338,3 +385,101 @@ func TestRedisHelper_ZRevRangeWithScores(t *testing.T) {
 	assert.Nil(t, values)
 	assert.Nil(t, scores)
 }
+
+func TestRedisHelper_Incr(t *testing.T) {
+	r, mock := setupRedisHelper()
+
+	key := "key"
+
+	mock.ExpectIncr("test:" + key).SetVal(3)
+
+	val, err := r.Incr(key)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), val)
+
+	// Simulate redis error
+	mock.ExpectIncr("test:" + key).SetErr(errors.New("redis error"))
+
+	_, err = r.Incr(key)
+	assert.Error(t, err)
+}
+
+func TestRedisHelper_SetNX(t *testing.T) {
+	r, mock := setupRedisHelper()
+
+	key := "key"
+	value := "value"
+	expiration := time.Minute
+
+	mock.ExpectSetNX("test:"+key, value, expiration).SetVal(true)
+
+	ok, err := r.SetNX(key, value, expiration)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the key exists already
+	mock.ExpectSetNX("test:"+key, value, expiration).SetVal(false)
+
+	ok, err = r.SetNX(key, value, expiration)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Simulate redis error
+	mock.ExpectSetNX("test:"+key, value, expiration).SetErr(errors.New("redis error"))
+
+	_, err = r.SetNX(key, value, expiration)
+	assert.Error(t, err)
+}
+
+func TestRedisHelper_ExpireIfValue(t *testing.T) {
+	r, mock := setupRedisHelper()
+
+	key := "key"
+	value := "value"
+	expiration := time.Minute
+
+	mock.ExpectEvalSha(expireIfValueScript.Hash(), []string{"test:" + key}, value, int64(60000)).SetVal(int64(1))
+
+	ok, err := r.ExpireIfValue(key, value, expiration)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the key holds another value
+	mock.ExpectEvalSha(expireIfValueScript.Hash(), []string{"test:" + key}, value, int64(60000)).SetVal(int64(0))
+
+	ok, err = r.ExpireIfValue(key, value, expiration)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Simulate redis error
+	mock.ExpectEvalSha(expireIfValueScript.Hash(), []string{"test:" + key}, value, int64(60000)).SetErr(errors.New("redis error"))
+
+	_, err = r.ExpireIfValue(key, value, expiration)
+	assert.Error(t, err)
+}
+
+func TestRedisHelper_DeleteIfValue(t *testing.T) {
+	r, mock := setupRedisHelper()
+
+	key := "key"
+	value := "value"
+
+	mock.ExpectEvalSha(deleteIfValueScript.Hash(), []string{"test:" + key}, value).SetVal(int64(1))
+
+	ok, err := r.DeleteIfValue(key, value)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the key holds another value
+	mock.ExpectEvalSha(deleteIfValueScript.Hash(), []string{"test:" + key}, value).SetVal(int64(0))
+
+	ok, err = r.DeleteIfValue(key, value)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Simulate redis error
+	mock.ExpectEvalSha(deleteIfValueScript.Hash(), []string{"test:" + key}, value).SetErr(errors.New("redis error"))
+
+	_, err = r.DeleteIfValue(key, value)
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrLockHeld is returned by Acquire while another holder has the lock.
+var ErrLockHeld = errors.New("lock is held by another instance")
+
+type ILocker interface {
+	Acquire(name string, ttl time.Duration) (ILease, error)
+}
+
+// ILease is a held lock. Its fencing token grows with every acquisition of the lock, so writes made
+// under a lease that was lost can be told apart from those of the holder that took over.
+type ILease interface {
+	Token() int64
+	// Lost is closed once the lease could not be renewed and another instance may hold the lock.
+	Lost() <-chan struct{}
+	Release() error
+}
+
+// RedisLocker hands out locks kept in Redis with SET NX. A lease is renewed in the background every
+// third of its ttl while held, so it only expires when its holder dies or loses Redis.
+type RedisLocker struct {
+	redisHelper IRedisHelper
+}
+
+func NewRedisLocker(redisHelper IRedisHelper) ILocker {
+	return &RedisLocker{
+		redisHelper: redisHelper,
+	}
+}
+
+func lockKey(name string) string {
+	return fmt.Sprintf("lock_%s", name)
+}
+
+func lockFencingTokenKey(name string) string {
+	return fmt.Sprintf("lock_%s_fencing_token", name)
+}
+
+// Acquire takes the named lock for the given ttl, ErrLockHeld when another holder has it.
+func (l *RedisLocker) Acquire(name string, ttl time.Duration) (ILease, error) {
+	token, err := l.redisHelper.Incr(lockFencingTokenKey(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to take a fencing token for lock %s: %w", name, err)
+	}
+
+	// the token is unique to this acquisition, so it tells the holder apart as well
+	value := strconv.FormatInt(token, 10)
+
+	ok, err := l.redisHelper.SetNX(lockKey(name), value, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, ErrLockHeld)
+	}
+
+	lease := &redisLease{
+		redisHelper: l.redisHelper,
+		name:        name,
+		value:       value,
+		token:       token,
+		ttl:         ttl,
+		lost:        make(chan struct{}),
+		stopCh:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	go lease.renew()
+
+	return lease, nil
+}
+
+type redisLease struct {
+	redisHelper IRedisHelper
+	name        string
+	value       string
+	token       int64
+	ttl         time.Duration
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	// closed by Release to stop renew
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// closed when renew returns
+	done chan struct{}
+}
+
+func (l *redisLease) Token() int64 {
+	return l.token
+}
+
+func (l *redisLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing the lease and frees the lock unless another holder has taken it over.
+func (l *redisLease) Release() error {
+	l.stopOnce.Do(func() { close(l.stopCh) })
+	<-l.done
+
+	if _, err := l.redisHelper.DeleteIfValue(lockKey(l.name), l.value); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", l.name, err)
+	}
+
+	return nil
+}
+
+// renew extends the lease until Release is called. The lease is lost once the lock holds another
+// value, or when it could not be extended for a whole ttl and may have expired.
+func (l *redisLease) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-l.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := l.redisHelper.ExpireIfValue(lockKey(l.name), l.value, l.ttl)
+		if err == nil && !ok {
+			l.lose()
+			return
+		}
+
+		if err == nil {
+			renewedAt = time.Now()
+			continue
+		}
+
+		if time.Since(renewedAt) >= l.ttl {
+			l.lose()
+			return
+		}
+	}
+}
+
+func (l *redisLease) lose() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
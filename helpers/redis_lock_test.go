@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLocker_Acquire(t *testing.T) {
+	r, mock := setupRedisHelper()
+	locker := NewRedisLocker(r)
+
+	ttl := time.Minute
+
+	mock.ExpectIncr("test:lock_settlement_fencing_token").SetVal(5)
+	mock.ExpectSetNX("test:lock_settlement", "5", ttl).SetVal(true)
+
+	lease, err := locker.Acquire("settlement", ttl)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), lease.Token())
+
+	// another instance holds the lock, the next acquisition has a greater token
+	mock.ExpectIncr("test:lock_settlement_fencing_token").SetVal(6)
+	mock.ExpectSetNX("test:lock_settlement", "6", ttl).SetVal(false)
+
+	_, err = locker.Acquire("settlement", ttl)
+	assert.ErrorIs(t, err, ErrLockHeld)
+
+	// only the holder's value is deleted
+	mock.ExpectEvalSha(deleteIfValueScript.Hash(), []string{"test:lock_settlement"}, "5").SetVal(int64(1))
+
+	assert.NoError(t, lease.Release())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisLocker_AcquireRedisError(t *testing.T) {
+	r, mock := setupRedisHelper()
+	locker := NewRedisLocker(r)
+
+	mock.ExpectIncr("test:lock_settlement_fencing_token").SetErr(errors.New("redis error"))
+
+	_, err := locker.Acquire("settlement", time.Minute)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrLockHeld)
+}
+
+func TestRedisLease_Renew(t *testing.T) {
+	r, mock := setupRedisHelper()
+	locker := NewRedisLocker(r)
+
+	ttl := 30 * time.Millisecond
+
+	mock.ExpectIncr("test:lock_settlement_fencing_token").SetVal(1)
+	mock.ExpectSetNX("test:lock_settlement", "1", ttl).SetVal(true)
+	mock.ExpectEvalSha(expireIfValueScript.Hash(), []string{"test:lock_settlement"}, "1", int64(30)).SetVal(int64(1))
+	// the lock expired meanwhile and another instance took it
+	mock.ExpectEvalSha(expireIfValueScript.Hash(), []string{"test:lock_settlement"}, "1", int64(30)).SetVal(int64(0))
+
+	lease, err := locker.Acquire("settlement", ttl)
+	assert.NoError(t, err)
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lease was not lost")
+	}
+
+	mock.ExpectEvalSha(deleteIfValueScript.Hash(), []string{"test:lock_settlement"}, "1").SetVal(int64(0))
+
+	assert.NoError(t, lease.Release())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,7 +3,11 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"time"
 	"trading-ace/config"
 	"trading-ace/controllers"
 	"trading-ace/helpers"
@@ -21,7 +25,14 @@ import (
 
 var ctx = context.Background()
 
-func NewDB(config *config.Config) (*sql.DB, error) {
+var fromBlock = flag.Uint64("from-block", 0, "backfill swap events starting from this block instead of starting the server")
+var toBlock = flag.Uint64("to-block", 0, "last block to backfill, defaults to the latest block")
+var chainID = flag.Int64("chain-id", config.MainnetChainID, "chain to backfill")
+
+// time allowed to drain the swap queue on shutdown
+const shutdownTimeout = time.Minute
+
+func NewDB(lc fx.Lifecycle, config *config.Config) (*sql.DB, error) {
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		config.Database.User,
 		config.Database.Password,
@@ -40,10 +51,16 @@ func NewDB(config *config.Config) (*sql.DB, error) {
 		return nil, err
 	}
 
+	lc.Append(fx.Hook{
+		OnStop: func(context.Context) error {
+			return db.Close()
+		},
+	})
+
 	return db, nil
 }
 
-func NewRedis(config *config.Config) (*redis.Client, error) {
+func NewRedis(lc fx.Lifecycle, config *config.Config) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
 		Password: "",
@@ -55,6 +72,12 @@ func NewRedis(config *config.Config) (*redis.Client, error) {
 		return nil, err
 	}
 
+	lc.Append(fx.Hook{
+		OnStop: func(context.Context) error {
+			return rdb.Close()
+		},
+	})
+
 	return rdb, nil
 }
 
@@ -64,23 +87,106 @@ func NewGinServer() *gin.Engine {
 }
 
 func SetupServer(
+	lc fx.Lifecycle,
 	r *gin.Engine,
 	logger logger.ILogger,
 	config *config.Config,
 	ethereumService services.IEthereumService,
+	settlementScheduler services.ISettlementScheduler,
 	homeRoutes routes.IHomeRoutes,
 	campaignRoutes routes.ICampaignRoutes,
+	adminRoutes routes.IAdminRoutes,
 ) {
-	go ethereumService.SubscribeEthereumSwap()
-
 	homeRoutes.RegisterHomeRoutes()
 	campaignRoutes.RegisterCampaignRoutes()
+	adminRoutes.RegisterAdminRoutes()
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.Server.Port),
+		Handler: r,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go ethereumService.SubscribeEthereumSwap()
+			go settlementScheduler.Run()
+
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Error(fmt.Sprintf("server stopped: %v", err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := server.Shutdown(ctx); err != nil {
+				logger.Error(fmt.Sprintf("failed to shut down server: %v", err))
+			}
+
+			if err := settlementScheduler.Shutdown(ctx); err != nil {
+				logger.Error(err)
+			}
+
+			// queued swaps are credited before exiting
+			return ethereumService.Shutdown(ctx)
+		},
+	})
+}
+
+// RunBackfill backfills on start, after the connections it needs are set up. A failed backfill fails
+// the start, which stops what was started already.
+func RunBackfill(lc fx.Lifecycle, logger logger.ILogger, ethereumService services.IEthereumService) {
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if err := ethereumService.BackfillSwapEvents(*chainID, *fromBlock, *toBlock); err != nil {
+				return fmt.Errorf("backfill failed: %w", err)
+			}
+
+			logger.Info("backfill completed")
+
+			return nil
+		},
+	})
+}
+
+// isFlagSet reports whether a flag was given on the command line, a flag left at its default is not.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
 
-	r.Run(fmt.Sprintf(":%d", config.Server.Port))
+	return set
+}
+
+// runToCompletion starts the app, which runs the backfill, and stops it so the connections are closed.
+func runToCompletion(app *fx.App) error {
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+
+	return app.Stop(stopCtx)
 }
 
 func main() {
+	flag.Parse()
+
+	// block 0 can be backfilled too, the mode is picked by the flag being given
+	backfill := isFlagSet("from-block")
+
+	invoke := fx.Invoke(SetupServer)
+	if backfill {
+		invoke = fx.Invoke(RunBackfill)
+	}
+
 	app := fx.New(
+		fx.StopTimeout(shutdownTimeout),
 		fx.Provide(
 
 			// Base
@@ -93,24 +199,45 @@ func main() {
 			// Controllers
 			controllers.NewHomeController,
 			controllers.NewCampaignController,
+			controllers.NewAdminController,
 
 			// Repositories
 			repositories.NewTaskRepository,
 			repositories.NewTaskHistoryRepository,
+			repositories.NewIngestionCheckpointRepository,
+			repositories.NewSwapEventRepository,
+			repositories.NewFailedSwapLogRepository,
+			repositories.NewTokenRepository,
+			repositories.NewCampaignRepository,
+			repositories.NewSettlementJobRepository,
+			repositories.NewTransactor,
 
 			// Routes
 			routes.NewHomeRoutes,
 			routes.NewCampaignRoutes,
+			routes.NewAdminRoutes,
 
 			// Services
 			services.NewCampaignService,
 			services.NewEthereumService,
+			services.NewTokenRegistry,
+			services.NewSettlementScheduler,
 
 			// Helper
 			helpers.NewRedisHelper,
+			helpers.NewRedisLocker,
 		),
-		fx.Invoke(SetupServer),
+		invoke,
 	)
 
-	app.Start(ctx)
+	// backfill runs to completion on start, the server runs until interrupted
+	if backfill {
+		if err := runToCompletion(app); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	app.Run()
 }
@@ -0,0 +1,11 @@
+-- how far swap log ingestion got per chain and contract
+CREATE TABLE ingestion_checkpoints (
+    id SERIAL PRIMARY KEY,
+    chain VARCHAR(64) NOT NULL,
+    contract_address VARCHAR(255) NOT NULL,
+    block_number BIGINT NOT NULL,
+    log_index INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT ingestion_checkpoints_chain_contract_address_unique UNIQUE (chain, contract_address)
+);
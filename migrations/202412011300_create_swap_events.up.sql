@@ -0,0 +1,15 @@
+-- decoded swap logs, (tx_hash, log_index) makes ingestion idempotent
+CREATE TABLE swap_events (
+    id SERIAL PRIMARY KEY,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    block_number BIGINT NOT NULL,
+    contract_address VARCHAR(255) NOT NULL,
+    sender_address VARCHAR(255) NOT NULL,
+    amount0_in NUMERIC(78, 0) NOT NULL,
+    amount1_in NUMERIC(78, 0) NOT NULL,
+    amount0_out NUMERIC(78, 0) NOT NULL,
+    amount1_out NUMERIC(78, 0) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT swap_events_tx_hash_log_index_unique UNIQUE (tx_hash, log_index)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE swap_events
+    DROP COLUMN price_usd,
+    DROP COLUMN volume_usd;
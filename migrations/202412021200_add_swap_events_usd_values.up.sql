@@ -0,0 +1,4 @@
+-- USD price of the non-stablecoin leg and the volume credited, NULL for swaps stored before pricing
+ALTER TABLE swap_events
+    ADD COLUMN price_usd NUMERIC,
+    ADD COLUMN volume_usd NUMERIC;
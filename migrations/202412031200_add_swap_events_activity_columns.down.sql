@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS swap_events_sender_address_block_number_idx;
+
+ALTER TABLE swap_events
+    DROP COLUMN block_timestamp,
+    DROP COLUMN task_id;
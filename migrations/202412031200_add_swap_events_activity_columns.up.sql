@@ -0,0 +1,6 @@
+-- block time of the swap and the share pool task it was credited to, for the swap activity API
+ALTER TABLE swap_events
+    ADD COLUMN block_timestamp TIMESTAMP NULL,
+    ADD COLUMN task_id INT NULL REFERENCES tasks(id);
+
+CREATE INDEX swap_events_sender_address_block_number_idx ON swap_events (sender_address, block_number DESC, log_index DESC);
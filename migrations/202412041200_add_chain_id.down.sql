@@ -0,0 +1,12 @@
+ALTER TABLE tasks
+    DROP COLUMN chain_id;
+
+ALTER TABLE ingestion_checkpoints
+    DROP CONSTRAINT ingestion_checkpoints_chain_id_contract_address_unique,
+    ADD CONSTRAINT ingestion_checkpoints_chain_contract_address_unique UNIQUE (chain, contract_address),
+    DROP COLUMN chain_id;
+
+ALTER TABLE swap_events
+    DROP CONSTRAINT swap_events_chain_id_tx_hash_log_index_unique,
+    ADD CONSTRAINT swap_events_tx_hash_log_index_unique UNIQUE (tx_hash, log_index),
+    DROP COLUMN chain_id;
@@ -0,0 +1,14 @@
+-- chain of every swap and checkpoint, rows stored before chains were configurable are mainnet
+ALTER TABLE swap_events
+    ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 1,
+    DROP CONSTRAINT swap_events_tx_hash_log_index_unique,
+    ADD CONSTRAINT swap_events_chain_id_tx_hash_log_index_unique UNIQUE (chain_id, tx_hash, log_index);
+
+ALTER TABLE ingestion_checkpoints
+    ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 1,
+    DROP CONSTRAINT ingestion_checkpoints_chain_contract_address_unique,
+    ADD CONSTRAINT ingestion_checkpoints_chain_id_contract_address_unique UNIQUE (chain_id, contract_address);
+
+-- a task with a chain only counts the swaps of that chain, NULL adds up every chain
+ALTER TABLE tasks
+    ADD COLUMN chain_id BIGINT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS failed_swap_logs;
@@ -0,0 +1,17 @@
+-- swap logs that failed to decode or credit, kept with the raw log until retried or discarded
+CREATE TABLE failed_swap_logs (
+    id SERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_timestamp TIMESTAMP NULL,
+    contract_address VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    stage VARCHAR(16) NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT failed_swap_logs_chain_id_tx_hash_log_index_unique UNIQUE (chain_id, tx_hash, log_index)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pools;
+DROP TABLE IF EXISTS tokens;
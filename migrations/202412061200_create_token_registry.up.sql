@@ -0,0 +1,21 @@
+-- ERC-20 token metadata per chain, resolved on chain or seeded from config
+CREATE TABLE tokens (
+    id SERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    symbol VARCHAR(64) NOT NULL,
+    decimals INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT tokens_chain_id_address_unique UNIQUE (chain_id, address)
+);
+
+-- the token0 and token1 of every tracked pool
+CREATE TABLE pools (
+    id SERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    token0_address VARCHAR(42) NOT NULL,
+    token1_address VARCHAR(42) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pools_chain_id_address_unique UNIQUE (chain_id, address)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+    DROP COLUMN target_amount,
+    DROP COLUMN campaign_id;
+
+DROP TABLE campaigns;
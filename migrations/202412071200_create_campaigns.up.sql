@@ -0,0 +1,16 @@
+-- campaigns, their tasks are created from a campaign definition
+CREATE TABLE campaigns (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- tasks created before campaigns were stored have no campaign
+ALTER TABLE tasks
+    ADD COLUMN campaign_id INT NULL REFERENCES campaigns(id),
+    ADD COLUMN target_amount DECIMAL NOT NULL DEFAULT 0;
+
+-- the onboarding target used to be fixed at 1000
+UPDATE tasks SET target_amount = 1000 WHERE name = 'OnboardingTask';
@@ -0,0 +1,10 @@
+ALTER TABLE swap_events
+    ADD COLUMN task_id INT NULL REFERENCES tasks(id);
+
+-- a swap credited to several campaigns keeps one of its tasks
+UPDATE swap_events se
+SET task_id = (SELECT MIN(st.task_id) FROM swap_event_tasks st WHERE st.swap_event_id = se.id);
+
+DROP TABLE swap_event_tasks;
+
+DROP TABLE campaign_pools;
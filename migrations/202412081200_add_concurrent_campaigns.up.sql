@@ -0,0 +1,20 @@
+-- the pools a campaign counts swaps of, a campaign without pools counts every tracked pool
+CREATE TABLE campaign_pools (
+    campaign_id INT NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    chain_id BIGINT NOT NULL,
+    pool_address VARCHAR(42) NOT NULL,
+    PRIMARY KEY (campaign_id, chain_id, pool_address)
+);
+
+-- a swap is credited to the share pool task of every campaign it matches
+CREATE TABLE swap_event_tasks (
+    swap_event_id INT NOT NULL REFERENCES swap_events(id) ON DELETE CASCADE,
+    task_id INT NOT NULL REFERENCES tasks(id),
+    PRIMARY KEY (swap_event_id, task_id)
+);
+
+INSERT INTO swap_event_tasks (swap_event_id, task_id)
+SELECT id, task_id FROM swap_events WHERE task_id IS NOT NULL;
+
+ALTER TABLE swap_events
+    DROP COLUMN task_id;
@@ -0,0 +1,23 @@
+-- share pool periods to settle, one job per task, picked up once due by the settlement scheduler
+CREATE TABLE settlement_jobs (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(id),
+    due_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    settled_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT settlement_jobs_task_id_unique UNIQUE (task_id)
+);
+
+CREATE INDEX settlement_jobs_status_due_at_idx ON settlement_jobs (status, due_at);
+
+-- periods were settled by an in-memory ticker, those without rewards yet are still to settle
+INSERT INTO settlement_jobs (task_id, due_at, status, settled_at)
+SELECT t.id, t.end_at,
+       CASE WHEN EXISTS (SELECT 1 FROM task_histories th WHERE th.task_id = t.id) THEN 'settled' ELSE 'pending' END,
+       (SELECT MAX(th.completed_at) FROM task_histories th WHERE th.task_id = t.id)
+FROM tasks t
+WHERE t.name = 'SharePoolTask' AND t.end_at IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE settlement_jobs
+    DROP COLUMN fencing_token;
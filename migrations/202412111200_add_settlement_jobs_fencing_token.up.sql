@@ -0,0 +1,4 @@
+-- fencing token of the settlement lock holder that last claimed the job, a job only takes a claim
+-- with the same or a greater token so a holder that lost the lock cannot settle it anymore
+ALTER TABLE settlement_jobs
+    ADD COLUMN fencing_token BIGINT NOT NULL DEFAULT 0;
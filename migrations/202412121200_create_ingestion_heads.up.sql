@@ -0,0 +1,10 @@
+-- the safe head block ingestion of a chain has credited every swap up to, settlement waits for it
+-- to pass the end of a period. It moves on with every block, swaps or not.
+CREATE TABLE ingestion_heads (
+    chain_id BIGINT PRIMARY KEY,
+    chain VARCHAR(64) NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_timestamp TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"database/sql"
+	"trading-ace/entities"
+	"trading-ace/repositories"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCampaignRepository struct {
+	mock.Mock
+}
+
+func (m *MockCampaignRepository) Create(campaign *entities.Campaign) (*entities.Campaign, error) {
+	args := m.Called(campaign)
+	return args.Get(0).(*entities.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) FindByID(id int64) (*entities.Campaign, error) {
+	args := m.Called(id)
+	return args.Get(0).(*entities.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) FindLatest() (*entities.Campaign, error) {
+	args := m.Called()
+	return args.Get(0).(*entities.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) List() ([]*entities.Campaign, error) {
+	args := m.Called()
+	return args.Get(0).([]*entities.Campaign), args.Error(1)
+}
+
+// WithTx returns the mock itself, calls made in a transaction are expected on it as well.
+func (m *MockCampaignRepository) WithTx(tx *sql.Tx) repositories.ICampaignRepository {
+	return m
+}
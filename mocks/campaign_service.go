@@ -1,6 +1,9 @@
 package mocks
 
 import (
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
 	"trading-ace/entities"
 	"trading-ace/models"
 
@@ -16,32 +19,62 @@ func (m *MockCampaignService) StartCampaign() error {
 	return args.Error(0)
 }
 
-func (m *MockCampaignService) GetPointHistories(address string) ([]*models.TaskTaskHistoryPair, error) {
-	args := m.Called(address)
+func (m *MockCampaignService) CreateCampaign(definition config.CampaignDefinition) (*entities.Campaign, error) {
+	args := m.Called(definition)
+	return args.Get(0).(*entities.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignService) ListCampaigns() ([]*entities.Campaign, error) {
+	args := m.Called()
+	return args.Get(0).([]*entities.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignService) GetPointHistories(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error) {
+	args := m.Called(address, campaignID)
 	return args.Get(0).([]*models.TaskTaskHistoryPair), args.Error(1)
 }
 
-func (m *MockCampaignService) RecordUSDCSwapTotalAmount(senderAddress string, amount float64) (float64, error) {
-	args := m.Called(senderAddress, amount)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockCampaignService) RecordUSDCSwapTotalAmount(task *entities.Task, senderAddress string, amount decimal.Decimal, swappedAt time.Time) (decimal.Decimal, error) {
+	args := m.Called(task, senderAddress, amount, swappedAt)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
 }
 
-func (m *MockCampaignService) GetTaskStatus(address string) ([]*models.TaskWithTaskHistory, error) {
-	args := m.Called(address)
+func (m *MockCampaignService) RevertUSDCSwapTotalAmount(task *entities.Task, senderAddress string, amount decimal.Decimal, swappedAt time.Time) (decimal.Decimal, error) {
+	args := m.Called(task, senderAddress, amount, swappedAt)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockCampaignService) GetTaskStatus(address string, campaignID int64) ([]*models.TaskWithTaskHistory, error) {
+	args := m.Called(address, campaignID)
 	return args.Get(0).([]*models.TaskWithTaskHistory), args.Error(1)
 }
 
-func (m *MockCampaignService) FindOnboardingTask() (*entities.Task, error) {
-	args := m.Called()
+func (m *MockCampaignService) FindOnboardingTask(campaignID *int64) (*entities.Task, error) {
+	args := m.Called(campaignID)
 	return args.Get(0).(*entities.Task), args.Error(1)
 }
 
-func (m *MockCampaignService) FindCurrentSharePoolTask() (*entities.Task, error) {
-	args := m.Called()
-	return args.Get(0).(*entities.Task), args.Error(1)
+func (m *MockCampaignService) GetSwapActivities(address string, campaignID int64, period int, page int, pageSize int) ([]*models.SwapActivity, int, error) {
+	args := m.Called(address, campaignID, period, page, pageSize)
+	return args.Get(0).([]*models.SwapActivity), args.Int(1), args.Error(2)
+}
+
+func (m *MockCampaignService) FindSharePoolTasksByIDs(ids []int64) ([]*entities.Task, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*entities.Task), args.Error(1)
+}
+
+func (m *MockCampaignService) FindSharePoolTasksAt(chainID int64, poolAddress string, at time.Time) ([]*entities.Task, error) {
+	args := m.Called(chainID, poolAddress, at)
+	return args.Get(0).([]*entities.Task), args.Error(1)
 }
 
-func (m *MockCampaignService) GetLeaderboard(taskName string, period int) ([]models.LeaderboardEntry, error) {
+func (m *MockCampaignService) GetLeaderboard(campaignID int64, taskName string, period int, chainID int64) ([]models.LeaderboardEntry, error) {
 	args := m.Called()
 	return args.Get(0).([]models.LeaderboardEntry), args.Error(1)
 }
+
+func (m *MockCampaignService) SettleSharePoolTask(task *entities.Task, jobID int64, fencingToken int64) error {
+	args := m.Called(task, jobID, fencingToken)
+	return args.Error(0)
+}
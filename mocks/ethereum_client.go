@@ -2,8 +2,10 @@ package mocks
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/mock"
 )
@@ -16,3 +18,37 @@ func (m *MockEthereumClient) SubscribeFilterLogs(ctx context.Context, query ethe
 	args := m.Called(ctx, query, logsCh)
 	return args.Get(0).(ethereum.Subscription), args.Error(1)
 }
+
+func (m *MockEthereumClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]types.Log), args.Error(1)
+}
+
+func (m *MockEthereumClient) Close() {
+	m.Called()
+}
+
+func (m *MockEthereumClient) BlockNumber(ctx context.Context) (uint64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockEthereumClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*types.Transaction), args.Bool(1), args.Error(2)
+}
+
+func (m *MockEthereumClient) TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error) {
+	args := m.Called(ctx, tx, block, index)
+	return args.Get(0).(common.Address), args.Error(1)
+}
+
+func (m *MockEthereumClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	args := m.Called(ctx, number)
+	return args.Get(0).(*types.Header), args.Error(1)
+}
+
+func (m *MockEthereumClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	args := m.Called(ctx, call, blockNumber)
+	return args.Get(0).([]byte), args.Error(1)
+}
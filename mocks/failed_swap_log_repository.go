@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"trading-ace/entities"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFailedSwapLogRepository struct {
+	mock.Mock
+}
+
+func (m *MockFailedSwapLogRepository) Record(failedLog *entities.FailedSwapLog) error {
+	args := m.Called(failedLog)
+	return args.Error(0)
+}
+
+func (m *MockFailedSwapLogRepository) FindByID(id int64) (*entities.FailedSwapLog, error) {
+	args := m.Called(id)
+	return args.Get(0).(*entities.FailedSwapLog), args.Error(1)
+}
+
+func (m *MockFailedSwapLogRepository) List(limit int, offset int) ([]*entities.FailedSwapLog, int, error) {
+	args := m.Called(limit, offset)
+	return args.Get(0).([]*entities.FailedSwapLog), args.Int(1), args.Error(2)
+}
+
+func (m *MockFailedSwapLogRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"trading-ace/entities"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIngestionCheckpointRepository struct {
+	mock.Mock
+}
+
+func (m *MockIngestionCheckpointRepository) FindByChainAndContract(chainID int64, contractAddress string) (*entities.IngestionCheckpoint, error) {
+	args := m.Called(chainID, contractAddress)
+	return args.Get(0).(*entities.IngestionCheckpoint), args.Error(1)
+}
+
+func (m *MockIngestionCheckpointRepository) Save(checkpoint *entities.IngestionCheckpoint) error {
+	args := m.Called(checkpoint)
+	return args.Error(0)
+}
+
+func (m *MockIngestionCheckpointRepository) Rewind(checkpoint *entities.IngestionCheckpoint) error {
+	args := m.Called(checkpoint)
+	return args.Error(0)
+}
+
+func (m *MockIngestionCheckpointRepository) SaveHead(head *entities.IngestionHead) error {
+	args := m.Called(head)
+	return args.Error(0)
+}
+
+func (m *MockIngestionCheckpointRepository) FindHead(chainID int64) (*entities.IngestionHead, error) {
+	args := m.Called(chainID)
+	return args.Get(0).(*entities.IngestionHead), args.Error(1)
+}
@@ -52,6 +52,16 @@ func (m *MockRedisHelper) HIncrFloat(key string, field string, value float64) er
 	return args.Error(0)
 }
 
+func (m *MockRedisHelper) HIncrByWithTotal(key string, field string, totalKey string, value int64) (int64, error) {
+	args := m.Called(key, field, totalKey, value)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRedisHelper) ScaleToIntegers(key string, totalKey string, markerKey string, factor int64) (bool, error) {
+	args := m.Called(key, totalKey, markerKey, factor)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockRedisHelper) ZAdd(key string, members ...*redis.Z) error {
 	args := m.Called(key, members)
 	return args.Error(0)
@@ -81,3 +91,23 @@ func (m *MockRedisHelper) SetTTL(key string, expiration time.Duration) error {
 	args := m.Called(key, expiration)
 	return args.Error(0)
 }
+
+func (m *MockRedisHelper) Incr(key string) (int64, error) {
+	args := m.Called(key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRedisHelper) SetNX(key string, value string, expiration time.Duration) (bool, error) {
+	args := m.Called(key, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRedisHelper) ExpireIfValue(key string, value string, expiration time.Duration) (bool, error) {
+	args := m.Called(key, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRedisHelper) DeleteIfValue(key string, value string) (bool, error) {
+	args := m.Called(key, value)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"database/sql"
+	"time"
+	"trading-ace/entities"
+	"trading-ace/repositories"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSettlementJobRepository struct {
+	mock.Mock
+}
+
+func (m *MockSettlementJobRepository) Create(job *entities.SettlementJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockSettlementJobRepository) FindDue(at time.Time) ([]*entities.SettlementJob, error) {
+	args := m.Called(at)
+	return args.Get(0).([]*entities.SettlementJob), args.Error(1)
+}
+
+func (m *MockSettlementJobRepository) FindNextDueAt(after time.Time) (*time.Time, error) {
+	args := m.Called(after)
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockSettlementJobRepository) Claim(id int64, fencingToken int64) (bool, error) {
+	args := m.Called(id, fencingToken)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSettlementJobRepository) LockClaim(id int64, fencingToken int64) error {
+	args := m.Called(id, fencingToken)
+	return args.Error(0)
+}
+
+func (m *MockSettlementJobRepository) MarkSettled(id int64, fencingToken int64) error {
+	args := m.Called(id, fencingToken)
+	return args.Error(0)
+}
+
+func (m *MockSettlementJobRepository) RecordFailure(id int64, fencingToken int64, message string, maxAttempts int) error {
+	args := m.Called(id, fencingToken, message, maxAttempts)
+	return args.Error(0)
+}
+
+// WithTx returns the mock itself, calls made in a transaction are expected on it as well.
+func (m *MockSettlementJobRepository) WithTx(tx *sql.Tx) repositories.ISettlementJobRepository {
+	return m
+}
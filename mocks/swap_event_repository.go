@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"trading-ace/entities"
+	"trading-ace/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSwapEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockSwapEventRepository) CreateIfNotExists(swapEvent *entities.SwapEvent) (bool, error) {
+	args := m.Called(swapEvent)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSwapEventRepository) DeleteByChainTxHashAndLogIndex(chainID int64, txHash string, logIndex uint) (*entities.SwapEvent, error) {
+	args := m.Called(chainID, txHash, logIndex)
+	return args.Get(0).(*entities.SwapEvent), args.Error(1)
+}
+
+func (m *MockSwapEventRepository) GetByAddress(address string, campaignID *int64, period int, limit int, offset int) ([]*models.SwapActivity, int, error) {
+	args := m.Called(address, campaignID, period, limit, offset)
+	return args.Get(0).([]*models.SwapActivity), args.Int(1), args.Error(2)
+}
@@ -1,8 +1,10 @@
 package mocks
 
 import (
+	"database/sql"
 	"trading-ace/entities"
 	"trading-ace/models"
+	"trading-ace/repositories"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -26,7 +28,17 @@ func (m *MockTaskHistoryRepository) FindByAddressAndTaskId(address string, taskI
 	return args.Get(0).(*entities.TaskHistory), args.Error(1)
 }
 
-func (m *MockTaskHistoryRepository) GetByAddressIncludingTasks(address string) ([]*models.TaskTaskHistoryPair, error) {
-	args := m.Called(address)
+func (m *MockTaskHistoryRepository) GetByAddressIncludingTasks(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error) {
+	args := m.Called(address, campaignID)
 	return args.Get(0).([]*models.TaskTaskHistoryPair), args.Error(1)
 }
+
+func (m *MockTaskHistoryRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// WithTx returns the mock itself, calls made in a transaction are expected on it as well.
+func (m *MockTaskHistoryRepository) WithTx(tx *sql.Tx) repositories.ITaskHistoryRepository {
+	return m
+}
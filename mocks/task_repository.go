@@ -1,8 +1,10 @@
 package mocks
 
 import (
+	"database/sql"
 	"trading-ace/entities"
 	"trading-ace/models"
+	"trading-ace/repositories"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -36,7 +38,12 @@ func (m *MockTaskRepository) IsExistedByName(name string) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockTaskRepository) GetByAddressAndNamesIncludingTaskHistories(address string, names []string) ([]*models.TaskWithTaskHistory, error) {
-	args := m.Called(address, names)
+func (m *MockTaskRepository) GetByAddressAndNamesIncludingTaskHistories(address string, campaignID int64, names []string) ([]*models.TaskWithTaskHistory, error) {
+	args := m.Called(address, campaignID, names)
 	return args.Get(0).([]*models.TaskWithTaskHistory), args.Error(1)
 }
+
+// WithTx returns the mock itself, calls made in a transaction are expected on it as well.
+func (m *MockTaskRepository) WithTx(tx *sql.Tx) repositories.ITaskRepository {
+	return m
+}
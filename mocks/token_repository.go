@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"trading-ace/entities"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) FindToken(chainID int64, address string) (*entities.Token, error) {
+	args := m.Called(chainID, address)
+	return args.Get(0).(*entities.Token), args.Error(1)
+}
+
+func (m *MockTokenRepository) CreateToken(token *entities.Token) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) FindPool(chainID int64, address string) (*entities.Pool, error) {
+	args := m.Called(chainID, address)
+	return args.Get(0).(*entities.Pool), args.Error(1)
+}
+
+func (m *MockTokenRepository) CreatePool(pool *entities.Pool) error {
+	args := m.Called(pool)
+	return args.Error(0)
+}
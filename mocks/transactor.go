@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"database/sql"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransactor struct {
+	mock.Mock
+}
+
+// InTransaction runs fn with a nil transaction unless the expectation returns an error, a failed
+// begin. The repository mocks ignore the transaction.
+func (m *MockTransactor) InTransaction(fn func(tx *sql.Tx) error) error {
+	args := m.Called()
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return fn(nil)
+}
@@ -0,0 +1,9 @@
+package models
+
+import "trading-ace/entities"
+
+type PoolTokens struct {
+	Pool   *entities.Pool
+	Token0 *entities.Token
+	Token1 *entities.Token
+}
@@ -0,0 +1,9 @@
+package models
+
+import "trading-ace/entities"
+
+type SwapActivity struct {
+	SwapEvent *entities.SwapEvent
+	// period of the share pool task the swap was credited to, nil if it was not credited to one
+	TaskPeriod *int
+}
@@ -1,11 +1,20 @@
 package models
 
-import "math/big"
+import (
+	"math/big"
+	"time"
+)
 
 type SwapEvent struct {
-	SenderAddress string
-	Amount0In     *big.Int
-	Amount1In     *big.Int
-	Amount0Out    *big.Int
-	Amount1Out    *big.Int
+	ChainID        int64
+	TxHash         string
+	LogIndex       uint
+	BlockNumber    uint64
+	BlockTimestamp time.Time
+	PoolAddress    string
+	SenderAddress  string
+	Amount0In      *big.Int
+	Amount1In      *big.Int
+	Amount0Out     *big.Int
+	Amount1Out     *big.Int
 }
@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"trading-ace/entities"
+
+	"github.com/lib/pq"
+)
+
+type ICampaignRepository interface {
+	Create(campaign *entities.Campaign) (*entities.Campaign, error)
+	FindByID(id int64) (*entities.Campaign, error)
+	FindLatest() (*entities.Campaign, error)
+	List() ([]*entities.Campaign, error)
+	WithTx(tx *sql.Tx) ICampaignRepository
+}
+
+type CampaignRepository struct {
+	db DBTX
+}
+
+func NewCampaignRepository(db *sql.DB) ICampaignRepository {
+	return &CampaignRepository{
+		db: db,
+	}
+}
+
+// WithTx returns the repository writing in the transaction tx.
+func (r *CampaignRepository) WithTx(tx *sql.Tx) ICampaignRepository {
+	return &CampaignRepository{
+		db: tx,
+	}
+}
+
+// Create stores the campaign together with its pools.
+func (r *CampaignRepository) Create(campaign *entities.Campaign) (*entities.Campaign, error) {
+	query := `
+		WITH created AS (
+			INSERT INTO campaigns (name, started_at, end_at, created_at)
+			VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+			RETURNING id, name, started_at, end_at, created_at
+		), pools AS (
+			INSERT INTO campaign_pools (campaign_id, chain_id, pool_address)
+			SELECT created.id, pool.chain_id, pool.pool_address
+			FROM created, unnest($4::bigint[], $5::varchar[]) AS pool (chain_id, pool_address)
+		)
+		SELECT id, name, started_at, end_at, created_at FROM created
+	`
+
+	chainIDs := []int64{}
+	poolAddresses := []string{}
+	for _, pool := range campaign.Pools {
+		chainIDs = append(chainIDs, pool.ChainID)
+		poolAddresses = append(poolAddresses, pool.PoolAddress)
+	}
+
+	var result entities.Campaign
+	err := r.db.QueryRow(query, campaign.Name, campaign.StartedAt, campaign.EndAt, pq.Array(chainIDs), pq.Array(poolAddresses)).Scan(
+		&result.ID, &result.Name, &result.StartedAt, &result.EndAt, &result.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	for _, pool := range campaign.Pools {
+		result.Pools = append(result.Pools, &entities.CampaignPool{CampaignID: result.ID, ChainID: pool.ChainID, PoolAddress: pool.PoolAddress})
+	}
+
+	return &result, nil
+}
+
+func (r *CampaignRepository) FindByID(id int64) (*entities.Campaign, error) {
+	query := `
+		SELECT id, name, started_at, end_at, created_at
+		FROM campaigns
+		WHERE id = $1
+	`
+
+	return r.findOne(query, id)
+}
+
+// FindLatest returns the campaign started last.
+func (r *CampaignRepository) FindLatest() (*entities.Campaign, error) {
+	query := `
+		SELECT id, name, started_at, end_at, created_at
+		FROM campaigns
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`
+
+	return r.findOne(query)
+}
+
+// List returns every campaign, active or ended, the latest first.
+func (r *CampaignRepository) List() ([]*entities.Campaign, error) {
+	query := `
+		SELECT id, name, started_at, end_at, created_at
+		FROM campaigns
+		ORDER BY started_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer rows.Close()
+
+	results := []*entities.Campaign{}
+	for rows.Next() {
+		campaign := &entities.Campaign{}
+
+		err := rows.Scan(&campaign.ID, &campaign.Name, &campaign.StartedAt, &campaign.EndAt, &campaign.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		results = append(results, campaign)
+	}
+
+	if err := r.loadPools(results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *CampaignRepository) findOne(query string, args ...interface{}) (*entities.Campaign, error) {
+	var result entities.Campaign
+	err := r.db.QueryRow(query, args...).Scan(
+		&result.ID, &result.Name, &result.StartedAt, &result.EndAt, &result.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("campaign not found: %w", err)
+		}
+
+		return nil, fmt.Errorf("failed to find campaign: %w", err)
+	}
+
+	if err := r.loadPools([]*entities.Campaign{&result}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// loadPools fills in the pools of the campaigns.
+func (r *CampaignRepository) loadPools(campaigns []*entities.Campaign) error {
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	ids := []int64{}
+	byID := map[int64]*entities.Campaign{}
+	for _, campaign := range campaigns {
+		ids = append(ids, campaign.ID)
+		byID[campaign.ID] = campaign
+	}
+
+	query := `
+		SELECT campaign_id, chain_id, pool_address
+		FROM campaign_pools
+		WHERE campaign_id = ANY($1)
+		ORDER BY campaign_id, chain_id, pool_address
+	`
+
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to find campaign pools: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		pool := &entities.CampaignPool{}
+		if err := rows.Scan(&pool.CampaignID, &pool.ChainID, &pool.PoolAddress); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+
+		if campaign, ok := byID[pool.CampaignID]; ok {
+			campaign.Pools = append(campaign.Pools, pool)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCampaign(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewCampaignRepository(db)
+
+	startedAt := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	endAt := startedAt.Add(28 * 24 * time.Hour)
+
+	// the pools are stored with the campaign
+	mock.ExpectQuery(`INSERT INTO campaigns \(name, started_at, end_at, created_at\)(.+)INSERT INTO campaign_pools`).
+		WithArgs("winter", startedAt, endAt, "{42161}", `{"0xPool"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "end_at", "created_at"}).
+			AddRow(2, "winter", startedAt, endAt, startedAt))
+
+	campaign, err := repo.Create(&entities.Campaign{
+		Name:      "winter",
+		StartedAt: startedAt,
+		EndAt:     endAt,
+		Pools:     []*entities.CampaignPool{{ChainID: 42161, PoolAddress: "0xPool"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), campaign.ID)
+	assert.Equal(t, endAt, campaign.EndAt)
+	assert.Equal(t, []*entities.CampaignPool{{CampaignID: 2, ChainID: 42161, PoolAddress: "0xPool"}}, campaign.Pools)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindLatestCampaign(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewCampaignRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, name, started_at, end_at, created_at
+		FROM campaigns
+		ORDER BY started_at DESC, id DESC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "end_at", "created_at"}).
+			AddRow(2, "winter", now, now, now))
+	mock.ExpectQuery(`SELECT campaign_id, chain_id, pool_address FROM campaign_pools`).
+		WithArgs("{2}").
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "chain_id", "pool_address"}))
+
+	campaign, err := repo.FindLatest()
+	assert.NoError(t, err)
+	assert.Equal(t, "winter", campaign.Name)
+	assert.Empty(t, campaign.Pools)
+
+	// no campaign yet
+	mock.ExpectQuery(`SELECT id, name, started_at, end_at, created_at`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.FindLatest()
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListCampaigns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewCampaignRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, name, started_at, end_at, created_at FROM campaigns ORDER BY started_at DESC, id DESC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "end_at", "created_at"}).
+			AddRow(3, "spring", now, now, now).
+			AddRow(2, "winter", now, now, now))
+	mock.ExpectQuery(`SELECT campaign_id, chain_id, pool_address FROM campaign_pools`).
+		WithArgs("{3,2}").
+		WillReturnRows(sqlmock.NewRows([]string{"campaign_id", "chain_id", "pool_address"}).
+			AddRow(3, 1, "0xA").
+			AddRow(3, 42161, "0xB"))
+
+	campaigns, err := repo.List()
+
+	assert.NoError(t, err)
+	assert.Len(t, campaigns, 2)
+	assert.Len(t, campaigns[0].Pools, 2)
+	assert.Equal(t, int64(42161), campaigns[0].Pools[1].ChainID)
+	assert.Empty(t, campaigns[1].Pools)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
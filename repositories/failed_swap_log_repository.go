@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"trading-ace/entities"
+)
+
+type IFailedSwapLogRepository interface {
+	Record(failedLog *entities.FailedSwapLog) error
+	FindByID(id int64) (*entities.FailedSwapLog, error)
+	List(limit int, offset int) ([]*entities.FailedSwapLog, int, error)
+	Delete(id int64) error
+}
+
+type FailedSwapLogRepository struct {
+	db *sql.DB
+}
+
+func NewFailedSwapLogRepository(db *sql.DB) IFailedSwapLogRepository {
+	return &FailedSwapLogRepository{
+		db: db,
+	}
+}
+
+// Record stores a failed log, a log that failed before keeps its row and counts one more attempt
+// with the latest stage and error.
+func (r *FailedSwapLogRepository) Record(failedLog *entities.FailedSwapLog) error {
+	query := `
+		INSERT INTO failed_swap_logs (chain_id, tx_hash, log_index, block_number, block_timestamp, contract_address, payload, stage, error, attempts, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (chain_id, tx_hash, log_index) DO UPDATE
+		SET block_timestamp = COALESCE(EXCLUDED.block_timestamp, failed_swap_logs.block_timestamp),
+		    stage = EXCLUDED.stage, error = EXCLUDED.error,
+		    attempts = failed_swap_logs.attempts + 1, updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.Exec(
+		query,
+		failedLog.ChainID, failedLog.TxHash, failedLog.LogIndex, failedLog.BlockNumber, failedLog.BlockTimestamp,
+		failedLog.ContractAddress, failedLog.Payload, failedLog.Stage, failedLog.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record failed swap log: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FailedSwapLogRepository) FindByID(id int64) (*entities.FailedSwapLog, error) {
+	query := `
+		SELECT id, chain_id, tx_hash, log_index, block_number, block_timestamp, contract_address, payload, stage, error, attempts, created_at, updated_at
+		FROM failed_swap_logs
+		WHERE id = $1
+	`
+
+	var result entities.FailedSwapLog
+	err := r.db.QueryRow(query, id).Scan(
+		&result.ID, &result.ChainID, &result.TxHash, &result.LogIndex, &result.BlockNumber, &result.BlockTimestamp,
+		&result.ContractAddress, &result.Payload, &result.Stage, &result.Error, &result.Attempts,
+		&result.CreatedAt, &result.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("failed swap log not found: %w", err)
+		}
+
+		return nil, fmt.Errorf("failed to find failed swap log: %w", err)
+	}
+
+	return &result, nil
+}
+
+// List returns a page of the failed logs, oldest first, together with the total number of failed
+// logs.
+func (r *FailedSwapLogRepository) List(limit int, offset int) ([]*entities.FailedSwapLog, int, error) {
+	// counted on its own, so a page past the end still reports the total
+	total := 0
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM failed_swap_logs`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count failed: %w", err)
+	}
+
+	query := `
+		SELECT id, chain_id, tx_hash, log_index, block_number, block_timestamp, contract_address, payload, stage, error, attempts, created_at, updated_at
+		FROM failed_swap_logs
+		ORDER BY id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer rows.Close()
+
+	results := []*entities.FailedSwapLog{}
+	for rows.Next() {
+		failedLog := &entities.FailedSwapLog{}
+
+		err := rows.Scan(
+			&failedLog.ID, &failedLog.ChainID, &failedLog.TxHash, &failedLog.LogIndex, &failedLog.BlockNumber, &failedLog.BlockTimestamp,
+			&failedLog.ContractAddress, &failedLog.Payload, &failedLog.Stage, &failedLog.Error, &failedLog.Attempts,
+			&failedLog.CreatedAt, &failedLog.UpdatedAt,
+		)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan failed: %w", err)
+		}
+
+		results = append(results, failedLog)
+	}
+
+	return results, total, nil
+}
+
+// Delete removes a failed log, it reports an error when there is no such log.
+func (r *FailedSwapLogRepository) Delete(id int64) error {
+	query := `
+		DELETE FROM failed_swap_logs
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete failed swap log: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete failed swap log: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("failed swap log not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
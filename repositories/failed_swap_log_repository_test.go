@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var failedSwapLogColumns = []string{
+	"id", "chain_id", "tx_hash", "log_index", "block_number", "block_timestamp", "contract_address",
+	"payload", "stage", "error", "attempts", "created_at", "updated_at",
+}
+
+func TestRecordFailedSwapLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewFailedSwapLogRepository(db)
+
+	blockTimestamp := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	failedLog := &entities.FailedSwapLog{
+		ChainID:         1,
+		TxHash:          "0xabc",
+		LogIndex:        7,
+		BlockNumber:     21000000,
+		BlockTimestamp:  &blockTimestamp,
+		ContractAddress: "0xPair",
+		Payload:         `{"address":"0xPair"}`,
+		Stage:           "credit",
+		Error:           "redis: connection refused",
+	}
+
+	mock.ExpectExec(`INSERT INTO failed_swap_logs`).
+		WithArgs(int64(1), "0xabc", uint(7), uint64(21000000), blockTimestamp, "0xPair", `{"address":"0xPair"}`, "credit", "redis: connection refused").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, repo.Record(failedLog))
+
+	mock.ExpectExec(`INSERT INTO failed_swap_logs`).WillReturnError(sql.ErrConnDone)
+	assert.Error(t, repo.Record(failedLog))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindFailedSwapLogByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewFailedSwapLogRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, chain_id, tx_hash, log_index, block_number, block_timestamp, contract_address, payload, stage, error, attempts, created_at, updated_at
+		FROM failed_swap_logs`).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows(failedSwapLogColumns).
+			AddRow(3, 42161, "0xabc", 7, 21000000, nil, "0xPair", "{}", "decode", "failed to unpack log", 2, now, now))
+
+	failedLog, err := repo.FindByID(3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42161), failedLog.ChainID)
+	assert.Nil(t, failedLog.BlockTimestamp)
+	assert.Equal(t, "decode", failedLog.Stage)
+	assert.Equal(t, 2, failedLog.Attempts)
+
+	// log missing
+	mock.ExpectQuery(`SELECT id, chain_id, tx_hash`).
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows(failedSwapLogColumns))
+
+	_, err = repo.FindByID(4)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListFailedSwapLogs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewFailedSwapLogRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM failed_swap_logs`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id, chain_id, tx_hash(.|\n)*FROM failed_swap_logs(.|\n)*ORDER BY id`).
+		WithArgs(2, 0).
+		WillReturnRows(sqlmock.NewRows(failedSwapLogColumns).
+			AddRow(1, 1, "0xabc", 7, 21000000, now, "0xPair", "{}", "credit", "redis: connection refused", 1, now, now).
+			AddRow(2, 1, "0xdef", 0, 21000001, nil, "0xPair", "{}", "decode", "failed to unpack log", 4, now, now))
+
+	failedLogs, total, err := repo.List(2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Len(t, failedLogs, 2)
+	assert.Equal(t, "0xdef", failedLogs[1].TxHash)
+	assert.Equal(t, 4, failedLogs[1].Attempts)
+
+	// a page past the end has no rows but still reports the total
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM failed_swap_logs`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id, chain_id, tx_hash(.|\n)*FROM failed_swap_logs(.|\n)*ORDER BY id`).
+		WithArgs(2, 4).
+		WillReturnRows(sqlmock.NewRows(failedSwapLogColumns))
+
+	failedLogs, total, err = repo.List(2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Empty(t, failedLogs)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteFailedSwapLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewFailedSwapLogRepository(db)
+
+	mock.ExpectExec(`DELETE FROM failed_swap_logs`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Delete(1))
+
+	// deleted already
+	mock.ExpectExec(`DELETE FROM failed_swap_logs`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorIs(t, repo.Delete(1), sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"trading-ace/entities"
+)
+
+type IIngestionCheckpointRepository interface {
+	FindByChainAndContract(chainID int64, contractAddress string) (*entities.IngestionCheckpoint, error)
+	Save(checkpoint *entities.IngestionCheckpoint) error
+	Rewind(checkpoint *entities.IngestionCheckpoint) error
+	SaveHead(head *entities.IngestionHead) error
+	FindHead(chainID int64) (*entities.IngestionHead, error)
+}
+
+type IngestionCheckpointRepository struct {
+	db *sql.DB
+}
+
+func NewIngestionCheckpointRepository(db *sql.DB) IIngestionCheckpointRepository {
+	return &IngestionCheckpointRepository{
+		db: db,
+	}
+}
+
+func (r *IngestionCheckpointRepository) FindByChainAndContract(chainID int64, contractAddress string) (*entities.IngestionCheckpoint, error) {
+	query := `
+		SELECT id, chain_id, chain, contract_address, block_number, log_index, created_at, updated_at
+		FROM ingestion_checkpoints
+		WHERE chain_id = $1 AND contract_address = $2
+	`
+
+	var result entities.IngestionCheckpoint
+	err := r.db.QueryRow(query, chainID, contractAddress).Scan(
+		&result.ID, &result.ChainID, &result.Chain, &result.ContractAddress, &result.BlockNumber,
+		&result.LogIndex, &result.CreatedAt, &result.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("ingestion checkpoint not found: %w", err)
+		}
+
+		return nil, fmt.Errorf("failed to find ingestion checkpoint: %w", err)
+	}
+
+	return &result, nil
+}
+
+// Save upserts the checkpoint, it never moves an existing checkpoint backwards.
+func (r *IngestionCheckpointRepository) Save(checkpoint *entities.IngestionCheckpoint) error {
+	query := `
+		INSERT INTO ingestion_checkpoints (chain_id, chain, contract_address, block_number, log_index, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (chain_id, contract_address) DO UPDATE
+		SET block_number = EXCLUDED.block_number, log_index = EXCLUDED.log_index, updated_at = CURRENT_TIMESTAMP
+		WHERE (ingestion_checkpoints.block_number, ingestion_checkpoints.log_index) < (EXCLUDED.block_number, EXCLUDED.log_index)
+	`
+
+	_, err := r.db.Exec(query, checkpoint.ChainID, checkpoint.Chain, checkpoint.ContractAddress, checkpoint.BlockNumber, checkpoint.LogIndex)
+	if err != nil {
+		return fmt.Errorf("failed to save ingestion checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Rewind moves a checkpoint back to the given position when it is past it, for logs removed by a
+// chain reorganization.
+func (r *IngestionCheckpointRepository) Rewind(checkpoint *entities.IngestionCheckpoint) error {
+	query := `
+		UPDATE ingestion_checkpoints
+		SET block_number = $3, log_index = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE chain_id = $1 AND contract_address = $2 AND (block_number, log_index) > ($3, $4)
+	`
+
+	_, err := r.db.Exec(query, checkpoint.ChainID, checkpoint.ContractAddress, checkpoint.BlockNumber, checkpoint.LogIndex)
+	if err != nil {
+		return fmt.Errorf("failed to rewind ingestion checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// SaveHead upserts the safe head of a chain, it never moves an existing head backwards.
+func (r *IngestionCheckpointRepository) SaveHead(head *entities.IngestionHead) error {
+	query := `
+		INSERT INTO ingestion_heads (chain_id, chain, block_number, block_timestamp, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (chain_id) DO UPDATE
+		SET block_number = EXCLUDED.block_number, block_timestamp = EXCLUDED.block_timestamp, updated_at = CURRENT_TIMESTAMP
+		WHERE ingestion_heads.block_number < EXCLUDED.block_number
+	`
+
+	_, err := r.db.Exec(query, head.ChainID, head.Chain, head.BlockNumber, head.BlockTimestamp)
+	if err != nil {
+		return fmt.Errorf("failed to save %s ingestion head: %w", head.Chain, err)
+	}
+
+	return nil
+}
+
+// FindHead returns the safe head ingestion of the chain has reached, nil before it reached one.
+func (r *IngestionCheckpointRepository) FindHead(chainID int64) (*entities.IngestionHead, error) {
+	query := `
+		SELECT chain_id, chain, block_number, block_timestamp, created_at, updated_at
+		FROM ingestion_heads
+		WHERE chain_id = $1
+	`
+
+	var result entities.IngestionHead
+	err := r.db.QueryRow(query, chainID).Scan(
+		&result.ChainID, &result.Chain, &result.BlockNumber, &result.BlockTimestamp, &result.CreatedAt, &result.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to find ingestion head of chain %d: %w", chainID, err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindIngestionCheckpointByChainAndContract(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewIngestionCheckpointRepository(db)
+
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT id, chain_id, chain, contract_address, block_number, log_index, created_at, updated_at
+		FROM ingestion_checkpoints`).
+		WithArgs(int64(42161), "0xPair").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "chain_id", "chain", "contract_address", "block_number", "log_index", "created_at", "updated_at",
+		}).AddRow(1, 42161, "arbitrum", "0xPair", 21000000, 4, now, now))
+
+	checkpoint, err := repo.FindByChainAndContract(42161, "0xPair")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42161), checkpoint.ChainID)
+	assert.Equal(t, uint64(21000000), checkpoint.BlockNumber)
+	assert.Equal(t, uint(4), checkpoint.LogIndex)
+
+	// checkpoint missing
+	mock.ExpectQuery(`SELECT id, chain_id, chain, contract_address`).
+		WithArgs(int64(1), "0xPair").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.FindByChainAndContract(1, "0xPair")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveIngestionCheckpoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewIngestionCheckpointRepository(db)
+
+	checkpoint := &entities.IngestionCheckpoint{
+		ChainID:         1,
+		Chain:           "ethereum",
+		ContractAddress: "0xPair",
+		BlockNumber:     21000000,
+		LogIndex:        4,
+	}
+
+	// an existing checkpoint only moves forward
+	mock.ExpectExec(`INSERT INTO ingestion_checkpoints (.+) WHERE \(ingestion_checkpoints.block_number, ingestion_checkpoints.log_index\) < \(EXCLUDED.block_number, EXCLUDED.log_index\)`).
+		WithArgs(checkpoint.ChainID, checkpoint.Chain, checkpoint.ContractAddress, checkpoint.BlockNumber, checkpoint.LogIndex).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.Save(checkpoint)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRewindIngestionCheckpoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewIngestionCheckpointRepository(db)
+
+	checkpoint := &entities.IngestionCheckpoint{
+		ChainID:         1,
+		ContractAddress: "0xPair",
+		BlockNumber:     20999999,
+		LogIndex:        2147483647,
+	}
+
+	// only a checkpoint past the position moves back
+	mock.ExpectExec(`UPDATE ingestion_checkpoints SET block_number = \$3, log_index = \$4(.+) WHERE chain_id = \$1 AND contract_address = \$2 AND \(block_number, log_index\) > \(\$3, \$4\)`).
+		WithArgs(checkpoint.ChainID, checkpoint.ContractAddress, checkpoint.BlockNumber, checkpoint.LogIndex).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Rewind(checkpoint))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveIngestionHead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewIngestionCheckpointRepository(db)
+
+	head := &entities.IngestionHead{
+		ChainID:        42161,
+		Chain:          "arbitrum",
+		BlockNumber:    21000000,
+		BlockTimestamp: time.Date(2024, 12, 10, 12, 0, 0, 0, time.UTC),
+	}
+
+	// an existing head only moves forward
+	mock.ExpectExec(`INSERT INTO ingestion_heads (.+) ON CONFLICT \(chain_id\) DO UPDATE (.+) WHERE ingestion_heads.block_number < EXCLUDED.block_number`).
+		WithArgs(head.ChainID, head.Chain, head.BlockNumber, head.BlockTimestamp).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.SaveHead(head))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindIngestionHead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewIngestionCheckpointRepository(db)
+
+	now := time.Now()
+	blockTimestamp := time.Date(2024, 12, 10, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT chain_id, chain, block_number, block_timestamp, created_at, updated_at FROM ingestion_heads WHERE chain_id = \$1`).
+		WithArgs(int64(42161)).
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id", "chain", "block_number", "block_timestamp", "created_at", "updated_at"}).
+			AddRow(42161, "arbitrum", 21000000, blockTimestamp, now, now))
+
+	head, err := repo.FindHead(42161)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(21000000), head.BlockNumber)
+	assert.Equal(t, blockTimestamp, head.BlockTimestamp)
+
+	// the chain has no head yet
+	mock.ExpectQuery(`SELECT chain_id, chain, block_number, block_timestamp, created_at, updated_at FROM ingestion_heads`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"chain_id"}))
+
+	head, err = repo.FindHead(1)
+	assert.NoError(t, err)
+	assert.Nil(t, head)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
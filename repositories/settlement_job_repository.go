@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"trading-ace/entities"
+)
+
+type ISettlementJobRepository interface {
+	Create(job *entities.SettlementJob) error
+	FindDue(at time.Time) ([]*entities.SettlementJob, error)
+	FindNextDueAt(after time.Time) (*time.Time, error)
+	Claim(id int64, fencingToken int64) (bool, error)
+	LockClaim(id int64, fencingToken int64) error
+	MarkSettled(id int64, fencingToken int64) error
+	RecordFailure(id int64, fencingToken int64, message string, maxAttempts int) error
+	WithTx(tx *sql.Tx) ISettlementJobRepository
+}
+
+type SettlementJobRepository struct {
+	db DBTX
+}
+
+func NewSettlementJobRepository(db *sql.DB) ISettlementJobRepository {
+	return &SettlementJobRepository{
+		db: db,
+	}
+}
+
+// WithTx returns the repository writing in the transaction tx.
+func (r *SettlementJobRepository) WithTx(tx *sql.Tx) ISettlementJobRepository {
+	return &SettlementJobRepository{
+		db: tx,
+	}
+}
+
+// Create schedules the settlement of a task, a task keeps the job it was given first.
+func (r *SettlementJobRepository) Create(job *entities.SettlementJob) error {
+	query := `
+		INSERT INTO settlement_jobs (task_id, due_at, status, created_at, updated_at)
+		VALUES ($1, $2, 'pending', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (task_id) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, job.TaskID, job.DueAt)
+	if err != nil {
+		return fmt.Errorf("failed to create settlement job: %w", err)
+	}
+
+	return nil
+}
+
+// FindDue returns the pending jobs due at the given time, overdue ones included, the earliest first.
+func (r *SettlementJobRepository) FindDue(at time.Time) ([]*entities.SettlementJob, error) {
+	query := `
+		SELECT id, task_id, due_at, status, attempts, error, settled_at, fencing_token, created_at, updated_at
+		FROM settlement_jobs
+		WHERE status = 'pending' AND due_at <= $1
+		ORDER BY due_at, id
+	`
+
+	rows, err := r.db.Query(query, at)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer rows.Close()
+
+	results := []*entities.SettlementJob{}
+	for rows.Next() {
+		job := &entities.SettlementJob{}
+
+		err := rows.Scan(
+			&job.ID, &job.TaskID, &job.DueAt, &job.Status, &job.Attempts, &job.Error, &job.SettledAt,
+			&job.FencingToken, &job.CreatedAt, &job.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		results = append(results, job)
+	}
+
+	return results, nil
+}
+
+// FindNextDueAt returns the earliest time a pending job falls due after the given time, nil when
+// none does.
+func (r *SettlementJobRepository) FindNextDueAt(after time.Time) (*time.Time, error) {
+	query := `
+		SELECT MIN(due_at)
+		FROM settlement_jobs
+		WHERE status = 'pending' AND due_at > $1
+	`
+
+	var result *time.Time
+	if err := r.db.QueryRow(query, after).Scan(&result); err != nil {
+		return nil, fmt.Errorf("failed to find next settlement: %w", err)
+	}
+
+	return result, nil
+}
+
+// Claim hands a pending job to the settlement lock holder with the given fencing token and reports
+// whether it took the claim. A job already claimed with a greater token was taken over by a newer
+// holder and is not handed back.
+func (r *SettlementJobRepository) Claim(id int64, fencingToken int64) (bool, error) {
+	query := `
+		UPDATE settlement_jobs
+		SET fencing_token = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending' AND fencing_token <= $2
+	`
+
+	result, err := r.db.Exec(query, id, fencingToken)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim settlement job %d: %w", id, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim settlement job %d: %w", id, err)
+	}
+
+	return rows == 1, nil
+}
+
+// LockClaim locks a pending job claimed with the given fencing token until the end of the transaction
+// of the repository, and fails once a newer holder claimed it. A newer claim waits for the lock, so
+// the writes made in the transaction are only kept under the token that claimed the job last.
+func (r *SettlementJobRepository) LockClaim(id int64, fencingToken int64) error {
+	query := `
+		SELECT id
+		FROM settlement_jobs
+		WHERE id = $1 AND status = 'pending' AND fencing_token = $2
+		FOR UPDATE
+	`
+
+	var locked int64
+	if err := r.db.QueryRow(query, id, fencingToken).Scan(&locked); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("settlement job %d is no longer claimed with fencing token %d: %w", id, fencingToken, err)
+		}
+
+		return fmt.Errorf("failed to lock settlement job %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// MarkSettled settles a job claimed with the given fencing token.
+func (r *SettlementJobRepository) MarkSettled(id int64, fencingToken int64) error {
+	query := `
+		UPDATE settlement_jobs
+		SET status = 'settled', attempts = attempts + 1, error = '', settled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND fencing_token = $2
+	`
+
+	result, err := r.db.Exec(query, id, fencingToken)
+	if err != nil {
+		return fmt.Errorf("failed to mark settlement job %d settled: %w", id, err)
+	}
+
+	return claimHeld(result, id, fencingToken)
+}
+
+// RecordFailure counts a failed attempt of a job claimed with the given fencing token, the job stays
+// pending to be retried until it has failed maxAttempts times.
+func (r *SettlementJobRepository) RecordFailure(id int64, fencingToken int64, message string, maxAttempts int) error {
+	query := `
+		UPDATE settlement_jobs
+		SET attempts = attempts + 1, error = $3,
+		    status = CASE WHEN attempts + 1 >= $4 THEN 'failed' ELSE 'pending' END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND fencing_token = $2
+	`
+
+	result, err := r.db.Exec(query, id, fencingToken, message, maxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to record settlement job %d failure: %w", id, err)
+	}
+
+	return claimHeld(result, id, fencingToken)
+}
+
+// claimHeld fails when an update of a job changed nothing because a newer lock holder claimed it.
+func claimHeld(result sql.Result, id int64, fencingToken int64) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update settlement job %d: %w", id, err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("settlement job %d is no longer claimed with fencing token %d", id, fencingToken)
+	}
+
+	return nil
+}
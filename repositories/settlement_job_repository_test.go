@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSettlementJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	dueAt := time.Date(2024, 12, 8, 0, 0, 0, 0, time.UTC)
+
+	// scheduling a task twice keeps its first job
+	mock.ExpectExec(`INSERT INTO settlement_jobs (.+) ON CONFLICT \(task_id\) DO NOTHING`).
+		WithArgs(int64(7), dueAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.Create(&entities.SettlementJob{TaskID: 7, DueAt: dueAt}))
+
+	mock.ExpectExec(`INSERT INTO settlement_jobs`).WillReturnError(sql.ErrConnDone)
+	assert.Error(t, repo.Create(&entities.SettlementJob{TaskID: 7, DueAt: dueAt}))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindDueSettlementJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "task_id", "due_at", "status", "attempts", "error", "settled_at", "fencing_token", "created_at", "updated_at"}
+
+	mock.ExpectQuery(`SELECT (.+) FROM settlement_jobs WHERE status = 'pending' AND due_at <= \$1 ORDER BY due_at, id`).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 7, now.Add(-14*24*time.Hour), "pending", 0, "", nil, 0, now, now).
+			AddRow(2, 8, now.Add(-7*24*time.Hour), "pending", 2, "redis: connection refused", nil, 3, now, now))
+
+	jobs, err := repo.FindDue(now)
+
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, int64(7), jobs[0].TaskID)
+	assert.Equal(t, 2, jobs[1].Attempts)
+	assert.Equal(t, "redis: connection refused", jobs[1].Error)
+	assert.Nil(t, jobs[1].SettledAt)
+	assert.Equal(t, int64(3), jobs[1].FencingToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindNextSettlementDueAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	dueAt := now.Add(2 * time.Hour)
+
+	mock.ExpectQuery(`SELECT MIN\(due_at\) FROM settlement_jobs WHERE status = 'pending' AND due_at > \$1`).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(dueAt))
+
+	next, err := repo.FindNextDueAt(now)
+	assert.NoError(t, err)
+	assert.Equal(t, dueAt, *next)
+
+	// nothing left to settle
+	mock.ExpectQuery(`SELECT MIN\(due_at\)`).
+		WithArgs(dueAt).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(nil))
+
+	next, err = repo.FindNextDueAt(dueAt)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimSettlementJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	mock.ExpectExec(`UPDATE settlement_jobs SET fencing_token = \$2(.+) WHERE id = \$1 AND status = 'pending' AND fencing_token <= \$2`).
+		WithArgs(int64(1), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	claimed, err := repo.Claim(1, 5)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	// a newer lock holder claimed the job
+	mock.ExpectExec(`UPDATE settlement_jobs SET fencing_token`).
+		WithArgs(int64(1), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	claimed, err = repo.Claim(1, 4)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLockSettlementJobClaim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	mock.ExpectQuery(`SELECT id FROM settlement_jobs WHERE id = \$1 AND status = 'pending' AND fencing_token = \$2 FOR UPDATE`).
+		WithArgs(int64(1), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	assert.NoError(t, repo.LockClaim(1, 5))
+
+	// a newer lock holder claimed the job
+	mock.ExpectQuery(`SELECT id FROM settlement_jobs`).
+		WithArgs(int64(1), int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	assert.ErrorIs(t, repo.LockClaim(1, 4), sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkSettlementJobSettled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	mock.ExpectExec(`UPDATE settlement_jobs SET status = 'settled'(.+) WHERE id = \$1 AND fencing_token = \$2`).
+		WithArgs(int64(1), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.MarkSettled(1, 5))
+
+	// a newer lock holder claimed the job
+	mock.ExpectExec(`UPDATE settlement_jobs SET status = 'settled'`).
+		WithArgs(int64(1), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Error(t, repo.MarkSettled(1, 4))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordSettlementJobFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSettlementJobRepository(db)
+
+	// the job gives up once it has failed the given number of times
+	mock.ExpectExec(`UPDATE settlement_jobs SET attempts = attempts \+ 1, error = \$3, status = CASE WHEN attempts \+ 1 >= \$4 THEN 'failed' ELSE 'pending' END(.+) WHERE id = \$1 AND fencing_token = \$2`).
+		WithArgs(int64(1), int64(5), "redis: connection refused", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.RecordFailure(1, 5, "redis: connection refused", 10))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
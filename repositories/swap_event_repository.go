@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"trading-ace/entities"
+	"trading-ace/models"
+
+	"github.com/lib/pq"
+)
+
+type ISwapEventRepository interface {
+	CreateIfNotExists(swapEvent *entities.SwapEvent) (bool, error)
+	DeleteByChainTxHashAndLogIndex(chainID int64, txHash string, logIndex uint) (*entities.SwapEvent, error)
+	GetByAddress(address string, campaignID *int64, period int, limit int, offset int) ([]*models.SwapActivity, int, error)
+}
+
+type SwapEventRepository struct {
+	db *sql.DB
+}
+
+func NewSwapEventRepository(db *sql.DB) ISwapEventRepository {
+	return &SwapEventRepository{
+		db: db,
+	}
+}
+
+// CreateIfNotExists stores the swap, credited to its TaskIDs, and reports whether it was new. A swap
+// with the same chain, transaction hash and log index is left untouched.
+func (r *SwapEventRepository) CreateIfNotExists(swapEvent *entities.SwapEvent) (bool, error) {
+	query := `
+		WITH created AS (
+			INSERT INTO swap_events (chain_id, tx_hash, log_index, block_number, block_timestamp, contract_address, sender_address, amount0_in, amount1_in, amount0_out, amount1_out, price_usd, volume_usd, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CURRENT_TIMESTAMP)
+			ON CONFLICT (chain_id, tx_hash, log_index) DO NOTHING
+			RETURNING id
+		), credited AS (
+			INSERT INTO swap_event_tasks (swap_event_id, task_id)
+			SELECT created.id, task_id FROM created, unnest($14::int[]) AS task_id
+		)
+		SELECT COUNT(*) FROM created
+	`
+
+	var affected int
+	err := r.db.QueryRow(
+		query,
+		swapEvent.ChainID, swapEvent.TxHash, swapEvent.LogIndex, swapEvent.BlockNumber, swapEvent.BlockTimestamp, swapEvent.ContractAddress, swapEvent.SenderAddress,
+		swapEvent.Amount0In, swapEvent.Amount1In, swapEvent.Amount0Out, swapEvent.Amount1Out,
+		swapEvent.PriceUSD, swapEvent.VolumeUSD, pq.Array(swapEvent.TaskIDs),
+	).Scan(&affected)
+	if err != nil {
+		return false, fmt.Errorf("failed to create swap event: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// DeleteByChainTxHashAndLogIndex deletes the swap and returns it with the tasks it was credited to,
+// or nil when it was never stored.
+func (r *SwapEventRepository) DeleteByChainTxHashAndLogIndex(chainID int64, txHash string, logIndex uint) (*entities.SwapEvent, error) {
+	query := `
+		WITH deleted AS (
+			DELETE FROM swap_events
+			WHERE chain_id = $1 AND tx_hash = $2 AND log_index = $3
+			RETURNING id, block_number, contract_address, sender_address, amount0_in, amount1_in, amount0_out, amount1_out,
+			          price_usd, volume_usd, block_timestamp, created_at
+		)
+		SELECT d.id, d.block_number, d.contract_address, d.sender_address, d.amount0_in, d.amount1_in, d.amount0_out, d.amount1_out,
+		       d.price_usd, d.volume_usd, d.block_timestamp, d.created_at,
+		       ARRAY(SELECT st.task_id FROM swap_event_tasks st WHERE st.swap_event_id = d.id ORDER BY st.task_id)
+		FROM deleted d
+	`
+
+	var swapEvent entities.SwapEvent
+	err := r.db.QueryRow(query, chainID, txHash, logIndex).Scan(
+		&swapEvent.ID, &swapEvent.BlockNumber, &swapEvent.ContractAddress, &swapEvent.SenderAddress,
+		&swapEvent.Amount0In, &swapEvent.Amount1In, &swapEvent.Amount0Out, &swapEvent.Amount1Out,
+		&swapEvent.PriceUSD, &swapEvent.VolumeUSD, &swapEvent.BlockTimestamp, &swapEvent.CreatedAt,
+		pq.Array(&swapEvent.TaskIDs),
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to delete swap event: %w", err)
+	}
+
+	swapEvent.ChainID = chainID
+	swapEvent.TxHash = txHash
+	swapEvent.LogIndex = logIndex
+
+	return &swapEvent, nil
+}
+
+// GetByAddress returns a page of the swaps credited to address by a campaign, newest first, together
+// with the total number of matching swaps. A nil campaignID selects the tasks created before campaigns
+// were stored and the swaps credited to no task. A period of 0 returns the swaps of all periods.
+func (r *SwapEventRepository) GetByAddress(address string, campaignID *int64, period int, limit int, offset int) ([]*models.SwapActivity, int, error) {
+	filter := `
+		FROM swap_events se
+		LEFT JOIN swap_event_tasks st ON st.swap_event_id = se.id
+		LEFT JOIN tasks t ON st.task_id = t.id
+		WHERE se.sender_address = $1 AND t.campaign_id IS NOT DISTINCT FROM $2 AND ($3 = 0 OR t.period = $3)
+	`
+
+	// counted on its own, so a page past the end still reports the total
+	total := 0
+	if err := r.db.QueryRow(`SELECT COUNT(*) `+filter, address, campaignID, period).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count failed: %w", err)
+	}
+
+	query := `
+		SELECT se.id, se.chain_id, se.tx_hash, se.log_index, se.block_number, se.block_timestamp, se.contract_address, se.sender_address,
+		       se.amount0_in, se.amount1_in, se.amount0_out, se.amount1_out, se.price_usd, se.volume_usd, se.created_at,
+		       t.id, t.period
+	` + filter + `
+		ORDER BY se.block_number DESC, se.log_index DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Query(query, address, campaignID, period, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer rows.Close()
+
+	results := []*models.SwapActivity{}
+	for rows.Next() {
+		swapEvent := &entities.SwapEvent{}
+		var taskID *int64
+		var taskPeriod *int
+
+		err := rows.Scan(
+			&swapEvent.ID, &swapEvent.ChainID, &swapEvent.TxHash, &swapEvent.LogIndex, &swapEvent.BlockNumber, &swapEvent.BlockTimestamp,
+			&swapEvent.ContractAddress, &swapEvent.SenderAddress,
+			&swapEvent.Amount0In, &swapEvent.Amount1In, &swapEvent.Amount0Out, &swapEvent.Amount1Out,
+			&swapEvent.PriceUSD, &swapEvent.VolumeUSD, &swapEvent.CreatedAt,
+			&taskID, &taskPeriod,
+		)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan failed: %w", err)
+		}
+
+		if taskID != nil {
+			swapEvent.TaskIDs = []int64{*taskID}
+		}
+
+		results = append(results, &models.SwapActivity{
+			SwapEvent:  swapEvent,
+			TaskPeriod: taskPeriod,
+		})
+	}
+
+	return results, total, nil
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+	"trading-ace/decimal"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSwapEventIfNotExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSwapEventRepository(db)
+
+	price := decimal.NewFromInt(3000)
+	volume := decimal.NewFromInt(1)
+	blockTimestamp := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	swapEvent := &entities.SwapEvent{
+		ChainID:         42161,
+		TxHash:          "0xabc",
+		LogIndex:        7,
+		BlockNumber:     21000000,
+		BlockTimestamp:  &blockTimestamp,
+		ContractAddress: "0xPair",
+		SenderAddress:   "0xSender",
+		Amount0In:       "1000000",
+		Amount1In:       "0",
+		Amount0Out:      "0",
+		Amount1Out:      "500000000000000",
+		PriceUSD:        &price,
+		VolumeUSD:       &volume,
+		TaskIDs:         []int64{2, 9},
+	}
+
+	args := []driver.Value{
+		swapEvent.ChainID, swapEvent.TxHash, swapEvent.LogIndex, swapEvent.BlockNumber, blockTimestamp, swapEvent.ContractAddress, swapEvent.SenderAddress,
+		swapEvent.Amount0In, swapEvent.Amount1In, swapEvent.Amount0Out, swapEvent.Amount1Out,
+		"3000", "1", "{2,9}",
+	}
+
+	// first insert creates the row, credited to the task of each campaign
+	mock.ExpectQuery(`INSERT INTO swap_events (.+) INSERT INTO swap_event_tasks`).WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	created, err := repo.CreateIfNotExists(swapEvent)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	// replaying the same log hits the unique constraint
+	mock.ExpectQuery(`INSERT INTO swap_events`).WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	created, err = repo.CreateIfNotExists(swapEvent)
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteSwapEventByChainTxHashAndLogIndex(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSwapEventRepository(db)
+
+	blockTimestamp := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "block_number", "contract_address", "sender_address", "amount0_in", "amount1_in", "amount0_out", "amount1_out", "price_usd", "volume_usd", "block_timestamp", "created_at", "task_ids"}
+
+	// the swap comes back with the tasks it was credited to, no RPC lookup is needed to revert it
+	rows := sqlmock.NewRows(columns).AddRow(1, 100, "0xPair", "0xSender", "2000000", "0", "0", "1000000000000000", "3000", "1", blockTimestamp, blockTimestamp, "{7,9}")
+	mock.ExpectQuery(`WITH deleted AS \( DELETE FROM swap_events (.+) swap_event_tasks`).WithArgs(int64(1), "0xabc", uint(7)).WillReturnRows(rows)
+
+	deleted, err := repo.DeleteByChainTxHashAndLogIndex(1, "0xabc", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted.ChainID)
+	assert.Equal(t, "0xPair", deleted.ContractAddress)
+	assert.Equal(t, "0xSender", deleted.SenderAddress)
+	assert.Equal(t, "2000000", deleted.Amount0In)
+	assert.Equal(t, "1", deleted.VolumeUSD.String())
+	assert.Equal(t, blockTimestamp, *deleted.BlockTimestamp)
+	assert.Equal(t, []int64{7, 9}, deleted.TaskIDs)
+
+	// swaps stored before pricing have no volume
+	rows = sqlmock.NewRows(columns).AddRow(2, 100, "0xPair", "0xSender", "2000000", "0", "0", "1000000000000000", nil, nil, nil, blockTimestamp, "{}")
+	mock.ExpectQuery(`DELETE FROM swap_events`).WithArgs(int64(1), "0xdef", uint(1)).WillReturnRows(rows)
+
+	deleted, err = repo.DeleteByChainTxHashAndLogIndex(1, "0xdef", 1)
+	assert.NoError(t, err)
+	assert.Nil(t, deleted.VolumeUSD)
+	assert.Nil(t, deleted.BlockTimestamp)
+	assert.Empty(t, deleted.TaskIDs)
+
+	// the swap was never stored on this chain
+	mock.ExpectQuery(`DELETE FROM swap_events`).WithArgs(int64(8453), "0xabc", uint(7)).WillReturnError(sql.ErrNoRows)
+
+	deleted, err = repo.DeleteByChainTxHashAndLogIndex(8453, "0xabc", 7)
+	assert.NoError(t, err)
+	assert.Nil(t, deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSwapEventsByAddress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewSwapEventRepository(db)
+
+	columns := []string{
+		"id", "chain_id", "tx_hash", "log_index", "block_number", "block_timestamp", "contract_address", "sender_address",
+		"amount0_in", "amount1_in", "amount0_out", "amount1_out", "price_usd", "volume_usd", "created_at",
+		"id", "period",
+	}
+	blockTimestamp := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	now := time.Now()
+
+	// the second swap was stored before periods were recorded
+	rows := sqlmock.NewRows(columns).
+		AddRow(2, 42161, "0xdef", 1, 21000010, blockTimestamp, "0xPair", "0xsender", "0", "1", "2000000", "0", "2000", "2000", now, 3, 2).
+		AddRow(1, 1, "0xabc", 7, 21000000, nil, "0xPair", "0xsender", "1000000", "0", "0", "1", nil, nil, now, nil, nil)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM swap_events se`).WithArgs("0xsender", nil, 0).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(`SELECT (.+) FROM swap_events se LEFT JOIN swap_event_tasks st (.+) LEFT JOIN tasks t (.+) LIMIT`).WithArgs("0xsender", nil, 0, 2, 0).WillReturnRows(rows)
+
+	activities, total, err := repo.GetByAddress("0xsender", nil, 0, 2, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, total)
+	assert.Len(t, activities, 2)
+	assert.Equal(t, "0xdef", activities[0].SwapEvent.TxHash)
+	assert.Equal(t, int64(42161), activities[0].SwapEvent.ChainID)
+	assert.Equal(t, blockTimestamp, *activities[0].SwapEvent.BlockTimestamp)
+	assert.Equal(t, "2000", activities[0].SwapEvent.VolumeUSD.String())
+	assert.Equal(t, []int64{3}, activities[0].SwapEvent.TaskIDs)
+	assert.Equal(t, 2, *activities[0].TaskPeriod)
+	assert.Nil(t, activities[1].SwapEvent.BlockTimestamp)
+	assert.Nil(t, activities[1].SwapEvent.VolumeUSD)
+	assert.Nil(t, activities[1].TaskPeriod)
+	assert.Empty(t, activities[1].SwapEvent.TaskIDs)
+
+	// a page past the end has no rows but still reports the total
+	campaignID := int64(2)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM swap_events se`).WithArgs("0xsender", campaignID, 1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(25))
+	mock.ExpectQuery(`SELECT (.+) FROM swap_events se (.+) LIMIT`).WithArgs("0xsender", campaignID, 1, 20, 40).WillReturnRows(sqlmock.NewRows(columns))
+
+	activities, total, err = repo.GetByAddress("0xsender", &campaignID, 1, 20, 40)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 25, total)
+	assert.Empty(t, activities)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -11,11 +11,13 @@ type ITaskHistoryRepository interface {
 	Create(taskHistory *entities.TaskHistory) (*entities.TaskHistory, error)
 	FindByID(id int64) (*entities.TaskHistory, error)
 	FindByAddressAndTaskId(address string, taskId int64) (*entities.TaskHistory, error)
-	GetByAddressIncludingTasks(address string) ([]*models.TaskTaskHistoryPair, error)
+	GetByAddressIncludingTasks(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error)
+	Delete(id int64) error
+	WithTx(tx *sql.Tx) ITaskHistoryRepository
 }
 
 type TaskHistoryRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewTaskHistoryRepository(db *sql.DB) ITaskHistoryRepository {
@@ -24,6 +26,13 @@ func NewTaskHistoryRepository(db *sql.DB) ITaskHistoryRepository {
 	}
 }
 
+// WithTx returns the repository writing in the transaction tx.
+func (r *TaskHistoryRepository) WithTx(tx *sql.Tx) ITaskHistoryRepository {
+	return &TaskHistoryRepository{
+		db: tx,
+	}
+}
+
 func (r *TaskHistoryRepository) Create(taskHistory *entities.TaskHistory) (*entities.TaskHistory, error) {
 	query := `
 		INSERT INTO task_histories (address, task_id, reward_points, amount, completed_at, created_at, updated_at)
@@ -97,16 +106,18 @@ func (r *TaskHistoryRepository) FindByAddressAndTaskId(address string, taskId in
 	return &result, nil
 }
 
-func (t *TaskHistoryRepository) GetByAddressIncludingTasks(address string) ([]*models.TaskTaskHistoryPair, error) {
+// GetByAddressIncludingTasks returns the completed tasks of address in a campaign, a campaignID of 0
+// returns those of every campaign.
+func (t *TaskHistoryRepository) GetByAddressIncludingTasks(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error) {
 	query := `
 		SELECT th.id, th.address, th.reward_points, th.amount, th.completed_at,
-		       t.id, t.name, t.description, t.points, t.started_at, t.end_at, t.period, t.created_at, t.updated_at
+		       t.id, t.campaign_id, t.name, t.description, t.points, t.started_at, t.end_at, t.period, t.chain_id, t.created_at, t.updated_at
 		FROM task_histories th
 		INNER JOIN tasks t ON th.task_id = t.id
-		WHERE th.address = $1
+		WHERE th.address = $1 AND ($2 = 0 OR t.campaign_id = $2)
 	`
 
-	rows, err := t.db.Query(query, address)
+	rows, err := t.db.Query(query, address, campaignID)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -122,8 +133,8 @@ func (t *TaskHistoryRepository) GetByAddressIncludingTasks(address string) ([]*m
 		err := rows.Scan(
 			&taskHistory.ID, &taskHistory.Address, &taskHistory.RewardPoints, &taskHistory.Amount, &taskHistory.CompletedAt,
 
-			&task.ID, &task.Name, &task.Description, &task.Points,
-			&task.StartedAt, &task.EndAt, &task.Period,
+			&task.ID, &task.CampaignID, &task.Name, &task.Description, &task.Points,
+			&task.StartedAt, &task.EndAt, &task.Period, &task.ChainID,
 			&task.CreatedAt, &task.UpdatedAt,
 		)
 
@@ -139,3 +150,16 @@ func (t *TaskHistoryRepository) GetByAddressIncludingTasks(address string) ([]*m
 
 	return results, nil
 }
+
+func (r *TaskHistoryRepository) Delete(id int64) error {
+	query := `
+		DELETE FROM task_histories
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to delete task record: %w", err)
+	}
+
+	return nil
+}
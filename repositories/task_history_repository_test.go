@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"trading-ace/decimal"
 	"trading-ace/entities"
 	"trading-ace/models"
 
@@ -25,8 +26,8 @@ func TestCreateTaskHistory(t *testing.T) {
 	taskHistory := &entities.TaskHistory{
 		Address:      "test_address",
 		TaskID:       1,
-		RewardPoints: 10.5,
-		Amount:       100.0,
+		RewardPoints: decimal.RequireFromString("10.5"),
+		Amount:       decimal.NewFromInt(100),
 		CompletedAt:  nil,
 	}
 
@@ -34,7 +35,7 @@ func TestCreateTaskHistory(t *testing.T) {
 	mock.ExpectQuery(`INSERT INTO task_histories`).
 		WithArgs(taskHistory.Address, taskHistory.TaskID, taskHistory.RewardPoints, taskHistory.Amount, taskHistory.CompletedAt).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "address", "task_id", "reward_points", "amount", "completed_at", "created_at", "updated_at"}).
-			AddRow(1, taskHistory.Address, taskHistory.TaskID, taskHistory.RewardPoints, taskHistory.Amount, taskHistory.CompletedAt, time.Now(), time.Now()))
+			AddRow(1, taskHistory.Address, taskHistory.TaskID, taskHistory.RewardPoints.String(), taskHistory.Amount.String(), taskHistory.CompletedAt, time.Now(), time.Now()))
 
 	// 呼叫 Create 函數
 	createdTaskHistory, err := repo.Create(taskHistory)
@@ -59,8 +60,8 @@ func TestFindByID(t *testing.T) {
 		ID:           taskHistoryID,
 		Address:      "test_address",
 		TaskID:       1,
-		RewardPoints: 10.5,
-		Amount:       100.0,
+		RewardPoints: decimal.RequireFromString("10.5"),
+		Amount:       decimal.NewFromInt(100),
 		CompletedAt:  nil,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
@@ -70,7 +71,7 @@ func TestFindByID(t *testing.T) {
 	mock.ExpectQuery(`SELECT id, address, task_id, reward_points, amount, completed_at, created_at, updated_at`).
 		WithArgs(taskHistoryID).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "address", "task_id", "reward_points", "amount", "completed_at", "created_at", "updated_at"}).
-			AddRow(expectedTaskHistory.ID, expectedTaskHistory.Address, expectedTaskHistory.TaskID, expectedTaskHistory.RewardPoints, expectedTaskHistory.Amount, expectedTaskHistory.CompletedAt, expectedTaskHistory.CreatedAt, expectedTaskHistory.UpdatedAt))
+			AddRow(expectedTaskHistory.ID, expectedTaskHistory.Address, expectedTaskHistory.TaskID, expectedTaskHistory.RewardPoints.String(), expectedTaskHistory.Amount.String(), expectedTaskHistory.CompletedAt, expectedTaskHistory.CreatedAt, expectedTaskHistory.UpdatedAt))
 
 	// 呼叫 FindByID 函數
 	result, err := repo.FindByID(taskHistoryID)
@@ -96,8 +97,8 @@ func TestFindByAddressAndTaskId(t *testing.T) {
 		ID:           1,
 		Address:      address,
 		TaskID:       taskId,
-		RewardPoints: 10.5,
-		Amount:       100.0,
+		RewardPoints: decimal.RequireFromString("10.5"),
+		Amount:       decimal.NewFromInt(100),
 		CompletedAt:  nil,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
@@ -107,7 +108,7 @@ func TestFindByAddressAndTaskId(t *testing.T) {
 	mock.ExpectQuery(`SELECT id, address, task_id, reward_points, amount, completed_at, created_at, updated_at`).
 		WithArgs(address, taskId).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "address", "task_id", "reward_points", "amount", "completed_at", "created_at", "updated_at"}).
-			AddRow(expectedTaskHistory.ID, expectedTaskHistory.Address, expectedTaskHistory.TaskID, expectedTaskHistory.RewardPoints, expectedTaskHistory.Amount, expectedTaskHistory.CompletedAt, expectedTaskHistory.CreatedAt, expectedTaskHistory.UpdatedAt))
+			AddRow(expectedTaskHistory.ID, expectedTaskHistory.Address, expectedTaskHistory.TaskID, expectedTaskHistory.RewardPoints.String(), expectedTaskHistory.Amount.String(), expectedTaskHistory.CompletedAt, expectedTaskHistory.CreatedAt, expectedTaskHistory.UpdatedAt))
 
 	// 呼叫 FindByAddressAndTaskId 函數
 	result, err := repo.FindByAddressAndTaskId(address, taskId)
@@ -134,7 +135,7 @@ func TestGetByAddressIncludingTasks(t *testing.T) {
 				ID:          1,
 				Name:        "Test Task",
 				Description: "Test Description",
-				Points:      100,
+				Points:      decimal.NewFromInt(100),
 				StartedAt:   nil,
 				EndAt:       nil,
 				Period:      1,
@@ -145,8 +146,8 @@ func TestGetByAddressIncludingTasks(t *testing.T) {
 				ID:           1,
 				Address:      address,
 				TaskID:       1,
-				RewardPoints: 10.5,
-				Amount:       100.0,
+				RewardPoints: decimal.RequireFromString("10.5"),
+				Amount:       decimal.NewFromInt(100),
 				CompletedAt:  nil,
 				CreatedAt:    time.Now(),
 				UpdatedAt:    time.Now(),
@@ -156,28 +157,49 @@ func TestGetByAddressIncludingTasks(t *testing.T) {
 
 	// 設定查詢語句及返回結果
 	mock.ExpectQuery(`SELECT th.id, th.address, th.reward_points, th.amount, th.completed_at,`).
-		WithArgs(address).
-		WillReturnRows(sqlmock.NewRows([]string{"th.id", "th.address", "th.reward_points", "th.amount", "th.completed_at", "t.id", "t.name", "t.description", "t.points", "t.started_at", "t.end_at", "t.period", "t.created_at", "t.updated_at"}).
+		WithArgs(address, int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"th.id", "th.address", "th.reward_points", "th.amount", "th.completed_at", "t.id", "t.campaign_id", "t.name", "t.description", "t.points", "t.started_at", "t.end_at", "t.period", "t.chain_id", "t.created_at", "t.updated_at"}).
 			AddRow(
 				expectedResults[0].TaskHistory.ID,
 				expectedResults[0].TaskHistory.Address,
-				expectedResults[0].TaskHistory.RewardPoints,
-				expectedResults[0].TaskHistory.Amount,
+				expectedResults[0].TaskHistory.RewardPoints.String(),
+				expectedResults[0].TaskHistory.Amount.String(),
 				expectedResults[0].TaskHistory.CompletedAt,
 				expectedResults[0].Task.ID,
+				expectedResults[0].Task.CampaignID,
 				expectedResults[0].Task.Name,
 				expectedResults[0].Task.Description,
-				expectedResults[0].Task.Points,
+				expectedResults[0].Task.Points.String(),
 				expectedResults[0].Task.StartedAt,
 				expectedResults[0].Task.EndAt,
 				expectedResults[0].Task.Period,
+				expectedResults[0].Task.ChainID,
 				expectedResults[0].Task.CreatedAt,
 				expectedResults[0].Task.UpdatedAt,
 			))
 
 	// 呼叫 GetByAddressIncludingTasks 函數
-	results, err := repo.GetByAddressIncludingTasks(address)
+	results, err := repo.GetByAddressIncludingTasks(address, 0)
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, expectedResults[0].TaskHistory.Address, results[0].TaskHistory.Address)
 }
+
+func TestDeleteTaskHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTaskHistoryRepository(db)
+
+	mock.ExpectExec(`DELETE FROM task_histories`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.Delete(1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -14,11 +14,12 @@ type ITaskRepository interface {
 	FindByName(name string) (*entities.Task, error)
 	GetByName(name string) ([]*entities.Task, error)
 	IsExistedByName(name string) (bool, error)
-	GetByAddressAndNamesIncludingTaskHistories(address string, names []string) ([]*models.TaskWithTaskHistory, error)
+	GetByAddressAndNamesIncludingTaskHistories(address string, campaignID int64, names []string) ([]*models.TaskWithTaskHistory, error)
+	WithTx(tx *sql.Tx) ITaskRepository
 }
 
 type TaskRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewTaskRepository(db *sql.DB) ITaskRepository {
@@ -27,22 +28,29 @@ func NewTaskRepository(db *sql.DB) ITaskRepository {
 	}
 }
 
+// WithTx returns the repository writing in the transaction tx.
+func (t *TaskRepository) WithTx(tx *sql.Tx) ITaskRepository {
+	return &TaskRepository{
+		db: tx,
+	}
+}
+
 func (t *TaskRepository) Create(task *entities.Task) (*entities.Task, error) {
 	query := `
-		INSERT INTO tasks (name, description, points, started_at, end_at, period, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		RETURNING id, name, description, points, started_at, end_at, period, created_at, updated_at
+		INSERT INTO tasks (campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 	`
 
 	var createdTask entities.Task
 	err := t.db.QueryRow(
 		query,
-		task.Name, task.Description, task.Points,
-		task.StartedAt, task.EndAt, task.Period,
+		task.CampaignID, task.Name, task.Description, task.Points, task.TargetAmount,
+		task.StartedAt, task.EndAt, task.Period, task.ChainID,
 	).Scan(
-		&createdTask.ID, &createdTask.Name, &createdTask.Description,
-		&createdTask.Points, &createdTask.StartedAt, &createdTask.EndAt,
-		&createdTask.Period, &createdTask.CreatedAt, &createdTask.UpdatedAt,
+		&createdTask.ID, &createdTask.CampaignID, &createdTask.Name, &createdTask.Description,
+		&createdTask.Points, &createdTask.TargetAmount, &createdTask.StartedAt, &createdTask.EndAt,
+		&createdTask.Period, &createdTask.ChainID, &createdTask.CreatedAt, &createdTask.UpdatedAt,
 	)
 
 	if err != nil {
@@ -54,15 +62,15 @@ func (t *TaskRepository) Create(task *entities.Task) (*entities.Task, error) {
 
 func (t *TaskRepository) FindById(id int64) (*entities.Task, error) {
 	query := `
-		SELECT id, name, description, points, started_at, end_at, period, created_at, updated_at
+		SELECT id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 		FROM tasks
 		WHERE id = $1
 	`
 
 	var task entities.Task
 	err := t.db.QueryRow(query, id).Scan(
-		&task.ID, &task.Name, &task.Description, &task.Points,
-		&task.StartedAt, &task.EndAt, &task.Period,
+		&task.ID, &task.CampaignID, &task.Name, &task.Description, &task.Points, &task.TargetAmount,
+		&task.StartedAt, &task.EndAt, &task.Period, &task.ChainID,
 		&task.CreatedAt, &task.UpdatedAt,
 	)
 
@@ -79,15 +87,15 @@ func (t *TaskRepository) FindById(id int64) (*entities.Task, error) {
 
 func (t *TaskRepository) FindByName(name string) (*entities.Task, error) {
 	query := `
-		SELECT id, name, description, points, started_at, end_at, period, created_at, updated_at
+		SELECT id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 		FROM tasks
 		WHERE name = $1
 	`
 
 	var task entities.Task
 	err := t.db.QueryRow(query, name).Scan(
-		&task.ID, &task.Name, &task.Description, &task.Points,
-		&task.StartedAt, &task.EndAt, &task.Period,
+		&task.ID, &task.CampaignID, &task.Name, &task.Description, &task.Points, &task.TargetAmount,
+		&task.StartedAt, &task.EndAt, &task.Period, &task.ChainID,
 		&task.CreatedAt, &task.UpdatedAt,
 	)
 
@@ -104,7 +112,7 @@ func (t *TaskRepository) FindByName(name string) (*entities.Task, error) {
 
 func (t *TaskRepository) GetByName(name string) ([]*entities.Task, error) {
 	query := `
-		SELECT id, name, description, points, started_at, end_at, period, created_at, updated_at
+		SELECT id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 		FROM tasks
 		WHERE name = $1
 	`
@@ -120,8 +128,8 @@ func (t *TaskRepository) GetByName(name string) ([]*entities.Task, error) {
 	for rows.Next() {
 		task := &entities.Task{}
 		err := rows.Scan(
-			&task.ID, &task.Name, &task.Description, &task.Points,
-			&task.StartedAt, &task.EndAt, &task.Period,
+			&task.ID, &task.CampaignID, &task.Name, &task.Description, &task.Points, &task.TargetAmount,
+			&task.StartedAt, &task.EndAt, &task.Period, &task.ChainID,
 			&task.CreatedAt, &task.UpdatedAt,
 		)
 
@@ -151,23 +159,27 @@ func (t *TaskRepository) IsExistedByName(name string) (bool, error) {
 	return exists, nil
 }
 
-func (t *TaskRepository) GetByAddressAndNamesIncludingTaskHistories(address string, names []string) ([]*models.TaskWithTaskHistory, error) {
+// GetByAddressAndNamesIncludingTaskHistories returns the tasks of a campaign with the histories of
+// address, a campaignID of 0 returns the tasks of every campaign.
+func (t *TaskRepository) GetByAddressAndNamesIncludingTaskHistories(address string, campaignID int64, names []string) ([]*models.TaskWithTaskHistory, error) {
 	placeholders := make([]string, len(names))
 	for i := range names {
-		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		placeholders[i] = fmt.Sprintf("$%d", i+3)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT t.id, t.name, t.description, t.points, t.started_at, t.end_at, t.period, t.created_at, t.updated_at,
+		SELECT t.id, t.campaign_id, t.name, t.description, t.points, t.started_at, t.end_at, t.period, t.chain_id, t.created_at, t.updated_at,
 			th.id, th.address, th.reward_points, th.amount, th.completed_at, th.created_at, th.updated_at
 		FROM tasks t
 		LEFT JOIN task_histories th ON t.id = th.task_id AND th.address = $1 AND t.name IN (%s)
+		WHERE $2 = 0 OR t.campaign_id = $2
 	`, strings.Join(placeholders, ","))
 
-	args := make([]interface{}, len(names)+1)
+	args := make([]interface{}, len(names)+2)
 	args[0] = address
+	args[1] = campaignID
 	for i, name := range names {
-		args[i+1] = name
+		args[i+2] = name
 	}
 
 	rows, err := t.db.Query(query, args...)
@@ -182,8 +194,8 @@ func (t *TaskRepository) GetByAddressAndNamesIncludingTaskHistories(address stri
 		taskWithHistory := &models.TaskWithTaskHistory{}
 
 		err := rows.Scan(
-			&taskWithHistory.TaskID, &taskWithHistory.TaskName, &taskWithHistory.TaskDescription, &taskWithHistory.TaskPoints,
-			&taskWithHistory.TaskStartedAt, &taskWithHistory.TaskEndAt, &taskWithHistory.TaskPeriod,
+			&taskWithHistory.TaskID, &taskWithHistory.TaskCampaignID, &taskWithHistory.TaskName, &taskWithHistory.TaskDescription, &taskWithHistory.TaskPoints,
+			&taskWithHistory.TaskStartedAt, &taskWithHistory.TaskEndAt, &taskWithHistory.TaskPeriod, &taskWithHistory.TaskChainID,
 			&taskWithHistory.TaskCreatedAt, &taskWithHistory.TaskUpdatedAt,
 			&taskWithHistory.TaskHistoryID, &taskWithHistory.TaskHistoryAddress, &taskWithHistory.TaskHistoryRewardPoints,
 			&taskWithHistory.TaskHistoryAmount, &taskWithHistory.TaskHistoryCompletedAt, &taskWithHistory.TaskHistoryCreatedAt, &taskWithHistory.TaskHistoryUpdatedAt,
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 	"time"
+	"trading-ace/decimal"
 	"trading-ace/entities"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -21,31 +22,36 @@ func TestCreate(t *testing.T) {
 
 	now := time.Now()
 
+	campaignID := int64(3)
 	task := &entities.Task{
-		Name:        "Test Task",
-		Description: "Test Description",
-		Points:      10,
-		StartedAt:   &now,
-		EndAt:       &now,
-		Period:      1,
+		CampaignID:   &campaignID,
+		Name:         "Test Task",
+		Description:  "Test Description",
+		Points:       decimal.NewFromInt(10),
+		TargetAmount: decimal.NewFromInt(1000),
+		StartedAt:    &now,
+		EndAt:        &now,
+		Period:       1,
 	}
 
 	// 設定 mock 查詢回傳值
 	mock.ExpectQuery(`
-		INSERT INTO tasks \(name, description, points, started_at, end_at, period, created_at, updated_at\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)
-		RETURNING id, name, description, points, started_at, end_at, period, created_at, updated_at
+		INSERT INTO tasks \(campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)
+		RETURNING id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 	`).
-		WithArgs(task.Name, task.Description, task.Points, task.StartedAt, task.EndAt, task.Period).
+		WithArgs(task.CampaignID, task.Name, task.Description, task.Points, task.TargetAmount, task.StartedAt, task.EndAt, task.Period, task.ChainID).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "description", "points", "started_at", "end_at", "period", "created_at", "updated_at",
-		}).AddRow(1, task.Name, task.Description, task.Points, task.StartedAt, task.EndAt, task.Period, now, now))
+			"id", "campaign_id", "name", "description", "points", "target_amount", "started_at", "end_at", "period", "chain_id", "created_at", "updated_at",
+		}).AddRow(1, *task.CampaignID, task.Name, task.Description, task.Points.String(), task.TargetAmount.String(), task.StartedAt, task.EndAt, task.Period, task.ChainID, now, now))
 
 	createdTask, err := repo.Create(task)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, createdTask)
 	assert.Equal(t, task.Name, createdTask.Name)
+	assert.Equal(t, campaignID, *createdTask.CampaignID)
+	assert.Equal(t, "1000", createdTask.TargetAmount.String())
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %s", err)
@@ -64,14 +70,14 @@ func TestFindById(t *testing.T) {
 	now := time.Now()
 
 	mock.ExpectQuery(`
-		SELECT id, name, description, points, started_at, end_at, period, created_at, updated_at
+		SELECT id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 		FROM tasks
 		WHERE id = \$1
 	`).
 		WithArgs(int64(1)).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "description", "points", "started_at", "end_at", "period", "created_at", "updated_at",
-		}).AddRow(1, "Test Task", "Test Description", 10, now, now, 1, now, now))
+			"id", "campaign_id", "name", "description", "points", "target_amount", "started_at", "end_at", "period", "chain_id", "created_at", "updated_at",
+		}).AddRow(1, nil, "Test Task", "Test Description", 10, 0, now, now, 1, nil, now, now))
 
 	task, err := repo.FindById(1)
 
@@ -96,14 +102,14 @@ func TestFindByName(t *testing.T) {
 	now := time.Now()
 
 	mock.ExpectQuery(`
-		SELECT id, name, description, points, started_at, end_at, period, created_at, updated_at
+		SELECT id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 		FROM tasks
 		WHERE name = \$1
 	`).
 		WithArgs("Test Task").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "description", "points", "started_at", "end_at", "period", "created_at", "updated_at",
-		}).AddRow(1, "Test Task", "Test Description", 10, now, now, 1, now, now))
+			"id", "campaign_id", "name", "description", "points", "target_amount", "started_at", "end_at", "period", "chain_id", "created_at", "updated_at",
+		}).AddRow(1, nil, "Test Task", "Test Description", 10, 0, now, now, 1, nil, now, now))
 
 	task, err := repo.FindByName("Test Task")
 
@@ -128,19 +134,29 @@ func TestGetByName(t *testing.T) {
 	now := time.Now()
 
 	mock.ExpectQuery(`
-		SELECT id, name, description, points, started_at, end_at, period, created_at, updated_at
+		SELECT id, campaign_id, name, description, points, target_amount, started_at, end_at, period, chain_id, created_at, updated_at
 		FROM tasks
 		WHERE name = \$1
 	`).
 		WithArgs("Test Task").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "description", "points", "started_at", "end_at", "period", "created_at", "updated_at",
-		}).AddRow(1, "Test Task", "Test Description", 10, now, now, 1, now, now))
+			"id", "campaign_id", "name", "description", "points", "target_amount", "started_at", "end_at", "period", "chain_id", "created_at", "updated_at",
+		}).
+			AddRow(1, nil, "Test Task", "Test Description", 10, 0, now, now, 1, nil, now, now).
+			AddRow(2, 3, "Test Task", "Test Description", 10, 0, now, now, 1, 42161, now, now))
 
 	tasks, err := repo.GetByName("Test Task")
 
 	assert.NoError(t, err)
-	assert.NotNil(t, tasks)
+	assert.Len(t, tasks, 2)
+
+	// tasks without a chain count the swaps of every chain
+	assert.Nil(t, tasks[0].ChainID)
+	assert.Equal(t, int64(42161), *tasks[1].ChainID)
+
+	// tasks created before campaigns were stored have no campaign
+	assert.Nil(t, tasks[0].CampaignID)
+	assert.Equal(t, int64(3), *tasks[1].CampaignID)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %s", err)
@@ -208,3 +224,30 @@ func TestIsExistedByName(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestGetByAddressAndNamesIncludingTaskHistories(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db)
+
+	now := time.Now()
+	campaignID := int64(2)
+
+	// only the tasks of the campaign are returned
+	mock.ExpectQuery(`SELECT t.id, t.campaign_id, (.+) FROM tasks t LEFT JOIN task_histories th (.+) WHERE \$2 = 0 OR t.campaign_id = \$2`).
+		WithArgs("0xabc", campaignID, "OnboardingTask", "SharePoolTask").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "campaign_id", "name", "description", "points", "started_at", "end_at", "period", "chain_id", "created_at", "updated_at",
+			"th_id", "address", "reward_points", "amount", "completed_at", "th_created_at", "th_updated_at",
+		}).AddRow(4, campaignID, "SharePoolTask", "SharePoolTask", "10000", now, now, 1, nil, now, now, nil, nil, nil, nil, nil, nil, nil))
+
+	results, err := repo.GetByAddressAndNamesIncludingTaskHistories("0xabc", campaignID, []string{"OnboardingTask", "SharePoolTask"})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, campaignID, *results[0].TaskCampaignID)
+	assert.Nil(t, results[0].TaskHistoryID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"trading-ace/entities"
+)
+
+type ITokenRepository interface {
+	FindToken(chainID int64, address string) (*entities.Token, error)
+	CreateToken(token *entities.Token) error
+	FindPool(chainID int64, address string) (*entities.Pool, error)
+	CreatePool(pool *entities.Pool) error
+}
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) ITokenRepository {
+	return &TokenRepository{
+		db: db,
+	}
+}
+
+func (r *TokenRepository) FindToken(chainID int64, address string) (*entities.Token, error) {
+	query := `
+		SELECT id, chain_id, address, symbol, decimals, created_at
+		FROM tokens
+		WHERE chain_id = $1 AND address = $2
+	`
+
+	var result entities.Token
+	err := r.db.QueryRow(query, chainID, address).Scan(
+		&result.ID, &result.ChainID, &result.Address, &result.Symbol, &result.Decimals, &result.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("token not found: %w", err)
+		}
+
+		return nil, fmt.Errorf("failed to find token: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CreateToken stores a token, a token already stored for the chain and address is left untouched.
+func (r *TokenRepository) CreateToken(token *entities.Token) error {
+	query := `
+		INSERT INTO tokens (chain_id, address, symbol, decimals, created_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (chain_id, address) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, token.ChainID, token.Address, token.Symbol, token.Decimals); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TokenRepository) FindPool(chainID int64, address string) (*entities.Pool, error) {
+	query := `
+		SELECT id, chain_id, address, token0_address, token1_address, created_at
+		FROM pools
+		WHERE chain_id = $1 AND address = $2
+	`
+
+	var result entities.Pool
+	err := r.db.QueryRow(query, chainID, address).Scan(
+		&result.ID, &result.ChainID, &result.Address, &result.Token0Address, &result.Token1Address, &result.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pool not found: %w", err)
+		}
+
+		return nil, fmt.Errorf("failed to find pool: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CreatePool stores a pool's tokens, a pool already stored for the chain and address is left untouched.
+func (r *TokenRepository) CreatePool(pool *entities.Pool) error {
+	query := `
+		INSERT INTO pools (chain_id, address, token0_address, token1_address, created_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (chain_id, address) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, pool.ChainID, pool.Address, pool.Token0Address, pool.Token1Address); err != nil {
+		return fmt.Errorf("failed to create pool: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, chain_id, address, symbol, decimals, created_at
+		FROM tokens`).
+		WithArgs(int64(1), "0xUSDC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chain_id", "address", "symbol", "decimals", "created_at"}).
+			AddRow(1, 1, "0xUSDC", "USDC", 6, now))
+
+	token, err := repo.FindToken(1, "0xUSDC")
+	assert.NoError(t, err)
+	assert.Equal(t, "USDC", token.Symbol)
+	assert.Equal(t, int64(6), token.Decimals)
+
+	// token missing
+	mock.ExpectQuery(`SELECT id, chain_id, address, symbol, decimals, created_at`).
+		WithArgs(int64(42161), "0xUSDC").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.FindToken(42161, "0xUSDC")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+
+	mock.ExpectExec(`INSERT INTO tokens`).
+		WithArgs(int64(1), "0xUSDC", "USDC", int64(6)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, repo.CreateToken(&entities.Token{ChainID: 1, Address: "0xUSDC", Symbol: "USDC", Decimals: 6}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindPool(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, chain_id, address, token0_address, token1_address, created_at
+		FROM pools`).
+		WithArgs(int64(1), "0xPair").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chain_id", "address", "token0_address", "token1_address", "created_at"}).
+			AddRow(1, 1, "0xPair", "0xUSDC", "0xWETH", now))
+
+	pool, err := repo.FindPool(1, "0xPair")
+	assert.NoError(t, err)
+	assert.Equal(t, "0xUSDC", pool.Token0Address)
+	assert.Equal(t, "0xWETH", pool.Token1Address)
+
+	// pool missing
+	mock.ExpectQuery(`SELECT id, chain_id, address, token0_address`).
+		WithArgs(int64(1), "0xOther").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.FindPool(1, "0xOther")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePool(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+
+	mock.ExpectExec(`INSERT INTO pools`).
+		WithArgs(int64(1), "0xPair", "0xUSDC", "0xWETH").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, repo.CreatePool(&entities.Pool{ChainID: 1, Address: "0xPair", Token0Address: "0xUSDC", Token1Address: "0xWETH"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
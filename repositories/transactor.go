@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DBTX runs the queries of a repository, a *sql.DB or, for a repository bound with WithTx, a *sql.Tx.
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type ITransactor interface {
+	InTransaction(fn func(tx *sql.Tx) error) error
+}
+
+type Transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) ITransactor {
+	return &Transactor{
+		db: db,
+	}
+}
+
+// InTransaction runs fn in a transaction, which is committed when fn returns nil and rolled back
+// otherwise. Repositories take part in it through their WithTx.
+func (t *Transactor) InTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+	"trading-ace/entities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	transactor := NewTransactor(db)
+	repo := NewSettlementJobRepository(db)
+	dueAt := time.Date(2024, 12, 8, 0, 0, 0, 0, time.UTC)
+
+	// the writes of a repository bound to the transaction are committed with it
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO settlement_jobs`).WithArgs(int64(7), dueAt).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = transactor.InTransaction(func(tx *sql.Tx) error {
+		return repo.WithTx(tx).Create(&entities.SettlementJob{TaskID: 7, DueAt: dueAt})
+	})
+	assert.NoError(t, err)
+
+	// and rolled back when a later write fails
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO settlement_jobs`).WithArgs(int64(7), dueAt).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+
+	failure := errors.New("task not created")
+	err = transactor.InTransaction(func(tx *sql.Tx) error {
+		if err := repo.WithTx(tx).Create(&entities.SettlementJob{TaskID: 7, DueAt: dueAt}); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+	assert.ErrorIs(t, transactor.InTransaction(func(tx *sql.Tx) error { return nil }), sql.ErrConnDone)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
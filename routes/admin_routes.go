@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"strings"
+	"trading-ace/config"
+	"trading-ace/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IAdminRoutes interface {
+	RegisterAdminRoutes()
+}
+
+type AdminRoutes struct {
+	r               *gin.Engine
+	adminController controllers.IAdminController
+	config          *config.Config
+}
+
+func NewAdminRoutes(r *gin.Engine, adminController controllers.IAdminController, config *config.Config) IAdminRoutes {
+	return &AdminRoutes{
+		r:               r,
+		adminController: adminController,
+		config:          config,
+	}
+}
+
+func (h *AdminRoutes) RegisterAdminRoutes() {
+	group := h.r.Group("/admin", h.requireToken)
+
+	group.GET("/failed-swap-logs", h.adminController.GetFailedSwapLogs)
+	group.POST("/failed-swap-logs/:id/retry", h.adminController.RetryFailedSwapLog)
+	group.DELETE("/failed-swap-logs/:id", h.adminController.DiscardFailedSwapLog)
+	group.POST("/campaigns", h.adminController.CreateCampaign)
+}
+
+// requireToken only lets requests carrying the configured admin token through.
+func (h *AdminRoutes) requireToken(ctx *gin.Context) {
+	if h.config.Admin.Token == "" {
+		ctx.AbortWithStatusJSON(403, gin.H{"status": "error", "message": "admin endpoints are disabled, set admin.token to enable them"})
+		return
+	}
+
+	token := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.Admin.Token)) != 1 {
+		ctx.AbortWithStatusJSON(401, gin.H{"status": "error", "message": "invalid admin token"})
+		return
+	}
+
+	ctx.Next()
+}
@@ -26,7 +26,9 @@ func (h *CampaignRoutes) RegisterCampaignRoutes() {
 	group := h.r.Group("/campaign")
 
 	group.GET("/start", h.campaignController.StartCampaign)
+	group.GET("/list", h.campaignController.ListCampaigns)
 	group.GET("/histories/:address", h.campaignController.GetPointHistories)
 	group.GET("/tasks/:address", h.campaignController.GetTaskStatus)
 	group.GET("/leaderboard/:taskName/:period", h.campaignController.GetLeaderboard)
+	group.GET("/swaps/:address", h.campaignController.GetSwapActivities)
 }
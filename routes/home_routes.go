@@ -31,6 +31,7 @@ func NewHomeRoutes(r *gin.Engine, homeController controllers.IHomeController, co
 
 func (h *HomeRoutes) RegisterHomeRoutes() {
 	h.r.GET("/", h.homeController.Home)
+	h.r.GET("/status", h.homeController.Status)
 
 	h.r.GET("/swagger.json", func(c *gin.Context) {
 		c.File("./docs/swagger.json")
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
+)
+
+// campaignTask is a validated task of a campaign definition.
+type campaignTask struct {
+	name         string
+	description  string
+	points       decimal.Decimal
+	targetAmount decimal.Decimal
+	periods      int
+	periodLength time.Duration
+	// a share pool run separately on every chain
+	perChain bool
+}
+
+// defaultCampaignDefinition is the campaign run when none is configured: a 28 day onboarding task
+// and four weekly share pool periods.
+func defaultCampaignDefinition() config.CampaignDefinition {
+	return config.CampaignDefinition{
+		Name: "trading-ace",
+		Tasks: []config.CampaignTaskDefinition{
+			{
+				Name:         OnboardingTaskStr,
+				Description:  OnboardingTaskDescription,
+				Points:       OnboardingTaskPoints.String(),
+				TargetAmount: OnboardingTaskTargetAmount.String(),
+				Periods:      1,
+				PeriodLength: 28 * 24 * time.Hour,
+			},
+			{
+				Name:         SharePoolTaskStr,
+				Description:  SharePoolTaskDescription,
+				Points:       SharePoolTaskPoints.String(),
+				Periods:      4,
+				PeriodLength: 7 * 24 * time.Hour,
+			},
+		},
+	}
+}
+
+// parseCampaignDefinition validates a definition. A campaign needs a share pool task, swap volume is
+// recorded per share pool period, and may have an onboarding task of a single period.
+func parseCampaignDefinition(definition config.CampaignDefinition) ([]*campaignTask, error) {
+	if definition.Name == "" {
+		return nil, fmt.Errorf("campaign has no name")
+	}
+
+	tasks := []*campaignTask{}
+	seen := map[string]bool{}
+	for _, taskDefinition := range definition.Tasks {
+		task, err := parseCampaignTask(taskDefinition)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[task.name] {
+			return nil, fmt.Errorf("task %s is declared twice", task.name)
+		}
+		seen[task.name] = true
+
+		tasks = append(tasks, task)
+	}
+
+	if !seen[SharePoolTaskStr] {
+		return nil, fmt.Errorf("campaign %s has no %s", definition.Name, SharePoolTaskStr)
+	}
+
+	return tasks, nil
+}
+
+func parseCampaignTask(definition config.CampaignTaskDefinition) (*campaignTask, error) {
+	if definition.Name != OnboardingTaskStr && definition.Name != SharePoolTaskStr {
+		return nil, fmt.Errorf("unknown task %q, expected %s or %s", definition.Name, OnboardingTaskStr, SharePoolTaskStr)
+	}
+
+	points, err := decimal.Parse(definition.Points)
+	if err != nil || points.Sign() <= 0 {
+		return nil, fmt.Errorf("task %s needs positive points, got %q", definition.Name, definition.Points)
+	}
+
+	task := &campaignTask{
+		name:         definition.Name,
+		description:  definition.Description,
+		points:       points,
+		periods:      definition.Periods,
+		periodLength: definition.PeriodLength,
+		perChain:     definition.PerChain,
+	}
+
+	if task.description == "" {
+		task.description = definition.Name
+	}
+
+	if task.periods == 0 {
+		task.periods = 1
+	}
+
+	if task.periods < 0 {
+		return nil, fmt.Errorf("task %s has %d periods", definition.Name, definition.Periods)
+	}
+
+	if task.periodLength <= 0 {
+		return nil, fmt.Errorf("task %s needs a positive period length", definition.Name)
+	}
+
+	if definition.Name == OnboardingTaskStr {
+		if task.perChain {
+			return nil, fmt.Errorf("task %s counts the swaps of every chain", definition.Name)
+		}
+
+		if task.periods != 1 {
+			return nil, fmt.Errorf("task %s runs a single period", definition.Name)
+		}
+
+		targetAmount, err := decimal.Parse(definition.TargetAmount)
+		if err != nil || targetAmount.Sign() <= 0 {
+			return nil, fmt.Errorf("task %s needs a positive target amount, got %q", definition.Name, definition.TargetAmount)
+		}
+		task.targetAmount = targetAmount
+	}
+
+	return task, nil
+}
+
+// length is the time the task runs, all its periods together.
+func (t *campaignTask) length() time.Duration {
+	return time.Duration(t.periods) * t.periodLength
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"trading-ace/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCampaignDefinition(t *testing.T) {
+	tasks, err := parseCampaignDefinition(defaultCampaignDefinition())
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, 28*24*time.Hour, tasks[0].length())
+	assert.Equal(t, 28*24*time.Hour, tasks[1].length())
+	assert.Equal(t, OnboardingTaskTargetAmount.String(), tasks[0].targetAmount.String())
+
+	sharePool := config.CampaignTaskDefinition{Name: SharePoolTaskStr, Points: "500", PeriodLength: time.Hour}
+
+	// periods default to one and descriptions to the task name
+	tasks, err = parseCampaignDefinition(config.CampaignDefinition{Name: "hourly", Tasks: []config.CampaignTaskDefinition{sharePool}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tasks[0].periods)
+	assert.Equal(t, SharePoolTaskStr, tasks[0].description)
+
+	invalid := map[string]config.CampaignDefinition{
+		"no name":              {Tasks: []config.CampaignTaskDefinition{sharePool}},
+		"no share pool":        {Name: "c", Tasks: []config.CampaignTaskDefinition{{Name: OnboardingTaskStr, Points: "1", TargetAmount: "1", PeriodLength: time.Hour}}},
+		"duplicate task":       {Name: "c", Tasks: []config.CampaignTaskDefinition{sharePool, sharePool}},
+		"unknown task":         {Name: "c", Tasks: []config.CampaignTaskDefinition{sharePool, {Name: "ReferralTask", Points: "1", PeriodLength: time.Hour}}},
+		"no points":            {Name: "c", Tasks: []config.CampaignTaskDefinition{{Name: SharePoolTaskStr, PeriodLength: time.Hour}}},
+		"no period length":     {Name: "c", Tasks: []config.CampaignTaskDefinition{{Name: SharePoolTaskStr, Points: "1"}}},
+		"onboarding per chain": {Name: "c", Tasks: []config.CampaignTaskDefinition{sharePool, {Name: OnboardingTaskStr, Points: "1", TargetAmount: "1", PeriodLength: time.Hour, PerChain: true}}},
+		"no target amount":     {Name: "c", Tasks: []config.CampaignTaskDefinition{sharePool, {Name: OnboardingTaskStr, Points: "1", PeriodLength: time.Hour}}},
+		"onboarding periods": {Name: "c", Tasks: []config.CampaignTaskDefinition{
+			sharePool,
+			{Name: OnboardingTaskStr, Points: "1", TargetAmount: "1", Periods: 2, PeriodLength: time.Hour},
+		}},
+	}
+
+	for name, definition := range invalid {
+		_, err := parseCampaignDefinition(definition)
+		assert.Error(t, err, name)
+	}
+}
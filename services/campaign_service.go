@@ -1,47 +1,87 @@
 package services
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"sort"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"trading-ace/config"
+	"trading-ace/decimal"
 	"trading-ace/entities"
 	"trading-ace/helpers"
 	"trading-ace/logger"
 	"trading-ace/models"
 	"trading-ace/repositories"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-redis/redis/v8"
 )
 
 type ICampaignService interface {
 	StartCampaign() error
-	GetPointHistories(address string) ([]*models.TaskTaskHistoryPair, error)
-	RecordUSDCSwapTotalAmount(senderAddress string, amount float64) (float64, error)
-	GetTaskStatus(address string) ([]*models.TaskWithTaskHistory, error)
-	FindOnboardingTask() (*entities.Task, error)
-	FindCurrentSharePoolTask() (*entities.Task, error)
-	GetLeaderboard(taskName string, period int) ([]models.LeaderboardEntry, error)
+	CreateCampaign(definition config.CampaignDefinition) (*entities.Campaign, error)
+	ListCampaigns() ([]*entities.Campaign, error)
+	GetPointHistories(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error)
+	RecordUSDCSwapTotalAmount(task *entities.Task, senderAddress string, amount decimal.Decimal, swappedAt time.Time) (decimal.Decimal, error)
+	RevertUSDCSwapTotalAmount(task *entities.Task, senderAddress string, amount decimal.Decimal, swappedAt time.Time) (decimal.Decimal, error)
+	GetTaskStatus(address string, campaignID int64) ([]*models.TaskWithTaskHistory, error)
+	FindOnboardingTask(campaignID *int64) (*entities.Task, error)
+	FindSharePoolTasksAt(chainID int64, poolAddress string, at time.Time) ([]*entities.Task, error)
+	FindSharePoolTasksByIDs(ids []int64) ([]*entities.Task, error)
+	GetLeaderboard(campaignID int64, taskName string, period int, chainID int64) ([]models.LeaderboardEntry, error)
+	GetSwapActivities(address string, campaignID int64, period int, page int, pageSize int) ([]*models.SwapActivity, int, error)
+	SettleSharePoolTask(task *entities.Task, jobID int64, fencingToken int64) error
 }
 
 type CampaignService struct {
-	config          *config.Config
-	logger          logger.ILogger
-	taskHistoryRepo repositories.ITaskHistoryRepository
-	taskRepo        repositories.ITaskRepository
-	redisHelper     helpers.IRedisHelper
+	config            *config.Config
+	logger            logger.ILogger
+	taskHistoryRepo   repositories.ITaskHistoryRepository
+	taskRepo          repositories.ITaskRepository
+	redisHelper       helpers.IRedisHelper
+	swapEventRepo     repositories.ISwapEventRepository
+	campaignRepo      repositories.ICampaignRepository
+	settlementJobRepo repositories.ISettlementJobRepository
+	transactor        repositories.ITransactor
+	locker            helpers.ILocker
+	// keys of tasks created before campaigns whose float amounts are known to be converted
+	convertedKeys sync.Map
 }
 
 const OnboardingTaskStr string = "OnboardingTask"
 const OnboardingTaskDescription string = "OnboardingTask"
-const OnboardingTaskPoints float64 = 100
-const OnboardingTaskTargetAmount float64 = 1000
+
+var OnboardingTaskPoints = decimal.NewFromInt(100)
+var OnboardingTaskTargetAmount = decimal.NewFromInt(1000)
 
 const SharePoolTaskStr string = "SharePoolTask"
 const SharePoolTaskDescription string = "SharePoolTask"
-const SharePoolTaskPoints float64 = 10000
+
+var SharePoolTaskPoints = decimal.NewFromInt(10000)
+
+// the share pool tasks and campaigns never change once created, the cached lists are dropped when a
+// campaign starts
+const sharePoolTasksCacheTTL = time.Hour
+const sharePoolTasksKey string = "share_pool_tasks"
+const campaignsKey string = "campaigns"
+
+// held while a campaign is created, so instances cannot both pass the check for a running campaign
+const campaignLockName = "campaign_create"
+const campaignLockTTL = 30 * time.Second
+
+// errSwapOutsideCampaign is returned for swaps whose block time falls outside every share pool period
+var errSwapOutsideCampaign = fmt.Errorf("swap is outside of every share pool period")
+
+// errNoOnboardingTask is returned for campaigns declared without an onboarding task
+var errNoOnboardingTask = fmt.Errorf("campaign has no onboarding task")
+
+// errSwapNotRecorded wraps the failures of RecordUSDCSwapTotalAmount, which leave the volume unrecorded
+var errSwapNotRecorded = fmt.Errorf("swap volume not recorded")
 
 func NewCampaignService(
 	config *config.Config,
@@ -49,77 +89,271 @@ func NewCampaignService(
 	taskHistoryRepo repositories.ITaskHistoryRepository,
 	taskRepo repositories.ITaskRepository,
 	redisHelper helpers.IRedisHelper,
+	swapEventRepo repositories.ISwapEventRepository,
+	campaignRepo repositories.ICampaignRepository,
+	settlementJobRepo repositories.ISettlementJobRepository,
+	transactor repositories.ITransactor,
+	locker helpers.ILocker,
 ) ICampaignService {
 	return &CampaignService{
-		config:          config,
-		logger:          logger,
-		taskHistoryRepo: taskHistoryRepo,
-		taskRepo:        taskRepo,
-		redisHelper:     redisHelper,
+		config:            config,
+		logger:            logger,
+		taskHistoryRepo:   taskHistoryRepo,
+		taskRepo:          taskRepo,
+		redisHelper:       redisHelper,
+		swapEventRepo:     swapEventRepo,
+		campaignRepo:      campaignRepo,
+		settlementJobRepo: settlementJobRepo,
+		transactor:        transactor,
+		locker:            locker,
 	}
 }
 
+// StartCampaign starts the configured campaign, or the built in one when none is configured.
 func (s *CampaignService) StartCampaign() error {
-	// if exists
-	if err := s.createOnboardingTask(); err != nil {
-		return err
+	definition := s.config.Campaign.Definition
+	if len(definition.Tasks) == 0 {
+		definition = defaultCampaignDefinition()
+	}
+
+	_, err := s.CreateCampaign(definition)
+
+	return err
+}
+
+// CreateCampaign starts a campaign from its definition. Campaigns may run side by side, but not two
+// of the same name. Only one instance creates a campaign at a time.
+func (s *CampaignService) CreateCampaign(definition config.CampaignDefinition) (*entities.Campaign, error) {
+	tasks, err := parseCampaignDefinition(definition)
+	if err != nil {
+		return nil, err
 	}
 
-	//share pool task
-	shareTasks, err := s.createSharePoolTask()
+	pools, err := s.campaignPools(definition.Pools)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.startLimitedWeeklySettlementScheduler(shareTasks)
+	lease, err := s.locker.Acquire(campaignLockName, campaignLockTTL)
+	if errors.Is(err, helpers.ErrLockHeld) {
+		return nil, fmt.Errorf("another instance is creating a campaign: %w", err)
+	}
 
-	return nil
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := lease.Release(); err != nil {
+			s.logger.Error(err)
+		}
+	}()
+
+	startedAt := time.Now().UTC()
+
+	campaigns, err := s.campaignRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, campaign := range campaigns {
+		if campaign.Name == definition.Name && campaign.EndAt.After(startedAt) {
+			return nil, fmt.Errorf("campaign %s is running until %s", campaign.Name, campaign.EndAt.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if isLost(lease) {
+		return nil, fmt.Errorf("lost the campaign lock before creating campaign %s", definition.Name)
+	}
+
+	endAt := startedAt
+	for _, task := range tasks {
+		if taskEndAt := startedAt.Add(task.length()); taskEndAt.After(endAt) {
+			endAt = taskEndAt
+		}
+	}
+
+	// the campaign, its tasks and their settlements are created together or not at all
+	var campaign *entities.Campaign
+	err = s.transactor.InTransaction(func(tx *sql.Tx) error {
+		created, err := s.campaignRepo.WithTx(tx).Create(&entities.Campaign{Name: definition.Name, StartedAt: startedAt, EndAt: endAt, Pools: pools})
+		if err != nil {
+			return err
+		}
+
+		for _, task := range tasks {
+			createdTasks, err := s.createCampaignTasks(s.taskRepo.WithTx(tx), created, task)
+			if err != nil {
+				return err
+			}
+
+			if task.name != SharePoolTaskStr {
+				continue
+			}
+
+			if err := s.scheduleSettlements(s.settlementJobRepo.WithTx(tx), createdTasks); err != nil {
+				return err
+			}
+		}
+
+		campaign = created
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign %s: %w", definition.Name, err)
+	}
+
+	// the cached lists lack the new campaign
+	s.redisHelper.Delete(sharePoolTasksKey)
+	s.redisHelper.Delete(campaignsKey)
+
+	s.logger.Info(fmt.Sprintf("campaign %d (%s) started, it ends at %s", campaign.ID, campaign.Name, campaign.EndAt.Format(time.RFC3339)))
+
+	return campaign, nil
+}
+
+// campaignPools checks that the pools of a definition are tracked, a pool without a chain is on mainnet.
+func (s *CampaignService) campaignPools(definitions []config.CampaignPoolDefinition) ([]*entities.CampaignPool, error) {
+	pools := []*entities.CampaignPool{}
+	for _, definition := range definitions {
+		chainID := definition.ChainID
+		if chainID == 0 {
+			chainID = config.MainnetChainID
+		}
+
+		if !s.isTrackedPool(chainID, definition.Address) {
+			return nil, fmt.Errorf("pool %s is not tracked on chain %d", definition.Address, chainID)
+		}
+
+		pools = append(pools, &entities.CampaignPool{ChainID: chainID, PoolAddress: common.HexToAddress(definition.Address).Hex()})
+	}
+
+	return pools, nil
 }
 
-func (s *CampaignService) GetPointHistories(address string) ([]*models.TaskTaskHistoryPair, error) {
-	return s.taskHistoryRepo.GetByAddressIncludingTasks(address)
+func (s *CampaignService) isTrackedPool(chainID int64, address string) bool {
+	if !common.IsHexAddress(address) {
+		return false
+	}
+
+	for _, chain := range s.config.ChainConfigs() {
+		if chain.ID != chainID {
+			continue
+		}
+
+		for _, pool := range chain.Pools {
+			if strings.EqualFold(pool.Address, address) {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
-func (s *CampaignService) GetTaskStatus(address string) ([]*models.TaskWithTaskHistory, error) {
-	return s.taskRepo.GetByAddressAndNamesIncludingTaskHistories(address, []string{OnboardingTaskStr, SharePoolTaskStr})
+func (s *CampaignService) ListCampaigns() ([]*entities.Campaign, error) {
+	return s.campaignRepo.List()
 }
 
-func (s *CampaignService) RecordUSDCSwapTotalAmount(senderAddress string, amount float64) (float64, error) {
-	// find current share task
-	task, err := s.FindCurrentSharePoolTask()
+// GetPointHistories returns the completed tasks of address, a campaignID of 0 returns those of every campaign.
+func (s *CampaignService) GetPointHistories(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error) {
+	return s.taskHistoryRepo.GetByAddressIncludingTasks(address, campaignID)
+}
+
+// GetTaskStatus returns the tasks with the progress of address, a campaignID of 0 returns the tasks of
+// every campaign.
+func (s *CampaignService) GetTaskStatus(address string, campaignID int64) ([]*models.TaskWithTaskHistory, error) {
+	return s.taskRepo.GetByAddressAndNamesIncludingTaskHistories(address, campaignID, []string{OnboardingTaskStr, SharePoolTaskStr})
+}
+
+// GetSwapActivities returns a page of the swaps a campaign credited to address, newest first, and the
+// total number of its swaps. A campaignID of 0 selects the latest campaign, a period of 0 lists the
+// swaps of every period.
+func (s *CampaignService) GetSwapActivities(address string, campaignID int64, period int, page int, pageSize int) ([]*models.SwapActivity, int, error) {
+	// swaps are stored under the lowercase address without the 0x prefix
+	address = strings.TrimPrefix(strings.ToLower(address), "0x")
+
+	taskCampaignID, err := s.findCampaignID(campaignID)
 	if err != nil {
-		return 0, err
+		return nil, 0, err
+	}
+
+	return s.swapEventRepo.GetByAddress(address, taskCampaignID, period, pageSize, (page-1)*pageSize)
+}
+
+// findCampaignID returns the ID the tasks of a campaign carry. A campaignID of 0 selects the latest
+// campaign, or the tasks created before campaigns were stored when there is none.
+func (s *CampaignService) findCampaignID(campaignID int64) (*int64, error) {
+	if campaignID != 0 {
+		campaign, err := s.campaignRepo.FindByID(campaignID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &campaign.ID, nil
+	}
+
+	campaign, err := s.campaignRepo.FindLatest()
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
 	}
 
-	key := fmt.Sprintf("%s_%d", task.Name, task.Period)
-	s.redisHelper.HIncrFloat(key, senderAddress, amount)
-	totalAmountStr, err := s.redisHelper.HGet(key, senderAddress)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	totalKey := fmt.Sprintf("%s_total", key)
-	s.redisHelper.IncrFloat(totalKey, amount)
+	return &campaign.ID, nil
+}
 
-	totalAmount, err := strconv.ParseFloat(totalAmountStr, 64)
+// RecordUSDCSwapTotalAmount adds a swap to a share pool task it counts towards, see
+// FindSharePoolTasksAt, and completes the onboarding task of the campaign once the address reaches
+// the target within the onboarding window. The swap is recorded whole or not at all: on any failure
+// the volume is taken back and the error wraps errSwapNotRecorded, so the swap can be recorded again.
+func (s *CampaignService) RecordUSDCSwapTotalAmount(task *entities.Task, senderAddress string, amount decimal.Decimal, swappedAt time.Time) (decimal.Decimal, error) {
+	// the address amount and the period total are updated together
+	totalAmount, err := s.incrSwapAmount(task, senderAddress, amount)
 	if err != nil {
-		return 0, err
+		return decimal.Decimal{}, fmt.Errorf("%w: %w", errSwapNotRecorded, err)
 	}
 
-	// if amount is not enough
-	if totalAmount < OnboardingTaskTargetAmount {
-		return totalAmount, nil
+	if err := s.completeOnboarding(task, senderAddress, totalAmount, swappedAt); err != nil {
+		if _, revertErr := s.incrSwapAmount(task, senderAddress, amount.Neg()); revertErr != nil {
+			s.logger.Error(fmt.Errorf("failed to take back the volume of %s: %w", senderAddress, revertErr))
+		}
+
+		return decimal.Decimal{}, fmt.Errorf("%w: %w", errSwapNotRecorded, err)
+	}
+
+	return totalAmount, nil
+}
+
+// completeOnboarding records the onboarding completion of an address whose total amount reached the
+// target of its campaign's onboarding task.
+func (s *CampaignService) completeOnboarding(task *entities.Task, senderAddress string, totalAmount decimal.Decimal, swappedAt time.Time) error {
+	onboardingTask, err := s.FindOnboardingTask(task.CampaignID)
+	if errors.Is(err, errNoOnboardingTask) {
+		return nil
 	}
 
-	onboardingTask, err := s.FindOnboardingTask()
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	// if amount is not enough
+	if totalAmount.Cmp(onboardingTask.TargetAmount) < 0 {
+		return nil
+	}
+
+	// swaps after the onboarding window still count for the share pool, but no longer onboard
+	if !isWithinTask(onboardingTask, swappedAt) {
+		return nil
 	}
 
 	// find existed onboarding completed task record
 	_, err = s.taskHistoryRepo.FindByAddressAndTaskId(senderAddress, onboardingTask.ID)
 	if err == nil {
-		return totalAmount, nil
+		return nil
 	}
 
 	// create onboarding task record
@@ -127,95 +361,291 @@ func (s *CampaignService) RecordUSDCSwapTotalAmount(senderAddress string, amount
 	taskHistory := &entities.TaskHistory{
 		Address:      senderAddress,
 		TaskID:       onboardingTask.ID,
-		RewardPoints: OnboardingTaskPoints,
+		RewardPoints: onboardingTask.Points,
 		Amount:       totalAmount,
 		CompletedAt:  &now,
 	}
 
-	s.taskHistoryRepo.Create(taskHistory)
+	if _, err := s.taskHistoryRepo.Create(taskHistory); err != nil {
+		return fmt.Errorf("failed to complete the onboarding of %s: %w", senderAddress, err)
+	}
+
+	return nil
+}
+
+// RevertUSDCSwapTotalAmount takes back a previously recorded amount from the task it was recorded
+// to, e.g. for a swap dropped by a chain reorganization. An onboarding completion is revoked only when
+// the swap was made within the onboarding window, no later than the completion, and took back the
+// amount that reached the target: the task held at least the completed amount and is now below the target.
+func (s *CampaignService) RevertUSDCSwapTotalAmount(task *entities.Task, senderAddress string, amount decimal.Decimal, swappedAt time.Time) (decimal.Decimal, error) {
+	totalAmount, err := s.incrSwapAmount(task, senderAddress, amount.Neg())
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	onboardingTask, err := s.FindOnboardingTask(task.CampaignID)
+	if errors.Is(err, errNoOnboardingTask) {
+		return totalAmount, nil
+	}
+
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	// swaps outside the onboarding window never onboarded the address
+	if !isWithinTask(onboardingTask, swappedAt) {
+		return totalAmount, nil
+	}
+
+	if totalAmount.Cmp(onboardingTask.TargetAmount) >= 0 {
+		return totalAmount, nil
+	}
+
+	history, err := s.taskHistoryRepo.FindByAddressAndTaskId(senderAddress, onboardingTask.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// no onboarding completion to revoke
+		return totalAmount, nil
+	}
+
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("failed to find the onboarding of %s: %w", senderAddress, err)
+	}
+
+	// a swap made after the completion did not reach the target
+	if history.CompletedAt != nil && swappedAt.After(*history.CompletedAt) {
+		return totalAmount, nil
+	}
+
+	// the target was reached by the amount of another task
+	if totalAmount.Add(amount).Cmp(history.Amount) < 0 {
+		return totalAmount, nil
+	}
+
+	if err := s.taskHistoryRepo.Delete(history.ID); err != nil {
+		return decimal.Decimal{}, fmt.Errorf("failed to revoke the onboarding of %s: %w", senderAddress, err)
+	}
 
 	return totalAmount, nil
 }
 
-func (s *CampaignService) createOnboardingTask() error {
-	isExisted, err := s.taskRepo.IsExistedByName(OnboardingTaskStr)
+// incrSwapAmount adds amount to the address and the period total of a share pool task and returns
+// the new address amount. Redis keeps the amounts as integer counts of decimal units, so sums are exact.
+func (s *CampaignService) incrSwapAmount(task *entities.Task, senderAddress string, amount decimal.Decimal) (decimal.Decimal, error) {
+	key := campaignKey(task.CampaignID, taskKey(task.Name, task.Period, task.ChainID))
+	totalKey := fmt.Sprintf("%s_total", key)
+
+	if err := s.convertLegacySwapAmounts(task, key, totalKey); err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	units := amount.Units()
+	if !units.IsInt64() {
+		return decimal.Decimal{}, fmt.Errorf("amount %s is out of range", amount)
+	}
+
+	totalUnits, err := s.redisHelper.HIncrByWithTotal(key, senderAddress, totalKey, units.Int64())
 	if err != nil {
-		return err
+		return decimal.Decimal{}, err
 	}
 
-	if isExisted {
-		return fmt.Errorf("onboarding task is existed")
+	return decimal.New(big.NewInt(totalUnits)), nil
+}
+
+// convertLegacySwapAmounts turns the float USD amounts that older releases wrote for tasks created
+// before campaigns were stored into integer counts of decimal units. The conversion runs once per key
+// in Redis, before anything else touches it, since a float like "2" can't be told from 2 units later.
+func (s *CampaignService) convertLegacySwapAmounts(task *entities.Task, key string, totalKey string) error {
+	// tasks of stored campaigns were written in units from the start
+	if task.CampaignID != nil {
+		return nil
 	}
 
-	startedAt := time.Now().UTC()
-	endAt := startedAt.Add(28 * 24 * time.Hour)
+	if _, ok := s.convertedKeys.Load(key); ok {
+		return nil
+	}
 
-	newTask := &entities.Task{
-		Name:        OnboardingTaskStr,
-		Description: OnboardingTaskDescription,
-		Points:      OnboardingTaskPoints,
-		StartedAt:   &startedAt,
-		EndAt:       &endAt,
-		Period:      1,
+	converted, err := s.redisHelper.ScaleToIntegers(key, totalKey, fmt.Sprintf("%s_units", key), decimal.NewFromInt(1).Units().Int64())
+	if err != nil {
+		return fmt.Errorf("failed to convert the swap amounts of %s: %w", key, err)
 	}
 
-	if _, err := s.taskRepo.Create(newTask); err != nil {
-		return fmt.Errorf("failed to create task: %w", err)
+	if converted {
+		s.logger.Info(fmt.Sprintf("converted the swap amounts of %s to decimal units", key))
 	}
 
+	s.convertedKeys.Store(key, struct{}{})
+
 	return nil
 }
 
-func (s *CampaignService) createSharePoolTask() ([]*entities.Task, error) {
-	isExisted, err := s.taskRepo.IsExistedByName(SharePoolTaskStr)
+// createCampaignTasks creates the periods of a campaign task, for a share pool run per chain every
+// chain gets its own periods.
+func (s *CampaignService) createCampaignTasks(taskRepo repositories.ITaskRepository, campaign *entities.Campaign, task *campaignTask) ([]*entities.Task, error) {
+	// nil adds up the swaps of every chain in one pool
+	chainIDs := []*int64{nil}
+	if task.perChain {
+		chainIDs = []*int64{}
+		for _, chain := range s.config.ChainConfigs() {
+			chainIDs = append(chainIDs, &chain.ID)
+		}
+	}
+
+	results := []*entities.Task{}
+	for _, chainID := range chainIDs {
+		periodStart := campaign.StartedAt
+		for i := 1; i <= task.periods; i++ {
+			startedAt := periodStart
+			endAt := startedAt.Add(task.periodLength)
+
+			newTask := &entities.Task{
+				CampaignID:   &campaign.ID,
+				Name:         task.name,
+				Description:  task.description,
+				Points:       task.points,
+				TargetAmount: task.targetAmount,
+				StartedAt:    &startedAt,
+				EndAt:        &endAt,
+				Period:       i,
+				ChainID:      chainID,
+			}
+
+			created, err := taskRepo.Create(newTask)
+			if err != nil {
+				return []*entities.Task{}, fmt.Errorf("failed to create task: %w", err)
+			}
+
+			results = append(results, created)
+
+			periodStart = endAt
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Period < results[j].Period
+	})
+
+	return results, nil
+}
+
+// FindSharePoolTasksByIDs returns the share pool tasks with the given ids, in the order of the ids.
+func (s *CampaignService) FindSharePoolTasksByIDs(ids []int64) ([]*entities.Task, error) {
+	tasks, err := s.getSharePoolTasks()
 	if err != nil {
-		return []*entities.Task{}, err
+		return nil, err
 	}
 
-	if isExisted {
-		return []*entities.Task{}, fmt.Errorf("share pool task is existed")
+	byID := map[int64]*entities.Task{}
+	for _, task := range tasks {
+		byID[task.ID] = task
 	}
 
-	startedAt := time.Now().UTC()
 	results := []*entities.Task{}
-	for i := 1; i <= 4; i++ {
-		var duration = 7 * 24 * time.Hour
-		endAt := startedAt.Add(duration)
-
-		newTask := &entities.Task{
-			Name:        SharePoolTaskStr,
-			Description: SharePoolTaskDescription,
-			Points:      SharePoolTaskPoints,
-			StartedAt:   &startedAt,
-			EndAt:       &endAt,
-			Period:      i,
+	for _, id := range ids {
+		task, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("share pool task %d not found", id)
 		}
 
-		task, err := s.taskRepo.Create(newTask)
-		if err != nil {
-			return []*entities.Task{}, fmt.Errorf("failed to create task: %w", err)
+		results = append(results, task)
+	}
+
+	return results, nil
+}
+
+// FindSharePoolTasksAt returns the share pool task of every campaign a swap in a pool on chainID at
+// the given time counts towards, periods include their start and exclude their end. A task either
+// counts the swaps of every chain or only those of its own chain, a campaign either counts every
+// tracked pool or only its own. errSwapOutsideCampaign is returned when no campaign counts the swap.
+func (s *CampaignService) FindSharePoolTasksAt(chainID int64, poolAddress string, at time.Time) ([]*entities.Task, error) {
+	tasks, err := s.getSharePoolTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	// the campaigns are only looked up once a task of one matches
+	var campaigns map[int64]*entities.Campaign
+
+	results := []*entities.Task{}
+	for _, task := range tasks {
+		if task.ChainID != nil && *task.ChainID != chainID {
+			continue
+		}
+
+		if !isWithinTask(task, at) {
+			continue
+		}
+
+		if task.CampaignID != nil {
+			if campaigns == nil {
+				campaigns, err = s.getCampaigns()
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			campaign, ok := campaigns[*task.CampaignID]
+			if !ok || !countsPool(campaign, chainID, poolAddress) {
+				continue
+			}
 		}
 
 		results = append(results, task)
+	}
 
-		startedAt = endAt
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: %s", errSwapOutsideCampaign, at.UTC().Format(time.RFC3339))
 	}
 
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Period < results[j].Period
-	})
+	return results, nil
+}
+
+// countsPool reports whether a campaign counts the swaps of a pool, a campaign without pools counts
+// every tracked pool.
+func countsPool(campaign *entities.Campaign, chainID int64, poolAddress string) bool {
+	if len(campaign.Pools) == 0 {
+		return true
+	}
+
+	for _, pool := range campaign.Pools {
+		if pool.ChainID == chainID && strings.EqualFold(pool.PoolAddress, poolAddress) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCampaigns returns every campaign by ID.
+func (s *CampaignService) getCampaigns() (map[int64]*entities.Campaign, error) {
+	campaigns := []*entities.Campaign{}
+
+	redisData, err := s.redisHelper.Get(campaignsKey)
+	if err != nil || json.Unmarshal([]byte(redisData), &campaigns) != nil {
+		campaigns, err = s.campaignRepo.List()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch campaigns: %w", err)
+		}
+
+		encodedCampaigns, _ := json.Marshal(campaigns)
+		s.redisHelper.Set(campaignsKey, string(encodedCampaigns), sharePoolTasksCacheTTL)
+	}
+
+	results := map[int64]*entities.Campaign{}
+	for _, campaign := range campaigns {
+		results[campaign.ID] = campaign
+	}
 
 	return results, nil
 }
 
-func (s *CampaignService) FindCurrentSharePoolTask() (*entities.Task, error) {
-	key := "curr_shared_pool_task"
+func (s *CampaignService) getSharePoolTasks() ([]*entities.Task, error) {
+	key := sharePoolTasksKey
 	redisData, err := s.redisHelper.Get(key)
 	if err == nil {
-		task := &entities.Task{}
-		json.Unmarshal([]byte(redisData), &task)
-
-		return task, nil
+		tasks := []*entities.Task{}
+		if err := json.Unmarshal([]byte(redisData), &tasks); err == nil {
+			return tasks, nil
+		}
 	}
 
 	tasks, err := s.taskRepo.GetByName(SharePoolTaskStr)
@@ -223,21 +653,55 @@ func (s *CampaignService) FindCurrentSharePoolTask() (*entities.Task, error) {
 		return nil, fmt.Errorf("failed to fetch share pool tasks: %w", err)
 	}
 
-	now := time.Now().UTC()
-	for _, task := range tasks {
-		if task.StartedAt != nil && task.EndAt != nil && now.After(*task.StartedAt) && now.Before(*task.EndAt) {
-			encodedTask, _ := json.Marshal(task)
-			s.redisHelper.Set(key, string(encodedTask), time.Until(*task.EndAt))
+	// an empty list is not cached, the campaign may not have been started yet
+	if len(tasks) > 0 {
+		encodedTasks, _ := json.Marshal(tasks)
+		s.redisHelper.Set(key, string(encodedTasks), sharePoolTasksCacheTTL)
+	}
 
-			return task, nil
-		}
+	return tasks, nil
+}
+
+// taskKey is the Redis key of the swap amounts of a task period. Tasks of a single chain keep their
+// amounts apart under the chain ID, tasks of every chain keep the key they had before chains.
+func taskKey(taskName string, period int, chainID *int64) string {
+	if chainID == nil {
+		return fmt.Sprintf("%s_%d", taskName, period)
+	}
+
+	return fmt.Sprintf("%s_%d_chain_%d", taskName, period, *chainID)
+}
+
+// campaignKey keeps a Redis key of a campaign apart from those of other campaigns, tasks created
+// before campaigns were stored keep their keys.
+func campaignKey(campaignID *int64, key string) string {
+	if campaignID == nil {
+		return key
+	}
+
+	return fmt.Sprintf("campaign_%d_%s", *campaignID, key)
+}
+
+func isSameCampaign(a *int64, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
 	}
 
-	return nil, fmt.Errorf("no active share pool task found")
+	return *a == *b
 }
 
-func (s *CampaignService) FindOnboardingTask() (*entities.Task, error) {
-	key := "onboarding_task"
+func isWithinTask(task *entities.Task, at time.Time) bool {
+	if task.StartedAt == nil || task.EndAt == nil {
+		return false
+	}
+
+	return !at.Before(*task.StartedAt) && at.Before(*task.EndAt)
+}
+
+// FindOnboardingTask returns the onboarding task of a campaign, a nil campaignID selects the one
+// created before campaigns were stored. errNoOnboardingTask is returned when there is none.
+func (s *CampaignService) FindOnboardingTask(campaignID *int64) (*entities.Task, error) {
+	key := campaignKey(campaignID, "onboarding_task")
 	redisData, err := s.redisHelper.Get(key)
 	if err == nil {
 		task := &entities.Task{}
@@ -246,99 +710,151 @@ func (s *CampaignService) FindOnboardingTask() (*entities.Task, error) {
 		return task, nil
 	}
 
-	task, err := s.taskRepo.FindByName(OnboardingTaskStr)
+	tasks, err := s.taskRepo.GetByName(OnboardingTaskStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch onboarding task: %w", err)
 	}
 
-	encodedTask, _ := json.Marshal(task)
-	s.redisHelper.Set(key, string(encodedTask), time.Until(*task.EndAt))
-
-	return task, nil
-}
+	for _, task := range tasks {
+		if !isSameCampaign(task.CampaignID, campaignID) {
+			continue
+		}
 
-func (s *CampaignService) startLimitedWeeklySettlementScheduler(tasks []*entities.Task) {
-	ticker := time.NewTicker(7 * 24 * time.Hour)
-	maxRuns := 4
-	runCount := 0
+		encodedTask, _ := json.Marshal(task)
+		s.redisHelper.Set(key, string(encodedTask), time.Until(*task.EndAt))
 
-	go func() {
-		for range ticker.C {
-			if runCount >= maxRuns {
-				s.logger.Info("Weekly settlement scheduler reached its limit, stopping...")
-				ticker.Stop()
-				return
-			}
+		return task, nil
+	}
 
-			if err := s.calculateSharePoolPoint(tasks[runCount]); err != nil {
-				s.logger.Error("Failed to perform weekly settlement: %v", err)
-			}
+	return nil, errNoOnboardingTask
+}
 
-			runCount++
+// scheduleSettlements stores a settlement job due at the end of each share pool period, so the
+// periods are settled even when the service restarts in between.
+func (s *CampaignService) scheduleSettlements(settlementJobRepo repositories.ISettlementJobRepository, tasks []*entities.Task) error {
+	for _, task := range tasks {
+		if err := settlementJobRepo.Create(&entities.SettlementJob{TaskID: task.ID, DueAt: *task.EndAt}); err != nil {
+			return err
 		}
-	}()
+	}
 
-	s.logger.Info("Limited weekly settlement scheduler started")
+	s.logger.Info(fmt.Sprintf("scheduled the settlement of %d share pool periods", len(tasks)))
+
+	return nil
 }
 
-func (s *CampaignService) calculateSharePoolPoint(task *entities.Task) error {
+// SettleSharePoolTask rewards the traders of an ended share pool period and marks its settlement job
+// settled, see calculateSharePoolPoint.
+func (s *CampaignService) SettleSharePoolTask(task *entities.Task, jobID int64, fencingToken int64) error {
+	return s.calculateSharePoolPoint(task, jobID, fencingToken)
+}
+
+// calculateSharePoolPoint splits the task points between the addresses in proportion to their swap
+// amounts. The rewards always add up to exactly task.Points, see decimal.Allocate for the rounding rule.
+// The rewards are stored and the settlement job claimed with fencingToken is marked settled in one
+// transaction, so a failed settlement leaves nothing behind and is retried whole. The job is locked
+// under the token first, a holder whose claim was taken over writes nothing. The ranking is written to
+// Redis only once the transaction has committed; the period is settled by then, so a failed ranking
+// write is logged rather than failing the settlement.
+func (s *CampaignService) calculateSharePoolPoint(task *entities.Task, jobID int64, fencingToken int64) error {
 	if task.Name != SharePoolTaskStr {
 		return fmt.Errorf("task is not shard pool task")
 	}
 
-	key := fmt.Sprintf("%s_%d", task.Name, task.Period)
-	totalKey := fmt.Sprintf("%s_total", key)
+	key := campaignKey(task.CampaignID, taskKey(task.Name, task.Period, task.ChainID))
 
-	totalStr, err := s.redisHelper.Get(totalKey)
-	if err != nil {
+	if err := s.convertLegacySwapAmounts(task, key, fmt.Sprintf("%s_total", key)); err != nil {
 		return err
 	}
 
-	totalAmount, err := strconv.ParseFloat(totalStr, 64)
-	if err != nil {
-		return fmt.Errorf("failed to parse total amount from key %s: %w", totalKey, err)
-	}
-
 	swapAmountMap, err := s.redisHelper.HGetAll(key)
 	if err != nil {
 		return err
 	}
 
-	taskPoints := task.Points
+	// addresses are sorted so that rounding ties are settled the same way on every run
+	addresses := []string{}
+	amounts := []decimal.Decimal{}
+	for address := range swapAmountMap {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
 
-	now := time.Now().UTC()
-	for address, v := range swapAmountMap {
-		amount, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return err
+	for _, address := range addresses {
+		units, ok := new(big.Int).SetString(swapAmountMap[address], 10)
+		if !ok {
+			return fmt.Errorf("failed to parse amount of %s from key %s", address, key)
 		}
 
-		quotes := amount / totalAmount
-		rewards := taskPoints * quotes
-
-		history := &entities.TaskHistory{
-			Address:      address,
-			TaskID:       task.ID,
-			RewardPoints: rewards,
-			Amount:       amount,
-			CompletedAt:  &now,
-			CreatedAt:    now,
-			UpdatedAt:    now,
+		amounts = append(amounts, decimal.New(units))
+	}
+
+	rewards := decimal.Allocate(task.Points, amounts)
+
+	var ranks []*redis.Z
+	err = s.transactor.InTransaction(func(tx *sql.Tx) error {
+		taskHistoryRepo := s.taskHistoryRepo.WithTx(tx)
+		settlementJobRepo := s.settlementJobRepo.WithTx(tx)
+
+		if err := settlementJobRepo.LockClaim(jobID, fencingToken); err != nil {
+			return err
 		}
 
-		if _, err := s.taskHistoryRepo.Create(history); err != nil {
-			s.logger.Error("create history failed for address %s, %v", address, err)
-			continue
+		now := time.Now().UTC()
+		ranks = []*redis.Z{}
+		for i, address := range addresses {
+			// addresses whose swaps were all reverted take no part
+			if amounts[i].Sign() <= 0 {
+				continue
+			}
+
+			history := &entities.TaskHistory{
+				Address:      address,
+				TaskID:       task.ID,
+				RewardPoints: rewards[i],
+				Amount:       amounts[i],
+				CompletedAt:  &now,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+
+			if _, err := taskHistoryRepo.Create(history); err != nil {
+				return fmt.Errorf("failed to reward %s: %w", address, err)
+			}
+
+			// sorted set scores are floats, they only order the leaderboard
+			ranks = append(ranks, &redis.Z{Score: rewards[i].Float64(), Member: address})
 		}
 
-		s.redisHelper.ZAdd(fmt.Sprintf("%s_rank", key), &redis.Z{Score: rewards, Member: address})
+		return settlementJobRepo.MarkSettled(jobID, fencingToken)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to settle period %d of task %d: %w", task.Period, task.ID, err)
+	}
+
+	if len(ranks) > 0 {
+		if err := s.redisHelper.ZAdd(fmt.Sprintf("%s_rank", key), ranks...); err != nil {
+			s.logger.Error(fmt.Errorf("failed to rank settled period %d of task %d: %w", task.Period, task.ID, err))
+		}
 	}
 
 	return nil
 }
 
-func (s *CampaignService) GetLeaderboard(taskName string, period int) ([]models.LeaderboardEntry, error) {
-	key := fmt.Sprintf("%s_%d_rank", taskName, period)
+// GetLeaderboard returns the settled ranking of a task period of a campaign. A campaignID of 0 selects
+// the latest campaign, a chainID of 0 selects the task that adds up every chain.
+func (s *CampaignService) GetLeaderboard(campaignID int64, taskName string, period int, chainID int64) ([]models.LeaderboardEntry, error) {
+	var taskChainID *int64
+	if chainID != 0 {
+		taskChainID = &chainID
+	}
+
+	taskCampaignID, err := s.findCampaignID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s_rank", campaignKey(taskCampaignID, taskKey(taskName, period, taskChainID)))
 
 	members, scores, err := s.redisHelper.ZRevRangeWithScores(key, 0, -1)
 	if err != nil {
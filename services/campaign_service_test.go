@@ -1,14 +1,23 @@
 package services
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 	"trading-ace/config"
+	"trading-ace/decimal"
 	"trading-ace/entities"
+	"trading-ace/helpers"
 	"trading-ace/mocks"
 	"trading-ace/models"
+	"trading-ace/repositories"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -27,8 +36,8 @@ func TestGetPointHistories(t *testing.T) {
 			TaskHistory: &entities.TaskHistory{
 				ID:           1,
 				Address:      "address1",
-				RewardPoints: 100,
-				Amount:       10.0,
+				RewardPoints: decimal.NewFromInt(100),
+				Amount:       decimal.NewFromInt(10),
 				CompletedAt:  &now,
 				CreatedAt:    now,
 				UpdatedAt:    now,
@@ -37,16 +46,16 @@ func TestGetPointHistories(t *testing.T) {
 				ID:          1,
 				Name:        "Onboarding",
 				Description: "Onboarding task",
-				Points:      10,
+				Points:      decimal.NewFromInt(10),
 			},
 		},
 	}
 
 	// 設置 mock 返回值
-	taskHistoryRepoMock.On("GetByAddressIncludingTasks", "address1").Return(taskHistoryMock, nil)
+	taskHistoryRepoMock.On("GetByAddressIncludingTasks", "address1", int64(0)).Return(taskHistoryMock, nil)
 
-	svc := NewCampaignService(cfg, loggerMock, taskHistoryRepoMock, taskRepoMock, redisHelperMock)
-	result, err := svc.GetPointHistories("address1")
+	svc := NewCampaignService(cfg, loggerMock, taskHistoryRepoMock, taskRepoMock, redisHelperMock, nil, nil, nil, nil, nil)
+	result, err := svc.GetPointHistories("address1", 0)
 
 	// 驗證結果
 	assert.NoError(t, err)                   // 確保沒有錯誤
@@ -71,16 +80,16 @@ func TestGetTaskStatus(t *testing.T) {
 			TaskID:          1,
 			TaskName:        OnboardingTaskStr,
 			TaskDescription: "Onboarding task",
-			TaskPoints:      10,
+			TaskPoints:      decimal.NewFromInt(10),
 		},
 	}
 
 	// 設置 mock 返回值
-	taskRepoMock.On("GetByAddressAndNamesIncludingTaskHistories", "address1", []string{OnboardingTaskStr, SharePoolTaskStr}).
+	taskRepoMock.On("GetByAddressAndNamesIncludingTaskHistories", "address1", int64(2), []string{OnboardingTaskStr, SharePoolTaskStr}).
 		Return(taskWithHistoryMock, nil)
 
-	svc := NewCampaignService(cfg, loggerMock, taskHistoryRepoMock, taskRepoMock, redisHelperMock)
-	result, err := svc.GetTaskStatus("address1")
+	svc := NewCampaignService(cfg, loggerMock, taskHistoryRepoMock, taskRepoMock, redisHelperMock, nil, nil, nil, nil, nil)
+	result, err := svc.GetTaskStatus("address1", 2)
 
 	// 驗證結果
 	assert.NoError(t, err)                       // 確保沒有錯誤
@@ -97,38 +106,244 @@ func TestStartCampaign(t *testing.T) {
 	taskHistoryRepoMock := &mocks.MockTaskHistoryRepository{}
 	taskRepoMock := &mocks.MockTaskRepository{}
 	redisHelperMock := &mocks.MockRedisHelper{}
+	campaignRepoMock := &mocks.MockCampaignRepository{}
+	settlementJobRepoMock := &mocks.MockSettlementJobRepository{}
+	transactorMock := &mocks.MockTransactor{}
 
 	// 模擬 taskRepo 的行為
-	taskRepoMock.On("IsExistedByName", OnboardingTaskStr).Return(false, nil)
-	taskRepoMock.On("Create", mock.Anything).Return(&entities.Task{}, nil)
-
-	// 模擬 share pool task 的行為
-	taskRepoMock.On("IsExistedByName", SharePoolTaskStr).Return(false, nil)
-	taskRepoMock.On("Create", mock.Anything).Return(&entities.Task{}, nil)
+	endAt := time.Now().Add(7 * 24 * time.Hour)
+	campaignRepoMock.On("List").Return([]*entities.Campaign{}, nil)
+	taskRepoMock.On("Create", mock.Anything).Return(&entities.Task{ID: 2, EndAt: &endAt}, nil)
+	settlementJobRepoMock.On("Create", &entities.SettlementJob{TaskID: 2, DueAt: endAt}).Return(nil)
+	campaignRepoMock.On("Create", mock.Anything).Return(&entities.Campaign{ID: 1}, nil)
+	redisHelperMock.On("Delete", mock.Anything).Return(nil)
+	transactorMock.On("InTransaction").Return(nil)
 
 	// 模擬 logger 的行為
 	loggerMock.On("Info", mock.Anything).Return()
 
 	// 呼叫 StartCampaign 方法
-	svc := NewCampaignService(cfg, loggerMock, taskHistoryRepoMock, taskRepoMock, redisHelperMock)
+	svc := NewCampaignService(cfg, loggerMock, taskHistoryRepoMock, taskRepoMock, redisHelperMock, nil, campaignRepoMock, settlementJobRepoMock, transactorMock, helpers.NewRedisLocker(newMemoryRedisHelper()))
 	err := svc.StartCampaign()
 
 	// 驗證結果
 	assert.NoError(t, err) // 確保沒有錯誤
 
-	// 驗證 createOnboardingTask 是否被調用
-	taskRepoMock.AssertCalled(t, "IsExistedByName", OnboardingTaskStr)
-	taskRepoMock.AssertCalled(t, "Create", mock.Anything)
+	// 驗證 campaign 與 tasks 是否被建立
+	campaignRepoMock.AssertCalled(t, "Create", mock.Anything)
+	taskRepoMock.AssertNumberOfCalls(t, "Create", 5)
 
-	// 驗證 createSharePoolTask 是否被調用
-	taskRepoMock.AssertCalled(t, "IsExistedByName", SharePoolTaskStr)
-	taskRepoMock.AssertCalled(t, "Create", mock.Anything)
+	// 驗證每個 share pool 週期都排入結算
+	settlementJobRepoMock.AssertNumberOfCalls(t, "Create", 4)
+	transactorMock.AssertNumberOfCalls(t, "InTransaction", 1)
 
 	// 驗證 logger 是否有記錄啟動計劃
 	loggerMock.AssertCalled(t, "Info", mock.Anything)
 }
 
-func TestFindCurrentSharePoolTask(t *testing.T) {
+func TestStartCampaignSharePoolPerChain(t *testing.T) {
+	cfg := &config.Config{
+		Chains: []config.ChainConfig{
+			{ID: 1, Name: "ethereum"},
+			{ID: 42161, Name: "arbitrum"},
+		},
+		Campaign: config.CampaignConfig{Definition: config.CampaignDefinition{
+			Name: "per-chain",
+			Tasks: []config.CampaignTaskDefinition{
+				{Name: OnboardingTaskStr, Points: "100", TargetAmount: "1000", PeriodLength: 28 * 24 * time.Hour},
+				{Name: SharePoolTaskStr, Points: "10000", Periods: 4, PeriodLength: 7 * 24 * time.Hour, PerChain: true},
+			},
+		}},
+	}
+	loggerMock := &mocks.MockLogger{}
+	taskRepoMock := &mocks.MockTaskRepository{}
+	redisHelperMock := &mocks.MockRedisHelper{}
+	campaignRepoMock := &mocks.MockCampaignRepository{}
+	settlementJobRepoMock := &mocks.MockSettlementJobRepository{}
+
+	campaignRepoMock.On("List").Return([]*entities.Campaign{}, nil)
+	campaignRepoMock.On("Create", mock.Anything).Return(&entities.Campaign{ID: 1}, nil)
+	redisHelperMock.On("Delete", mock.Anything).Return(nil)
+	settlementJobRepoMock.On("Create", mock.Anything).Return(nil)
+	loggerMock.On("Info", mock.Anything).Return()
+	transactorMock := &mocks.MockTransactor{}
+	transactorMock.On("InTransaction").Return(nil)
+
+	endAt := time.Now().Add(7 * 24 * time.Hour)
+	created := map[string]int{}
+	taskRepoMock.On("Create", mock.Anything).Return(&entities.Task{EndAt: &endAt}, nil).Run(func(args mock.Arguments) {
+		task := args.Get(0).(*entities.Task)
+		created[taskKey(task.Name, task.Period, task.ChainID)]++
+	})
+
+	svc := NewCampaignService(cfg, loggerMock, &mocks.MockTaskHistoryRepository{}, taskRepoMock, redisHelperMock, nil, campaignRepoMock, settlementJobRepoMock, transactorMock, helpers.NewRedisLocker(newMemoryRedisHelper()))
+	assert.NoError(t, svc.StartCampaign())
+
+	// the onboarding task and four periods per chain
+	assert.Len(t, created, 9)
+	assert.Equal(t, 1, created["OnboardingTask_1"])
+	for period := 1; period <= 4; period++ {
+		assert.Equal(t, 1, created[fmt.Sprintf("SharePoolTask_%d_chain_1", period)])
+		assert.Equal(t, 1, created[fmt.Sprintf("SharePoolTask_%d_chain_42161", period)])
+	}
+
+	// every chain's periods are settled
+	settlementJobRepoMock.AssertNumberOfCalls(t, "Create", 8)
+}
+
+func TestCreateCampaign(t *testing.T) {
+	loggerMock := &mocks.MockLogger{}
+	taskRepoMock := &mocks.MockTaskRepository{}
+	redisHelperMock := &mocks.MockRedisHelper{}
+	campaignRepoMock := &mocks.MockCampaignRepository{}
+	settlementJobRepoMock := &mocks.MockSettlementJobRepository{}
+
+	transactorMock := &mocks.MockTransactor{}
+
+	loggerMock.On("Info", mock.Anything).Return()
+	transactorMock.On("InTransaction").Return(nil)
+
+	cfg := &config.Config{Pools: []config.PoolConfig{{Address: testPoolAddress}}}
+	locker := helpers.NewRedisLocker(newMemoryRedisHelper())
+	svc := NewCampaignService(cfg, loggerMock, &mocks.MockTaskHistoryRepository{}, taskRepoMock, redisHelperMock, nil, campaignRepoMock, settlementJobRepoMock, transactorMock, locker)
+
+	definition := config.CampaignDefinition{
+		Name: "second",
+		Tasks: []config.CampaignTaskDefinition{
+			{Name: OnboardingTaskStr, Points: "50", TargetAmount: "500", Periods: 1, PeriodLength: 48 * time.Hour},
+			{Name: SharePoolTaskStr, Points: "2000", Periods: 2, PeriodLength: 24 * time.Hour},
+		},
+	}
+
+	t.Run("Rejected while a campaign of the same name is running", func(t *testing.T) {
+		campaignRepoMock.On("List").Return([]*entities.Campaign{{ID: 1, Name: "second", EndAt: time.Now().Add(time.Hour)}}, nil).Once()
+
+		_, err := svc.CreateCampaign(definition)
+
+		assert.ErrorContains(t, err, "campaign second is running")
+		campaignRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Rejects pools that are not tracked", func(t *testing.T) {
+		untracked := definition
+		untracked.Pools = []config.CampaignPoolDefinition{{ChainID: 42161, Address: testPoolAddress}}
+
+		_, err := svc.CreateCampaign(untracked)
+
+		assert.ErrorContains(t, err, "is not tracked on chain 42161")
+		campaignRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Rejected while another instance creates a campaign", func(t *testing.T) {
+		lease, err := locker.Acquire(campaignLockName, campaignLockTTL)
+		assert.NoError(t, err)
+
+		_, err = svc.CreateCampaign(definition)
+
+		assert.ErrorIs(t, err, helpers.ErrLockHeld)
+		campaignRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+		assert.NoError(t, lease.Release())
+	})
+
+	t.Run("Tasks follow the definition", func(t *testing.T) {
+		// campaigns of other names run side by side
+		campaignRepoMock.On("List").Return([]*entities.Campaign{
+			{ID: 1, Name: "first", EndAt: time.Now().Add(time.Hour)},
+			{ID: 0, Name: "second", EndAt: time.Now().Add(-time.Hour)},
+		}, nil).Once()
+
+		campaign := &entities.Campaign{ID: 2}
+		campaignRepoMock.On("Create", mock.Anything).Return(campaign, nil).Run(func(args mock.Arguments) {
+			created := args.Get(0).(*entities.Campaign)
+			campaign.Name, campaign.StartedAt, campaign.EndAt, campaign.Pools = created.Name, created.StartedAt, created.EndAt, created.Pools
+		})
+
+		endAt := time.Now().Add(24 * time.Hour)
+		tasks := []*entities.Task{}
+		taskRepoMock.On("Create", mock.Anything).Return(&entities.Task{ID: 9, EndAt: &endAt}, nil).Run(func(args mock.Arguments) {
+			tasks = append(tasks, args.Get(0).(*entities.Task))
+		})
+
+		// each share pool period is settled once it ends
+		settlementJobRepoMock.On("Create", &entities.SettlementJob{TaskID: 9, DueAt: endAt}).Return(nil).Times(2)
+
+		// the cached lists lack the new campaign
+		redisHelperMock.On("Delete", sharePoolTasksKey).Return(nil).Once()
+		redisHelperMock.On("Delete", campaignsKey).Return(nil).Once()
+
+		// pools are kept under their checksum address, on mainnet unless given a chain
+		pooled := definition
+		pooled.Pools = []config.CampaignPoolDefinition{{Address: strings.ToLower(testPoolAddress)}}
+
+		_, err := svc.CreateCampaign(pooled)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []*entities.CampaignPool{{ChainID: config.MainnetChainID, PoolAddress: common.HexToAddress(testPoolAddress).Hex()}}, campaign.Pools)
+
+		// the campaign ends with its longest task
+		assert.Equal(t, "second", campaign.Name)
+		assert.Equal(t, 48*time.Hour, campaign.EndAt.Sub(campaign.StartedAt))
+
+		assert.Len(t, tasks, 3)
+		for _, task := range tasks {
+			assert.Equal(t, int64(2), *task.CampaignID)
+		}
+
+		assert.Equal(t, OnboardingTaskStr, tasks[0].Name)
+		assert.Equal(t, "500", tasks[0].TargetAmount.String())
+		assert.Equal(t, "50", tasks[0].Points.String())
+
+		assert.Equal(t, 2, tasks[2].Period)
+		assert.Equal(t, "2000", tasks[2].Points.String())
+		assert.Equal(t, *tasks[1].EndAt, *tasks[2].StartedAt)
+		assert.Equal(t, 24*time.Hour, tasks[2].EndAt.Sub(*tasks[2].StartedAt))
+		redisHelperMock.AssertExpectations(t)
+		settlementJobRepoMock.AssertExpectations(t)
+	})
+}
+
+func TestCreateCampaignInOneTransaction(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	loggerMock := &mocks.MockLogger{}
+	redisHelperMock := &mocks.MockRedisHelper{}
+	loggerMock.On("Info", mock.Anything).Return()
+
+	svc := NewCampaignService(&config.Config{}, loggerMock, nil, repositories.NewTaskRepository(db), redisHelperMock, nil,
+		repositories.NewCampaignRepository(db), repositories.NewSettlementJobRepository(db), repositories.NewTransactor(db), helpers.NewRedisLocker(newMemoryRedisHelper()))
+
+	definition := config.CampaignDefinition{
+		Name:  "single",
+		Tasks: []config.CampaignTaskDefinition{{Name: SharePoolTaskStr, Points: "2000", PeriodLength: 24 * time.Hour}},
+	}
+
+	now := time.Now()
+	endAt := now.Add(24 * time.Hour)
+
+	sqlMock.ExpectQuery(`SELECT (.+) FROM campaigns`).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "end_at", "created_at"}))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`INSERT INTO campaigns`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "started_at", "end_at", "created_at"}).AddRow(3, "single", now, endAt, now))
+	sqlMock.ExpectQuery(`INSERT INTO tasks`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "name", "description", "points", "target_amount", "started_at", "end_at", "period", "chain_id", "created_at", "updated_at"}).
+			AddRow(8, 3, SharePoolTaskStr, SharePoolTaskStr, "2000", "0", now, endAt, 1, nil, now, now))
+
+	// the period cannot be scheduled, so the campaign and its task are not kept either
+	sqlMock.ExpectExec(`INSERT INTO settlement_jobs`).WithArgs(int64(8), endAt).WillReturnError(sql.ErrConnDone)
+	sqlMock.ExpectRollback()
+
+	_, err = svc.CreateCampaign(definition)
+
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	redisHelperMock.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestFindOnboardingTask(t *testing.T) {
 	// 設置模擬的 RedisHelper 和 TaskRepo
 	mockRedisHelper := new(mocks.MockRedisHelper)
 	mockTaskRepo := new(mocks.MockTaskRepository)
@@ -140,36 +355,47 @@ func TestFindCurrentSharePoolTask(t *testing.T) {
 	}
 
 	// 測試場景：Redis 已經有資料
-	mockRedisHelper.On("Get", "curr_shared_pool_task").Return(`{"id":1,"name":"share_pool","started_at":"2024-11-01T00:00:00Z","end_at":"2024-11-30T00:00:00Z"}`, nil)
+	mockRedisHelper.On("Get", "onboarding_task").Return(`{"id":1,"name":"onboarding","started_at":"2024-11-01T00:00:00Z","end_at":"2024-11-30T00:00:00Z"}`, nil)
 
-	task, err := service.FindCurrentSharePoolTask()
+	task, err := service.FindOnboardingTask(nil)
 
 	assert.NoError(t, err)
-	assert.Equal(t, "share_pool", task.Name)
+	assert.Equal(t, "onboarding", task.Name)
 	mockRedisHelper.AssertExpectations(t)
 	mockTaskRepo.AssertExpectations(t)
 }
 
-func TestFindOnboardingTask(t *testing.T) {
-	// 設置模擬的 RedisHelper 和 TaskRepo
+func TestFindOnboardingTaskOfCampaign(t *testing.T) {
 	mockRedisHelper := new(mocks.MockRedisHelper)
 	mockTaskRepo := new(mocks.MockTaskRepository)
 
-	// 設置 CampaignService
 	service := &CampaignService{
 		redisHelper: mockRedisHelper,
 		taskRepo:    mockTaskRepo,
 	}
 
-	// 測試場景：Redis 已經有資料
-	mockRedisHelper.On("Get", "onboarding_task").Return(`{"id":1,"name":"onboarding","started_at":"2024-11-01T00:00:00Z","end_at":"2024-11-30T00:00:00Z"}`, nil)
+	first, second := int64(1), int64(2)
+	endAt := time.Now().Add(time.Hour)
+	mockTaskRepo.On("GetByName", OnboardingTaskStr).Return([]*entities.Task{
+		{ID: 10, CampaignID: &first, Name: OnboardingTaskStr, EndAt: &endAt},
+		{ID: 20, CampaignID: &second, Name: OnboardingTaskStr, EndAt: &endAt},
+	}, nil)
 
-	task, err := service.FindOnboardingTask()
+	// each campaign caches its own onboarding task
+	mockRedisHelper.On("Get", "campaign_2_onboarding_task").Return("", errors.New("redis: nil"))
+	mockRedisHelper.On("Set", "campaign_2_onboarding_task", mock.Anything, mock.Anything).Return(nil)
 
+	task, err := service.FindOnboardingTask(&second)
 	assert.NoError(t, err)
-	assert.Equal(t, "onboarding", task.Name)
+	assert.Equal(t, int64(20), task.ID)
+
+	// a campaign declared without an onboarding task
+	third := int64(3)
+	mockRedisHelper.On("Get", "campaign_3_onboarding_task").Return("", errors.New("redis: nil"))
+
+	_, err = service.FindOnboardingTask(&third)
+	assert.ErrorIs(t, err, errNoOnboardingTask)
 	mockRedisHelper.AssertExpectations(t)
-	mockTaskRepo.AssertExpectations(t)
 }
 
 func TestRecordUSDCSwapTotalAmount(t *testing.T) {
@@ -186,56 +412,408 @@ func TestRecordUSDCSwapTotalAmount(t *testing.T) {
 
 	// Mock data
 	senderAddress := "0x123"
-	amount := 100.0
-	currentTask := &entities.Task{
-		Name:   "share_pool_task",
-		Period: 1,
-	}
+	amount := decimal.NewFromInt(1000)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	sharePoolTasks := newSharePoolTasks(start)
+	onboardingEnd := start.Add(7 * 24 * time.Hour)
 	onboardingTask := &entities.Task{
-		ID:     1,
-		Name:   "onboarding_task",
-		Period: 1,
+		ID:           1,
+		Name:         OnboardingTaskStr,
+		Points:       OnboardingTaskPoints,
+		TargetAmount: OnboardingTaskTargetAmount,
+		StartedAt:    &start,
+		EndAt:        &onboardingEnd,
+		Period:       1,
 	}
-	totalAmountStr := "100.0"
-
-	// Mock Redis responses
-	mockRedisHelper.On("HIncrFloat", mock.Anything, senderAddress, amount).Return(nil)
-	mockRedisHelper.On("HGet", mock.Anything, senderAddress).Return(totalAmountStr, nil)
-	mockRedisHelper.On("IncrFloat", mock.Anything, amount).Return(nil)
-	mockRedisHelper.On("Get", mock.Anything).Return(totalAmountStr, nil)
 
-	// Mock FindCurrentSharePoolTask response
-	mockTaskRepo.On("GetByName", "share_pool_task").Return([]*entities.Task{currentTask}, nil)
-	mockTaskRepo.On("FindByName", "onboarding_task").Return(onboardingTask, nil)
+	// Mock the onboarding task lookup, it is not cached yet
+	mockRedisHelper.On("Get", "onboarding_task").Return("", errors.New("redis: nil"))
+	mockRedisHelper.On("Set", "onboarding_task", mock.Anything, mock.Anything).Return(nil)
+	mockTaskRepo.On("GetByName", OnboardingTaskStr).Return([]*entities.Task{onboardingTask}, nil)
 
-	// Mock task history repo to simulate no existing onboarding task history
-	mockTaskHistoryRepo.On("FindByAddressAndTaskId", senderAddress, onboardingTask.ID).Return(nil, errors.New("not found"))
+	// the amounts of these legacy tasks are already in units
+	mockRedisHelper.On("ScaleToIntegers", mock.Anything, mock.Anything, mock.Anything, int64(1000000)).Return(false, nil)
 
-	// Simulate successful creation of task history
-	mockTaskHistoryRepo.On("Create", mock.Anything).Return(nil)
+	// a swap in the first week completes onboarding
+	mockRedisHelper.On("HIncrByWithTotal", "SharePoolTask_1", senderAddress, "SharePoolTask_1_total", int64(1000000000)).Return(int64(1000000000), nil)
+	mockTaskHistoryRepo.On("FindByAddressAndTaskId", senderAddress, onboardingTask.ID).Return((*entities.TaskHistory)(nil), errors.New("not found"))
+	mockTaskHistoryRepo.On("Create", mock.Anything).Return(&entities.TaskHistory{}, nil).Once()
 
-	// Call the method under test
-	totalAmountReturned, err := campaignService.RecordUSDCSwapTotalAmount(senderAddress, amount)
+	totalAmountReturned, err := campaignService.RecordUSDCSwapTotalAmount(sharePoolTasks[0], senderAddress, amount, start.Add(24*time.Hour))
 
-	// Assert the results
 	assert.NoError(t, err)
 	assert.Equal(t, amount, totalAmountReturned)
 
+	// a swap after the onboarding window still counts for the share pool
+	mockRedisHelper.On("HIncrByWithTotal", "SharePoolTask_2", senderAddress, "SharePoolTask_2_total", int64(1000000000)).Return(int64(1000000000), nil)
+
+	_, err = campaignService.RecordUSDCSwapTotalAmount(sharePoolTasks[1], senderAddress, amount, onboardingEnd)
+
+	assert.NoError(t, err)
+
+	// a failed volume update leaves the swap unrecorded
+	mockRedisHelper.On("HIncrByWithTotal", "SharePoolTask_3", senderAddress, "SharePoolTask_3_total", int64(1000000000)).Return(int64(0), errors.New("redis: connection refused"))
+
+	_, err = campaignService.RecordUSDCSwapTotalAmount(sharePoolTasks[2], senderAddress, amount, start.Add(15*24*time.Hour))
+	assert.ErrorIs(t, err, errSwapNotRecorded)
+
 	// Assert that the Redis helper and task history repo methods were called
 	mockRedisHelper.AssertExpectations(t)
+	mockTaskHistoryRepo.AssertExpectations(t)
+}
+
+func TestRecordUSDCSwapTotalAmountOfCampaign(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTaskHistoryRepo := new(mocks.MockTaskHistoryRepository)
+
+	campaignService := &CampaignService{redisHelper: mockRedisHelper, taskHistoryRepo: mockTaskHistoryRepo}
+
+	campaignID := int64(3)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	task := newSharePoolTasks(start)[0]
+	task.CampaignID = &campaignID
+
+	// the volume and the onboarding target are those of the campaign
+	mockRedisHelper.On("HIncrByWithTotal", "campaign_3_SharePoolTask_1", "0x123", "campaign_3_SharePoolTask_1_total", int64(600000000)).Return(int64(600000000), nil)
+	mockRedisHelper.On("Get", "campaign_3_onboarding_task").Return(`{"id":30,"name":"OnboardingTask","period":1,"Points":50,"TargetAmount":500,"StartedAt":"2024-11-01T00:00:00Z","EndAt":"2024-11-15T00:00:00Z"}`, nil)
+	mockTaskHistoryRepo.On("FindByAddressAndTaskId", "0x123", int64(30)).Return((*entities.TaskHistory)(nil), errors.New("not found"))
+	mockTaskHistoryRepo.On("Create", mock.MatchedBy(func(history *entities.TaskHistory) bool {
+		return history.TaskID == 30 && history.RewardPoints.String() == "50"
+	})).Return(&entities.TaskHistory{}, nil).Once()
+
+	totalAmount, err := campaignService.RecordUSDCSwapTotalAmount(task, "0x123", decimal.NewFromInt(600), start)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "600", totalAmount.String())
+	mockRedisHelper.AssertExpectations(t)
+	mockTaskHistoryRepo.AssertExpectations(t)
+}
+
+func TestRecordUSDCSwapTotalAmountRevertsFailedOnboarding(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTaskHistoryRepo := new(mocks.MockTaskHistoryRepository)
+
+	campaignService := &CampaignService{logger: mockLogger, redisHelper: mockRedisHelper, taskHistoryRepo: mockTaskHistoryRepo}
+
+	campaignID := int64(3)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	task := newSharePoolTasks(start)[0]
+	task.CampaignID = &campaignID
+
+	mockRedisHelper.On("HIncrByWithTotal", "campaign_3_SharePoolTask_1", "0x123", "campaign_3_SharePoolTask_1_total", int64(600000000)).Return(int64(600000000), nil)
+	mockRedisHelper.On("Get", "campaign_3_onboarding_task").Return(`{"id":30,"name":"OnboardingTask","period":1,"Points":50,"TargetAmount":500,"StartedAt":"2024-11-01T00:00:00Z","EndAt":"2024-11-15T00:00:00Z"}`, nil)
+	mockTaskHistoryRepo.On("FindByAddressAndTaskId", "0x123", int64(30)).Return((*entities.TaskHistory)(nil), errors.New("not found"))
+	mockTaskHistoryRepo.On("Create", mock.Anything).Return((*entities.TaskHistory)(nil), errors.New("connection refused")).Once()
+
+	// the onboarding could not be recorded, the volume is taken back so a retry records the swap whole
+	mockRedisHelper.On("HIncrByWithTotal", "campaign_3_SharePoolTask_1", "0x123", "campaign_3_SharePoolTask_1_total", int64(-600000000)).Return(int64(0), nil).Once()
+
+	_, err := campaignService.RecordUSDCSwapTotalAmount(task, "0x123", decimal.NewFromInt(600), start)
+
+	assert.ErrorIs(t, err, errSwapNotRecorded)
+	mockRedisHelper.AssertExpectations(t)
+	mockTaskHistoryRepo.AssertExpectations(t)
+}
+
+func TestRecordUSDCSwapTotalAmountPerChain(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+
+	campaignService := &CampaignService{redisHelper: mockRedisHelper}
+
+	chainID := int64(42161)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	task := newSharePoolTasks(start)[0]
+	task.ChainID = &chainID
+
+	mockRedisHelper.On("Get", "onboarding_task").Return(`{"id":1,"name":"OnboardingTask","period":1,"TargetAmount":1000}`, nil)
+
+	mockRedisHelper.On("ScaleToIntegers", mock.Anything, mock.Anything, mock.Anything, int64(1000000)).Return(false, nil)
+
+	// the amount is kept under the chain of the task, below the onboarding target
+	mockRedisHelper.On("HIncrByWithTotal", "SharePoolTask_1_chain_42161", "0x123", "SharePoolTask_1_chain_42161_total", int64(250000000)).Return(int64(250000000), nil)
+
+	totalAmount, err := campaignService.RecordUSDCSwapTotalAmount(task, "0x123", decimal.NewFromInt(250), start)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "250", totalAmount.String())
+	mockRedisHelper.AssertExpectations(t)
+}
+
+func TestFindSharePoolTasksAt(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTaskRepo := new(mocks.MockTaskRepository)
+
+	service := &CampaignService{
+		redisHelper: mockRedisHelper,
+		taskRepo:    mockTaskRepo,
+	}
+
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	// the share pool tasks are not cached yet
+	mockRedisHelper.On("Get", "share_pool_tasks").Return("", errors.New("redis: nil")).Once()
+	mockRedisHelper.On("Set", "share_pool_tasks", mock.Anything, sharePoolTasksCacheTTL).Return(nil).Once()
+	mockTaskRepo.On("GetByName", SharePoolTaskStr).Return(newSharePoolTasks(start), nil).Once()
+
+	tasks, err := service.FindSharePoolTasksAt(config.MainnetChainID, testPoolAddress, start)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, 1, tasks[0].Period)
+
+	// the cached tasks are used without hitting the database
+	encodedTasks, err := json.Marshal(newSharePoolTasks(start))
+	assert.NoError(t, err)
+	mockRedisHelper.On("Get", "share_pool_tasks").Return(string(encodedTasks), nil)
+
+	// periods include their start and exclude their end
+	tasks, err = service.FindSharePoolTasksAt(config.MainnetChainID, testPoolAddress, start.Add(7*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tasks[0].Period)
+
+	tasks, err = service.FindSharePoolTasksAt(config.MainnetChainID, testPoolAddress, start.Add(28*24*time.Hour-time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, tasks[0].Period)
+
+	// swaps outside every period are rejected
+	_, err = service.FindSharePoolTasksAt(config.MainnetChainID, testPoolAddress, start.Add(-time.Second))
+	assert.ErrorIs(t, err, errSwapOutsideCampaign)
+
+	_, err = service.FindSharePoolTasksAt(config.MainnetChainID, testPoolAddress, start.Add(28*24*time.Hour))
+	assert.ErrorIs(t, err, errSwapOutsideCampaign)
+
+	mockTaskRepo.AssertExpectations(t)
+}
+
+func TestFindSharePoolTasksAtPerChain(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+
+	service := &CampaignService{redisHelper: mockRedisHelper}
+
+	// each chain runs its own share pool
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	tasks := []*entities.Task{}
+	for _, chainID := range []int64{1, 42161} {
+		for _, task := range newSharePoolTasks(start) {
+			task.ID = chainID*10 + int64(task.Period)
+			task.ChainID = &chainID
+			tasks = append(tasks, task)
+		}
+	}
+
+	encodedTasks, err := json.Marshal(tasks)
+	assert.NoError(t, err)
+	mockRedisHelper.On("Get", "share_pool_tasks").Return(string(encodedTasks), nil)
+
+	// a swap only counts towards the pool of its own chain
+	found, err := service.FindSharePoolTasksAt(42161, testPoolAddress, start.Add(8*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, int64(421612), found[0].ID)
+	assert.Equal(t, "SharePoolTask_2_chain_42161", taskKey(found[0].Name, found[0].Period, found[0].ChainID))
+
+	found, err = service.FindSharePoolTasksAt(1, testPoolAddress, start.Add(8*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), found[0].ID)
+
+	// chains without a pool of their own are outside the campaign
+	_, err = service.FindSharePoolTasksAt(8453, testPoolAddress, start.Add(8*24*time.Hour))
+	assert.ErrorIs(t, err, errSwapOutsideCampaign)
+}
+
+func TestFindSharePoolTasksAtConcurrentCampaigns(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockCampaignRepo := new(mocks.MockCampaignRepository)
+
+	service := &CampaignService{redisHelper: mockRedisHelper, campaignRepo: mockCampaignRepo}
+
+	// campaign 1 counts every pool for four weeks, campaign 2 a single pool for a week from the second
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	first, second := int64(1), int64(2)
+	tasks := newSharePoolTasks(start)
+	for _, task := range tasks {
+		task.CampaignID = &first
+	}
+
+	secondStart := start.Add(7 * 24 * time.Hour)
+	secondTask := newSharePoolTasks(secondStart)[0]
+	secondTask.ID = 20
+	secondTask.CampaignID = &second
+	tasks = append(tasks, secondTask)
+
+	encodedTasks, err := json.Marshal(tasks)
+	assert.NoError(t, err)
+	mockRedisHelper.On("Get", "share_pool_tasks").Return(string(encodedTasks), nil)
+
+	// the campaigns are not cached yet
+	otherPool := "0x397FF1542f962076d0BFE58eA045FfA2d347ACa0"
+	mockRedisHelper.On("Get", "campaigns").Return("", errors.New("redis: nil"))
+	mockRedisHelper.On("Set", "campaigns", mock.Anything, sharePoolTasksCacheTTL).Return(nil)
+	mockCampaignRepo.On("List").Return([]*entities.Campaign{
+		{ID: 2, Pools: []*entities.CampaignPool{{CampaignID: 2, ChainID: config.MainnetChainID, PoolAddress: otherPool}}},
+		{ID: 1},
+	}, nil)
+
+	// a swap in the pool of campaign 2 counts towards both campaigns
+	found, err := service.FindSharePoolTasksAt(config.MainnetChainID, otherPool, secondStart.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, int64(3), found[0].ID)
+	assert.Equal(t, int64(20), found[1].ID)
+
+	// other pools, and the pool on other chains, only count towards campaign 1
+	found, err = service.FindSharePoolTasksAt(config.MainnetChainID, testPoolAddress, secondStart.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, int64(3), found[0].ID)
+
+	found, err = service.FindSharePoolTasksAt(42161, otherPool, secondStart.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+}
+
+func TestRevertUSDCSwapTotalAmount(t *testing.T) {
+	senderAddress := "0x123"
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	tasks := newSharePoolTasks(start)
+	completedAt := start.Add(4 * 24 * time.Hour)
+
+	// the onboarding window covers the first two periods
+	onboarding := `{"id":1,"name":"OnboardingTask","period":1,"TargetAmount":1000,"StartedAt":"2024-11-01T00:00:00Z","EndAt":"2024-11-15T00:00:00Z"}`
+
+	tests := []struct {
+		name       string
+		task       *entities.Task
+		swappedAt  time.Time
+		remaining  int64
+		history    *entities.TaskHistory
+		historyErr error
+		revoked    bool
+		wantErr    bool
+	}{
+		{
+			name:      "the swap that reached the target is reverted",
+			task:      tasks[0],
+			swappedAt: start.Add(2 * 24 * time.Hour),
+			remaining: 900000000,
+			history:   &entities.TaskHistory{ID: 5, Amount: decimal.NewFromInt(1100), CompletedAt: &completedAt},
+			revoked:   true,
+		},
+		{
+			name:      "a swap after the onboarding window is reverted",
+			task:      tasks[2],
+			swappedAt: start.Add(15 * 24 * time.Hour),
+			remaining: 900000000,
+		},
+		{
+			name:      "the target was reached in another period",
+			task:      tasks[1],
+			swappedAt: start.Add(8 * 24 * time.Hour),
+			remaining: 300000000,
+			history:   &entities.TaskHistory{ID: 5, Amount: decimal.NewFromInt(1100), CompletedAt: &completedAt},
+		},
+		{
+			name:      "a swap made after the completion is reverted",
+			task:      tasks[0],
+			swappedAt: start.Add(5 * 24 * time.Hour),
+			remaining: 900000000,
+			history:   &entities.TaskHistory{ID: 5, Amount: decimal.NewFromInt(1100), CompletedAt: &completedAt},
+		},
+		{
+			name:       "the address was never onboarded",
+			task:       tasks[0],
+			swappedAt:  start.Add(2 * 24 * time.Hour),
+			remaining:  900000000,
+			historyErr: fmt.Errorf("task record not found: %w", sql.ErrNoRows),
+		},
+		{
+			name:       "the onboarding lookup fails",
+			task:       tasks[0],
+			swappedAt:  start.Add(2 * 24 * time.Hour),
+			remaining:  900000000,
+			historyErr: errors.New("connection refused"),
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRedisHelper := new(mocks.MockRedisHelper)
+			mockTaskHistoryRepo := new(mocks.MockTaskHistoryRepository)
+
+			campaignService := &CampaignService{
+				redisHelper:     mockRedisHelper,
+				taskHistoryRepo: mockTaskHistoryRepo,
+			}
+
+			key := fmt.Sprintf("SharePoolTask_%d", tt.task.Period)
+			mockRedisHelper.On("Get", "onboarding_task").Return(onboarding, nil)
+			mockRedisHelper.On("ScaleToIntegers", mock.Anything, mock.Anything, mock.Anything, int64(1000000)).Return(false, nil)
+
+			// the swap is taken back from the period it was credited to
+			mockRedisHelper.On("HIncrByWithTotal", key, senderAddress, key+"_total", int64(-200000000)).Return(tt.remaining, nil)
+
+			if tt.history != nil || tt.historyErr != nil {
+				mockTaskHistoryRepo.On("FindByAddressAndTaskId", senderAddress, int64(1)).Return(tt.history, tt.historyErr)
+			}
+
+			if tt.revoked {
+				mockTaskHistoryRepo.On("Delete", tt.history.ID).Return(nil)
+			}
+
+			totalAmount, err := campaignService.RevertUSDCSwapTotalAmount(tt.task, senderAddress, decimal.NewFromInt(200), tt.swappedAt)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, decimal.NewFromInt(tt.remaining/1000000).String(), totalAmount.String())
+			}
+
+			mockRedisHelper.AssertExpectations(t)
+			mockTaskHistoryRepo.AssertExpectations(t)
+			if !tt.revoked {
+				mockTaskHistoryRepo.AssertNotCalled(t, "Delete", mock.Anything)
+			}
+		})
+	}
+}
+
+// newSharePoolTasks returns four consecutive weekly share pool tasks starting at start.
+func newSharePoolTasks(start time.Time) []*entities.Task {
+	tasks := []*entities.Task{}
+	for i := 1; i <= 4; i++ {
+		startedAt := start.Add(time.Duration(i-1) * 7 * 24 * time.Hour)
+		endAt := startedAt.Add(7 * 24 * time.Hour)
+
+		tasks = append(tasks, &entities.Task{
+			ID:        int64(i + 1),
+			Name:      SharePoolTaskStr,
+			StartedAt: &startedAt,
+			EndAt:     &endAt,
+			Period:    i,
+		})
+	}
+
+	return tasks
 }
 
 func TestCampaignService_GetLeaderboard(t *testing.T) {
 	// Mock dependencies
 	mockRedisHelper := new(mocks.MockRedisHelper)
 	mockTaskRepo := new(mocks.MockTaskRepository)
+	mockCampaignRepo := new(mocks.MockCampaignRepository)
 
 	// Initialize the service with mocked dependencies
 	campaignService := &CampaignService{
-		redisHelper: mockRedisHelper,
-		taskRepo:    mockTaskRepo,
+		redisHelper:  mockRedisHelper,
+		taskRepo:     mockTaskRepo,
+		campaignRepo: mockCampaignRepo,
 	}
 
+	// tasks created before campaigns were stored keep their keys
+	mockCampaignRepo.On("FindLatest").Return((*entities.Campaign)(nil), fmt.Errorf("campaign not found: %w", sql.ErrNoRows)).Times(2)
+
 	// Test case variables
 	taskName := "SharePoolTask"
 	period := 7
@@ -253,7 +831,7 @@ func TestCampaignService_GetLeaderboard(t *testing.T) {
 		}
 
 		// Call the method
-		result, err := campaignService.GetLeaderboard(taskName, period)
+		result, err := campaignService.GetLeaderboard(0, taskName, period, 0)
 
 		// Assertions
 		assert.NoError(t, err)
@@ -262,4 +840,303 @@ func TestCampaignService_GetLeaderboard(t *testing.T) {
 		// Assert that Redis helper was called as expected
 		mockRedisHelper.AssertExpectations(t)
 	})
+
+	t.Run("Per chain", func(t *testing.T) {
+		mockRedisHelper.On("ZRevRangeWithScores", "SharePoolTask_7_chain_8453_rank", int64(0), int64(-1)).
+			Return([]string{"address3"}, []float64{10000}, nil)
+
+		result, err := campaignService.GetLeaderboard(0, taskName, period, 8453)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.LeaderboardEntry{{Address: "address3", Score: 10000}}, result)
+	})
+
+	t.Run("Latest campaign", func(t *testing.T) {
+		mockCampaignRepo.On("FindLatest").Return(&entities.Campaign{ID: 2}, nil).Once()
+		mockRedisHelper.On("ZRevRangeWithScores", "campaign_2_SharePoolTask_7_rank", int64(0), int64(-1)).
+			Return([]string{"address4"}, []float64{500}, nil)
+
+		result, err := campaignService.GetLeaderboard(0, taskName, period, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.LeaderboardEntry{{Address: "address4", Score: 500}}, result)
+	})
+
+	t.Run("Earlier campaign", func(t *testing.T) {
+		mockCampaignRepo.On("FindByID", int64(3)).Return(&entities.Campaign{ID: 3}, nil).Once()
+		mockRedisHelper.On("ZRevRangeWithScores", "campaign_3_SharePoolTask_7_rank", int64(0), int64(-1)).
+			Return([]string{"address5"}, []float64{250}, nil)
+
+		result, err := campaignService.GetLeaderboard(3, taskName, period, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.LeaderboardEntry{{Address: "address5", Score: 250}}, result)
+	})
+
+	t.Run("Unknown campaign", func(t *testing.T) {
+		mockCampaignRepo.On("FindByID", int64(4)).Return((*entities.Campaign)(nil), fmt.Errorf("campaign not found: %w", sql.ErrNoRows)).Once()
+
+		_, err := campaignService.GetLeaderboard(4, taskName, period, 0)
+
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestCalculateSharePoolPoint(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTaskHistoryRepo := new(mocks.MockTaskHistoryRepository)
+	mockSettlementJobRepo := new(mocks.MockSettlementJobRepository)
+	mockTransactor := new(mocks.MockTransactor)
+
+	campaignService := &CampaignService{
+		redisHelper:       mockRedisHelper,
+		taskHistoryRepo:   mockTaskHistoryRepo,
+		settlementJobRepo: mockSettlementJobRepo,
+		transactor:        mockTransactor,
+	}
+
+	task := &entities.Task{ID: 2, Name: SharePoolTaskStr, Period: 1, Points: SharePoolTaskPoints}
+
+	mockRedisHelper.On("ScaleToIntegers", mock.Anything, mock.Anything, mock.Anything, int64(1000000)).Return(false, nil)
+
+	// three equal traders, and one whose swaps were all reverted
+	mockRedisHelper.On("HGetAll", "SharePoolTask_1").Return(map[string]string{
+		"0xc": "1000000",
+		"0xa": "1000000",
+		"0xb": "1000000",
+		"0xd": "0",
+	}, nil)
+	mockRedisHelper.On("ZAdd", "SharePoolTask_1_rank", mock.Anything).Return(nil).Once()
+	mockTransactor.On("InTransaction").Return(nil)
+	mockSettlementJobRepo.On("LockClaim", int64(6), testFencingToken).Return(nil).Once()
+	mockSettlementJobRepo.On("MarkSettled", int64(6), testFencingToken).Return(nil).Once()
+
+	rewards := map[string]decimal.Decimal{}
+	mockTaskHistoryRepo.On("Create", mock.Anything).Return(&entities.TaskHistory{}, nil).Run(func(args mock.Arguments) {
+		history := args.Get(0).(*entities.TaskHistory)
+		rewards[history.Address] = history.RewardPoints
+	})
+
+	assert.NoError(t, campaignService.calculateSharePoolPoint(task, 6, testFencingToken))
+	mockSettlementJobRepo.AssertExpectations(t)
+	mockRedisHelper.AssertExpectations(t)
+
+	// the rewards add up to exactly the pool, the leftover unit goes to the first address
+	assert.Len(t, rewards, 3)
+	assert.Equal(t, "3333.333334", rewards["0xa"].String())
+	assert.Equal(t, "3333.333333", rewards["0xb"].String())
+	assert.Equal(t, "3333.333333", rewards["0xc"].String())
+	assert.Equal(t, "10000", rewards["0xa"].Add(rewards["0xb"]).Add(rewards["0xc"]).String())
+
+	// the period is settled once the transaction commits, a failed ranking write is logged
+	loggerMock := &mocks.MockLogger{}
+	loggerMock.On("Error", mock.Anything).Return().Once()
+	campaignService.logger = loggerMock
+
+	mockRedisHelper.On("ZAdd", "SharePoolTask_1_rank", mock.Anything).Return(errors.New("redis: connection refused")).Once()
+	mockSettlementJobRepo.On("LockClaim", int64(7), testFencingToken).Return(nil).Once()
+	mockSettlementJobRepo.On("MarkSettled", int64(7), testFencingToken).Return(nil).Once()
+
+	assert.NoError(t, campaignService.calculateSharePoolPoint(task, 7, testFencingToken))
+	loggerMock.AssertExpectations(t)
+
+	// a newer holder claimed the job before it was settled, the period is neither settled nor ranked
+	mockSettlementJobRepo.On("LockClaim", int64(8), testFencingToken).Return(nil).Once()
+	mockSettlementJobRepo.On("MarkSettled", int64(8), testFencingToken).Return(errors.New("settlement job 8 is no longer claimed")).Once()
+
+	assert.Error(t, campaignService.calculateSharePoolPoint(task, 8, testFencingToken))
+	mockRedisHelper.AssertNumberOfCalls(t, "ZAdd", 2)
+}
+
+func TestCalculateSharePoolPointInOneTransaction(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	redisHelper := newMemoryRedisHelper()
+	campaignID := int64(3)
+
+	campaignService := &CampaignService{
+		redisHelper:       redisHelper,
+		taskHistoryRepo:   repositories.NewTaskHistoryRepository(db),
+		settlementJobRepo: repositories.NewSettlementJobRepository(db),
+		transactor:        repositories.NewTransactor(db),
+	}
+
+	task := &entities.Task{ID: 2, CampaignID: &campaignID, Name: SharePoolTaskStr, Period: 1, Points: SharePoolTaskPoints}
+
+	redisHelper.HSet("campaign_3_SharePoolTask_1", "0xa", "3000000")
+	redisHelper.HSet("campaign_3_SharePoolTask_1", "0xb", "1000000")
+
+	historyColumns := []string{"id", "address", "task_id", "reward_points", "amount", "completed_at", "created_at", "updated_at"}
+	now := time.Now()
+
+	// a newer holder of the settlement lock claimed the job, nothing is written
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT id FROM settlement_jobs (.+) FOR UPDATE`).WithArgs(int64(6), testFencingToken).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectRollback()
+
+	err = campaignService.calculateSharePoolPoint(task, 6, testFencingToken)
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// the second reward fails, the first one is rolled back and the period stays pending
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT id FROM settlement_jobs (.+) FOR UPDATE`).WithArgs(int64(6), testFencingToken).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	sqlMock.ExpectQuery(`INSERT INTO task_histories`).WithArgs("0xa", int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow(1, "0xa", 2, "7500", "3", now, now, now))
+	sqlMock.ExpectQuery(`INSERT INTO task_histories`).WithArgs("0xb", int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(sql.ErrConnDone)
+	sqlMock.ExpectRollback()
+
+	err = campaignService.calculateSharePoolPoint(task, 6, testFencingToken)
+
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	ranks, _, _ := redisHelper.ZRevRangeWithScores("campaign_3_SharePoolTask_1_rank", 0, -1)
+	assert.Empty(t, ranks)
+
+	// the commit fails, the period is not ranked either
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT id FROM settlement_jobs (.+) FOR UPDATE`).WithArgs(int64(6), testFencingToken).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	sqlMock.ExpectQuery(`INSERT INTO task_histories`).WithArgs("0xa", int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow(1, "0xa", 2, "7500", "3", now, now, now))
+	sqlMock.ExpectQuery(`INSERT INTO task_histories`).WithArgs("0xb", int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow(2, "0xb", 2, "2500", "1", now, now, now))
+	sqlMock.ExpectExec(`UPDATE settlement_jobs SET status = 'settled'`).WithArgs(int64(6), testFencingToken).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit().WillReturnError(sql.ErrConnDone)
+
+	err = campaignService.calculateSharePoolPoint(task, 6, testFencingToken)
+
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	ranks, _, _ = redisHelper.ZRevRangeWithScores("campaign_3_SharePoolTask_1_rank", 0, -1)
+	assert.Empty(t, ranks)
+
+	// the retry stores every reward, settles the job and ranks the period
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT id FROM settlement_jobs (.+) FOR UPDATE`).WithArgs(int64(6), testFencingToken).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	sqlMock.ExpectQuery(`INSERT INTO task_histories`).WithArgs("0xa", int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow(2, "0xa", 2, "7500", "3", now, now, now))
+	sqlMock.ExpectQuery(`INSERT INTO task_histories`).WithArgs("0xb", int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow(3, "0xb", 2, "2500", "1", now, now, now))
+	sqlMock.ExpectExec(`UPDATE settlement_jobs SET status = 'settled'`).WithArgs(int64(6), testFencingToken).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	assert.NoError(t, campaignService.calculateSharePoolPoint(task, 6, testFencingToken))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+
+	ranks, _, _ = redisHelper.ZRevRangeWithScores("campaign_3_SharePoolTask_1_rank", 0, -1)
+	assert.Equal(t, []string{"0xa", "0xb"}, ranks)
+}
+
+func TestCalculateSharePoolPointConvertsLegacyAmounts(t *testing.T) {
+	redisHelper := newMemoryRedisHelper()
+	taskHistoryRepo := &memoryTaskHistoryRepository{}
+	loggerMock := &mocks.MockLogger{}
+	loggerMock.On("Info", mock.Anything).Return()
+
+	mockSettlementJobRepo := new(mocks.MockSettlementJobRepository)
+	mockTransactor := new(mocks.MockTransactor)
+
+	campaignService := &CampaignService{
+		logger:            loggerMock,
+		redisHelper:       redisHelper,
+		taskHistoryRepo:   taskHistoryRepo,
+		settlementJobRepo: mockSettlementJobRepo,
+		transactor:        mockTransactor,
+	}
+
+	task := &entities.Task{ID: 2, Name: SharePoolTaskStr, Period: 1, Points: SharePoolTaskPoints}
+
+	mockTransactor.On("InTransaction").Return(nil)
+	mockSettlementJobRepo.On("LockClaim", int64(6), testFencingToken).Return(nil)
+	mockSettlementJobRepo.On("MarkSettled", int64(6), testFencingToken).Return(nil)
+
+	// float USD amounts written by a release before the amounts were kept in units
+	redisHelper.HSet("SharePoolTask_1", "0xa", "1.5")
+	redisHelper.HSet("SharePoolTask_1", "0xb", "0.5")
+
+	assert.NoError(t, campaignService.calculateSharePoolPoint(task, 6, testFencingToken))
+
+	rewards := map[string]string{}
+	for _, history := range taskHistoryRepo.histories {
+		rewards[history.Address] = history.RewardPoints.String()
+	}
+	assert.Equal(t, map[string]string{"0xa": "7500", "0xb": "2500"}, rewards)
+
+	amounts, _ := redisHelper.HGetAll("SharePoolTask_1")
+	assert.Equal(t, map[string]string{"0xa": "1500000", "0xb": "500000"}, amounts)
+
+	// later swaps add units to the converted amounts, and nothing is converted twice
+	totalAmount, err := campaignService.incrSwapAmount(task, "0xa", decimal.RequireFromString("0.25"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.75", totalAmount.String())
+
+	converted, err := redisHelper.ScaleToIntegers("SharePoolTask_1", "SharePoolTask_1_total", "SharePoolTask_1_units", 1000000)
+	assert.NoError(t, err)
+	assert.False(t, converted)
+}
+
+func TestRecordUSDCSwapTotalAmountConvertsLegacyAmountsOnce(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	loggerMock := &mocks.MockLogger{}
+	loggerMock.On("Info", mock.Anything).Return()
+
+	campaignService := &CampaignService{logger: loggerMock, redisHelper: mockRedisHelper}
+
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	task := newSharePoolTasks(start)[0]
+
+	mockRedisHelper.On("Get", "onboarding_task").Return(`{"id":1,"name":"OnboardingTask","period":1,"TargetAmount":1000}`, nil)
+	mockRedisHelper.On("ScaleToIntegers", "SharePoolTask_1", "SharePoolTask_1_total", "SharePoolTask_1_units", int64(1000000)).Return(true, nil).Once()
+	mockRedisHelper.On("HIncrByWithTotal", "SharePoolTask_1", "0x123", "SharePoolTask_1_total", int64(100000000)).Return(int64(100000000), nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := campaignService.RecordUSDCSwapTotalAmount(task, "0x123", decimal.NewFromInt(100), start)
+		assert.NoError(t, err)
+	}
+
+	// a failed conversion leaves the swap unrecorded rather than mixing floats with units
+	failingRedisHelper := new(mocks.MockRedisHelper)
+	failing := &CampaignService{logger: loggerMock, redisHelper: failingRedisHelper}
+	failingRedisHelper.On("ScaleToIntegers", "SharePoolTask_1", "SharePoolTask_1_total", "SharePoolTask_1_units", int64(1000000)).Return(false, errors.New("redis: connection refused"))
+
+	_, err := failing.RecordUSDCSwapTotalAmount(task, "0x123", decimal.NewFromInt(100), start)
+	assert.ErrorIs(t, err, errSwapNotRecorded)
+	mockRedisHelper.AssertExpectations(t)
+}
+
+func TestGetSwapActivities(t *testing.T) {
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+	mockCampaignRepo := new(mocks.MockCampaignRepository)
+
+	campaignService := &CampaignService{
+		swapEventRepo: mockSwapEventRepo,
+		campaignRepo:  mockCampaignRepo,
+	}
+
+	period := 2
+	activities := []*models.SwapActivity{
+		{SwapEvent: &entities.SwapEvent{ID: 1, SenderAddress: "d8da6bf26964af9d7eed9e03e53415d37aa96045"}, TaskPeriod: &period},
+	}
+
+	// the address is looked up the way swaps are stored, page 3 of 20 starts at offset 40
+	mockCampaignRepo.On("FindByID", int64(5)).Return(&entities.Campaign{ID: 5}, nil)
+	mockSwapEventRepo.On("GetByAddress", "d8da6bf26964af9d7eed9e03e53415d37aa96045", mock.MatchedBy(func(campaignID *int64) bool {
+		return campaignID != nil && *campaignID == 5
+	}), 2, 20, 40).Return(activities, 41, nil)
+
+	result, total, err := campaignService.GetSwapActivities("0xD8dA6BF26964aF9D7eEd9e03E53415D37aA96045", 5, 2, 3, 20)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 41, total)
+	assert.Equal(t, activities, result)
+	mockSwapEventRepo.AssertExpectations(t)
 }
@@ -2,17 +2,25 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"strings"
 	"sync"
+	"time"
 	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
 	"trading-ace/logger"
 	"trading-ace/models"
+	"trading-ace/repositories"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -20,10 +28,24 @@ import (
 
 type IEthereumClient interface {
 	SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error)
+	FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error)
+	BlockNumber(context.Context) (uint64, error)
+	TransactionByHash(context.Context, common.Hash) (*types.Transaction, bool, error)
+	TransactionSender(context.Context, *types.Transaction, common.Hash, uint) (common.Address, error)
+	HeaderByNumber(context.Context, *big.Int) (*types.Header, error)
+	CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error)
+	Close()
 }
 
 type IEthereumService interface {
 	SubscribeEthereumSwap() error
+	BackfillSwapEvents(chainID int64, fromBlock uint64, toBlock uint64) error
+	QueueDepth() int
+	QueueCapacity() int
+	Shutdown(ctx context.Context) error
+	GetFailedSwapLogs(page int, pageSize int) ([]*entities.FailedSwapLog, int, error)
+	RetryFailedSwapLog(id int64) error
+	DiscardFailedSwapLog(id int64) error
 }
 
 type IABI interface {
@@ -34,22 +56,141 @@ type EthereumService struct {
 	campaignService ICampaignService
 	logger          logger.ILogger
 	config          *config.Config
+	checkpointRepo  repositories.IIngestionCheckpointRepository
+	swapEventRepo   repositories.ISwapEventRepository
+	// logs that failed to decode or credit, kept for retrying, failed logs are only logged when nil
+	failedSwapLogRepo repositories.IFailedSwapLogRepository
+	// resolves the tokens of the pools, pools keep their configured tokens when nil
+	tokenRegistry ITokenRegistry
+
+	// one service per configured chain, each running its own ingestion loop on the shared pipeline
+	chains []*EthereumService
+	// chain ingested by this service, nil for the service that runs the chains
+	chain *config.ChainConfig
+	// set once the pool tokens of chain were resolved through the token registry
+	poolsResolved bool
+	poolsMu       sync.Mutex
+
+	// client of the current connection, used for lookups while processing logs
+	client IEthereumClient
+	// transaction hash to transaction sender, for origin attribution
+	originCache *lru.Cache[common.Hash, common.Address]
+	// block number to block time, for the swap history
+	blockTimeCache *lru.Cache[uint64, time.Time]
+	// pool address to the USD price of its non-stablecoin token, from the latest Sync log
+	poolPrices map[common.Address]*big.Rat
+
+	// position of the last log handed to processSwapEvent, used to fill the gap after a reconnect
+	lastProcessed *logPosition
+	// logs waiting for enough confirmations before they are credited
+	pendingLogs []types.Log
+
+	// workers storing and crediting decoded swaps, logs are processed inline when nil
+	pipeline *swapPipeline
+	// closed by Shutdown to stop the swap source
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// closed when SubscribeEthereumSwap returns
+	sourceDone chan struct{}
+}
+
+type logPosition struct {
+	BlockNumber uint64
+	LogIndex    uint
+}
+
+var swapV2EventSignatureHash = crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)"))
+var swapV3EventSignatureHash = crypto.Keccak256Hash([]byte("Swap(address,address,int256,int256,uint160,uint128,int24)"))
+
+// swapV3Event holds the non-indexed fields of a Uniswap V3 Swap log
+type swapV3Event struct {
+	Amount0      *big.Int
+	Amount1      *big.Int
+	SqrtPriceX96 *big.Int
+	Liquidity    *big.Int
+	Tick         *big.Int
 }
 
-const wethDecimals int64 = 18
-const usdcDecimals int64 = 6
+const originCacheSize = 10000
+const blockTimeCacheSize = 1000
+
+const attributionSender string = "sender"
+const attributionRecipient string = "recipient"
+const attributionOrigin string = "origin"
+
+const quoteToken0 string = "token0"
+const quoteToken1 string = "token1"
+
+const defaultBackfillChunkSize uint64 = 2000
+
+// maxLogIndex is the largest log index a checkpoint stores, log_index is an INT column
+const maxLogIndex uint = math.MaxInt32
+
+const headPollInterval = 12 * time.Second
+const defaultPollInterval = 12 * time.Second
+
+// errSourceStopped is returned by the swap sources once Shutdown was called
+var errSourceStopped = fmt.Errorf("swap source stopped")
+
+const minReconnectBackoff = time.Second
+const maxReconnectBackoff = time.Minute
+
+func NewEthereumService(
+	logger logger.ILogger,
+	config *config.Config,
+	campaignService ICampaignService,
+	checkpointRepo repositories.IIngestionCheckpointRepository,
+	swapEventRepo repositories.ISwapEventRepository,
+	failedSwapLogRepo repositories.IFailedSwapLogRepository,
+	tokenRegistry ITokenRegistry,
+) IEthereumService {
+	e := &EthereumService{
+		campaignService:   campaignService,
+		logger:            logger,
+		config:            config,
+		checkpointRepo:    checkpointRepo,
+		swapEventRepo:     swapEventRepo,
+		failedSwapLogRepo: failedSwapLogRepo,
+		tokenRegistry:     tokenRegistry,
+		stopCh:            make(chan struct{}),
+		sourceDone:        make(chan struct{}),
+	}
+	e.pipeline = newSwapPipeline(logger, config.Pipeline.Workers, config.Pipeline.QueueSize, e.commitJob)
+
+	for _, chain := range config.ChainConfigs() {
+		e.chains = append(e.chains, e.newChainService(chain))
+	}
+
+	return e
+}
+
+// newChainService returns the service ingesting one chain. It shares the stores, the pipeline and
+// the stop signal with e, and keeps its own client, caches and log position.
+func (e *EthereumService) newChainService(chain config.ChainConfig) *EthereumService {
+	// the resolved tokens are written to the pools, the config is left as loaded
+	chain.Pools = append([]config.PoolConfig(nil), chain.Pools...)
 
-func NewEthereumService(logger logger.ILogger, config *config.Config, campaignService ICampaignService) IEthereumService {
 	return &EthereumService{
-		campaignService: campaignService,
-		logger:          logger,
-		config:          config,
+		campaignService:   e.campaignService,
+		logger:            e.logger,
+		config:            e.config,
+		checkpointRepo:    e.checkpointRepo,
+		swapEventRepo:     e.swapEventRepo,
+		failedSwapLogRepo: e.failedSwapLogRepo,
+		tokenRegistry:     e.tokenRegistry,
+		chain:             &chain,
+		originCache:       lru.NewCache[common.Hash, common.Address](originCacheSize),
+		blockTimeCache:    lru.NewCache[uint64, time.Time](blockTimeCacheSize),
+		pipeline:          e.pipeline,
+		stopCh:            e.stopCh,
 	}
 }
 
+// SubscribeEthereumSwap runs the ingestion loop of every configured chain until Shutdown is called.
 func (e *EthereumService) SubscribeEthereumSwap() error {
-	client, err := e.connectToClient()
-	if err != nil {
+	defer close(e.sourceDone)
+
+	if err := e.validateChains(); err != nil {
 		return err
 	}
 
@@ -58,39 +199,673 @@ func (e *EthereumService) SubscribeEthereumSwap() error {
 		return err
 	}
 
+	errs := make([]error, len(e.chains))
+
+	var wg sync.WaitGroup
+	for i, chain := range e.chains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// a failing chain does not stop the others
+			if err := chain.newSwapSource().Run(parsedABI); err != nil {
+				errs[i] = fmt.Errorf("%s ingestion stopped: %w", chain.chain.Name, err)
+				e.logger.Error(errs[i])
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (e *EthereumService) validateChains() error {
+	if len(e.chains) == 0 {
+		return fmt.Errorf("no chains configured")
+	}
+
+	seen := map[int64]bool{}
+	for _, chain := range e.chains {
+		if chain.chain.ID <= 0 {
+			return fmt.Errorf("chain %s has no chain id", chain.chain.Name)
+		}
+
+		if seen[chain.chain.ID] {
+			return fmt.Errorf("chain id %d is configured twice", chain.chain.ID)
+		}
+		seen[chain.chain.ID] = true
+
+		if len(chain.chain.Pools) == 0 {
+			return fmt.Errorf("no pools configured for chain %s", chain.chain.Name)
+		}
+	}
+
+	return nil
+}
+
+// findChain returns the service of a configured chain.
+func (e *EthereumService) findChain(chainID int64) (*EthereumService, error) {
+	for _, chain := range e.chains {
+		if chain.chain.ID == chainID {
+			return chain, nil
+		}
+	}
+
+	return nil, fmt.Errorf("chain %d is not configured", chainID)
+}
+
+func (e *EthereumService) QueueDepth() int {
+	if e.pipeline == nil {
+		return 0
+	}
+
+	return e.pipeline.Depth()
+}
+
+func (e *EthereumService) QueueCapacity() int {
+	if e.pipeline == nil {
+		return 0
+	}
+
+	return e.pipeline.Capacity()
+}
+
+// Shutdown stops the swap source and waits for the swaps already queued to be credited.
+func (e *EthereumService) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stopCh) })
+
+	select {
+	case <-e.sourceDone:
+	case <-ctx.Done():
+		return fmt.Errorf("swap source did not stop: %w", ctx.Err())
+	}
+
+	if e.pipeline == nil {
+		return nil
+	}
+
+	e.logger.Info(fmt.Sprintf("draining %d queued swaps", e.pipeline.Depth()))
+
+	return e.pipeline.Drain(ctx)
+}
+
+// isStopping reports whether Shutdown was called.
+func (e *EthereumService) isStopping() bool {
+	select {
+	case <-e.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// consumeSwapEvents subscribes to swap logs, fills the gap since the last processed log and then
+// processes live logs until the subscription fails. The returned bool reports whether the
+// subscription was established, so the caller can reset its backoff.
+func (e *EthereumService) consumeSwapEvents(client IEthereumClient, parsedABI IABI) (bool, error) {
 	logsCh, sub, err := e.subscribeToSwapEvent(client)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	defer sub.Unsubscribe()
 
-	for vLog := range logsCh {
-		event, err := e.retrieveEventData(vLog, parsedABI)
+	// unconfirmed logs of a previous session are fetched again by the backfill below
+	e.pendingLogs = nil
+
+	// subscribe before backfilling so nothing emitted in between is missed, duplicates are skipped by position
+	if err := e.backfillSinceLastProcessed(client, parsedABI); err != nil {
+		return true, err
+	}
+
+	// the head is looked up periodically to release confirmed logs and to record the safe head
+	ticker := time.NewTicker(headPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return true, errSourceStopped
+		case err := <-sub.Err():
+			if err == nil {
+				err = fmt.Errorf("subscription closed")
+			}
+
+			return true, err
+		case vLog := <-logsCh:
+			e.handleLog(vLog, parsedABI)
+		case <-ticker.C:
+			head, err := client.BlockNumber(context.Background())
+			if err != nil {
+				e.logger.Error(fmt.Errorf("failed to get latest block number: %v", err))
+				continue
+			}
+
+			e.releaseConfirmedLogs(head, parsedABI)
+
+			// the logs of the newest block may still be on their way, the safe head stays a block behind
+			if head > e.chain.Confirmations {
+				e.reachHead(head - e.chain.Confirmations - 1)
+			}
+		}
+	}
+}
+
+// BackfillSwapEvents replays historical swap logs of a chain between fromBlock and toBlock
+// (inclusive) through the regular processing path. A toBlock of 0 means the latest block.
+func (e *EthereumService) BackfillSwapEvents(chainID int64, fromBlock uint64, toBlock uint64) error {
+	chain, err := e.findChain(chainID)
+	if err != nil {
+		return err
+	}
+
+	if len(chain.chain.Pools) == 0 {
+		return fmt.Errorf("no pools configured for chain %s", chain.chain.Name)
+	}
+
+	client, err := chain.connectToClient()
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if err := chain.resolvePools(client); err != nil {
+		return err
+	}
+
+	parsedABI, err := e.parseABI()
+	if err != nil {
+		return err
+	}
+
+	if err := chain.backfillRange(client, parsedABI, fromBlock, toBlock); err != nil {
+		return err
+	}
+
+	// the backfilled swaps are only credited once the queue has drained
+	if e.pipeline != nil {
+		return e.pipeline.Drain(context.Background())
+	}
+
+	return nil
+}
+
+func (e *EthereumService) backfillRange(client IEthereumClient, parsedABI IABI, fromBlock uint64, toBlock uint64) error {
+	if toBlock == 0 {
+		latest, err := client.BlockNumber(context.Background())
 		if err != nil {
-			e.logger.Error(err)
-			continue
+			return fmt.Errorf("failed to get latest block number: %v", err)
+		}
+
+		// stay behind the head by the confirmation depth so reorged logs are never credited
+		if latest < e.chain.Confirmations {
+			return fmt.Errorf("latest block %d is below the confirmation depth", latest)
 		}
 
-		err = e.processSwapEvent(event)
+		toBlock = latest - e.chain.Confirmations
+	}
+
+	if fromBlock > toBlock {
+		return fmt.Errorf("invalid block range: from %d is after to %d", fromBlock, toBlock)
+	}
+
+	totalBlocks := toBlock - fromBlock + 1
+	totalLogs := 0
+
+	return e.filterLogsInChunks(client, fromBlock, toBlock, func(start uint64, end uint64, logs []types.Log) {
+		for _, vLog := range logs {
+			e.processLog(vLog, parsedABI)
+		}
+
+		totalLogs += len(logs)
+		e.logger.Info(fmt.Sprintf("backfilled %s blocks %d-%d (%d/%d blocks, %d logs)", e.chain.Name, start, end, end-fromBlock+1, totalBlocks, totalLogs))
+	})
+}
+
+// filterLogsInChunks fetches swap logs between fromBlock and toBlock (inclusive) in chunks of
+// backfill.chunk_size blocks and hands each chunk to onChunk in block order.
+func (e *EthereumService) filterLogsInChunks(client IEthereumClient, fromBlock uint64, toBlock uint64, onChunk func(start uint64, end uint64, logs []types.Log)) error {
+	chunkSize := e.config.Backfill.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultBackfillChunkSize
+	}
+
+	start := fromBlock
+	for start <= toBlock {
+		end := start + chunkSize - 1
+		if end > toBlock || end < start {
+			end = toBlock
+		}
+
+		query := e.swapFilterQuery()
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(end)
+
+		logs, err := client.FilterLogs(context.Background(), query)
+		if err != nil {
+			// providers cap the number of results per request, retry the same range in smaller chunks
+			if chunkSize > 1 {
+				chunkSize /= 2
+				e.logger.Warn(fmt.Sprintf("failed to filter %s logs for blocks %d-%d: %v, retrying with chunk size %d", e.chain.Name, start, end, err, chunkSize))
+				continue
+			}
+
+			return fmt.Errorf("failed to filter logs for blocks %d-%d: %v", start, end, err)
+		}
+
+		onChunk(start, end, logs)
+
+		start = end + 1
+	}
+
+	return nil
+}
+
+// pollSwapEvents is the fallback for HTTP endpoints that cannot push logs. It polls eth_getLogs
+// with a block cursor and stays the confirmation depth behind the head, so removed logs never
+// reach it. The returned bool reports whether the endpoint answered at least once.
+func (e *EthereumService) pollSwapEvents(client IEthereumClient, parsedABI IABI) (bool, error) {
+	interval := e.chain.PollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reachable := false
+	hasCursor := e.lastProcessed != nil
+	var nextBlock uint64
+	if hasCursor {
+		// the last processed block is scanned again, logs up to the processed position are skipped
+		nextBlock = e.lastProcessed.BlockNumber
+	}
+
+	for {
+		head, err := client.BlockNumber(context.Background())
 		if err != nil {
+			return reachable, fmt.Errorf("failed to get latest block number: %v", err)
+		}
+
+		if head >= e.chain.Confirmations {
+			safeHead := head - e.chain.Confirmations
+
+			// without a previous position polling starts at the live head
+			if !hasCursor {
+				nextBlock = safeHead + 1
+				hasCursor = true
+			}
+
+			if nextBlock <= safeHead {
+				err := e.filterLogsInChunks(client, nextBlock, safeHead, func(start uint64, end uint64, logs []types.Log) {
+					for _, vLog := range logs {
+						e.processLog(vLog, parsedABI)
+					}
+				})
+				if err != nil {
+					return true, err
+				}
+
+				nextBlock = safeHead + 1
+				e.reachHead(safeHead)
+			}
+		}
+
+		reachable = true
+
+		select {
+		case <-e.stopCh:
+			return true, errSourceStopped
+		case <-ticker.C:
+		}
+	}
+}
+
+// backfillSinceLastProcessed fetches the logs from the last processed block up to the head in
+// chunks, an outage can leave a gap larger than providers serve in one request. Without a last
+// processed log ingestion starts at the head, which becomes the position a reconnect backfills from.
+func (e *EthereumService) backfillSinceLastProcessed(client IEthereumClient, parsedABI IABI) error {
+	head, err := client.BlockNumber(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get latest block number: %v", err)
+	}
+
+	if e.lastProcessed == nil {
+		e.lastProcessed = &logPosition{BlockNumber: head, LogIndex: maxLogIndex}
+		return nil
+	}
+
+	if head < e.lastProcessed.BlockNumber {
+		return nil
+	}
+
+	return e.filterLogsInChunks(client, e.lastProcessed.BlockNumber, head, func(start uint64, end uint64, logs []types.Log) {
+		for _, vLog := range logs {
+			e.handleLog(vLog, parsedABI)
+		}
+	})
+}
+
+func (e *EthereumService) handleLog(vLog types.Log, parsedABI IABI) {
+	if vLog.Removed {
+		e.rollbackLog(vLog)
+		return
+	}
+
+	if e.chain.Confirmations > 0 {
+		e.pendingLogs = append(e.pendingLogs, vLog)
+		return
+	}
+
+	e.processLog(vLog, parsedABI)
+}
+
+// releaseConfirmedLogs processes the pending logs that are at least the confirmation depth below head.
+func (e *EthereumService) releaseConfirmedLogs(head uint64, parsedABI IABI) {
+	remaining := e.pendingLogs[:0]
+	for _, vLog := range e.pendingLogs {
+		if vLog.BlockNumber+e.chain.Confirmations <= head {
+			e.processLog(vLog, parsedABI)
+			continue
+		}
+
+		remaining = append(remaining, vLog)
+	}
+
+	e.pendingLogs = remaining
+}
+
+// processLog decodes and values a log on the reader and hands the storing and crediting to the
+// pipeline, keyed by the credited address.
+func (e *EthereumService) processLog(vLog types.Log, parsedABI IABI) {
+	if e.isProcessed(vLog) {
+		return
+	}
+
+	job := &swapJob{
+		key:      vLog.Address.Hex(),
+		position: &logPosition{BlockNumber: vLog.BlockNumber, LogIndex: vLog.Index},
+		chain:    e.chain,
+		contract: vLog.Address.Hex(),
+	}
+
+	// prices are updated on the reader so every swap is valued at the price of its position
+	if isSyncLog(vLog) {
+		if err := e.updatePoolPrice(vLog, parsedABI); err != nil {
+			e.logger.Error(err)
+		}
+	} else if event, credit, err := e.prepareSwapLog(vLog, parsedABI); err != nil {
+		e.logger.Error(err)
+		e.deadLetter(vLog, failedStageDecode, nil, err)
+	} else {
+		job.key = event.SenderAddress
+		job.run = e.deadLetterOnFailure(vLog, event, credit)
+	}
+
+	e.lastProcessed = job.position
+	e.dispatch(job)
+}
+
+// prepareSwapLog decodes a swap log, dates it by its block and values it.
+func (e *EthereumService) prepareSwapLog(vLog types.Log, parsedABI IABI) (*models.SwapEvent, func() error, error) {
+	event, err := e.decodeLog(vLog, parsedABI)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the block time decides the period the swap counts towards
+	event.BlockTimestamp, err = e.blockTimestamp(vLog)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credit, err := e.valueSwapEvent(event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return event, credit, nil
+}
+
+// rollbackLog handles a log dropped by a chain reorganization. Logs still waiting for confirmations
+// are discarded, logs that were already credited get their credit reversed from what was stored
+// when they were credited, the removed transaction can no longer be looked up.
+func (e *EthereumService) rollbackLog(vLog types.Log) {
+	for i, pending := range e.pendingLogs {
+		if pending.TxHash == vLog.TxHash && pending.Index == vLog.Index {
+			e.pendingLogs = append(e.pendingLogs[:i], e.pendingLogs[i+1:]...)
+			return
+		}
+	}
+
+	// the replacement block may hold logs at the same or lower positions, they are only processed
+	// once the position is moved back before the removed block
+	rewindTo := positionBeforeBlock(vLog.BlockNumber)
+	if e.lastProcessed != nil && e.isBefore(rewindTo, e.lastProcessed) {
+		e.lastProcessed = rewindTo
+	}
+
+	job := &swapJob{
+		key:      vLog.Address.Hex(),
+		position: rewindTo,
+		rewind:   true,
+		chain:    e.chain,
+		contract: vLog.Address.Hex(),
+	}
+
+	// the price is corrected by the next Sync log of the canonical chain
+	if !isSyncLog(vLog) {
+		// same key as the credit, so the revert runs after it
+		job.key = e.creditKey(vLog)
+		job.run = func() error { return e.revertSwapLog(vLog) }
+	}
+
+	e.dispatch(job)
+}
+
+// positionBeforeBlock returns the position of the last log a block before blockNumber can hold.
+func positionBeforeBlock(blockNumber uint64) *logPosition {
+	if blockNumber == 0 {
+		return &logPosition{}
+	}
+
+	return &logPosition{BlockNumber: blockNumber - 1, LogIndex: maxLogIndex}
+}
+
+// dispatch queues a job on the pipeline, or runs it inline when there is none.
+func (e *EthereumService) dispatch(job *swapJob) {
+	if e.pipeline != nil {
+		if err := e.pipeline.Submit(job); err != nil {
+			e.logger.Error(err)
+		}
+		return
+	}
+
+	if job.run != nil {
+		if err := job.run(); err != nil {
+			e.logger.Error(err)
+		}
+	}
+
+	e.commitJob(job)
+}
+
+// commitJob saves the checkpoint of a job whose log and all logs before it have been credited, or the
+// safe head of a chain whose logs up to it have all been credited.
+func (e *EthereumService) commitJob(job *swapJob) {
+	if job.head != nil {
+		if err := e.checkpointRepo.SaveHead(job.head); err != nil {
 			e.logger.Error(err)
 		}
+		return
+	}
+
+	if job.position == nil {
+		return
+	}
+
+	if job.rewind {
+		e.rewindCheckpoint(job.chain, job.contract, job.position)
+		return
+	}
+
+	e.saveCheckpoint(job.chain, job.contract, job.position)
+}
+
+// reachHead records that the reader has handed every log up to the safe head block on, so settlement
+// knows the swaps of the chain are credited up to its time once the jobs before it are done.
+func (e *EthereumService) reachHead(blockNumber uint64) {
+	blockTimestamp, err := e.blockTime(blockNumber)
+	if err != nil {
+		e.logger.Error(err)
+		return
+	}
+
+	e.dispatch(&swapJob{
+		key:   e.chain.Name,
+		chain: e.chain,
+		head: &entities.IngestionHead{
+			ChainID:        e.chain.ID,
+			Chain:          e.chain.Name,
+			BlockNumber:    blockNumber,
+			BlockTimestamp: blockTimestamp,
+		},
+	})
+}
+
+// resumeFromCheckpoint resumes from the oldest checkpoint of the chain's pools, so no pool misses
+// logs, or from the safe head of the chain once it is past it: every log up to the head is credited,
+// so a pool without swaps for a long time does not hold the restart position back. Logs of pools that
+// were further ahead are skipped by the swap_events dedupe. A failed lookup is returned rather than
+// taken for a missing checkpoint, which would skip the gap since it.
+func (e *EthereumService) resumeFromCheckpoint() error {
+	var resumeFrom *logPosition
+	for _, pool := range e.chain.Pools {
+		checkpoint, err := e.checkpointRepo.FindByChainAndContract(e.chain.ID, common.HexToAddress(pool.Address).Hex())
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resume %s ingestion: %w", e.chain.Name, err)
+		}
+
+		position := &logPosition{BlockNumber: checkpoint.BlockNumber, LogIndex: checkpoint.LogIndex}
+		if resumeFrom == nil || e.isBefore(position, resumeFrom) {
+			resumeFrom = position
+		}
 	}
 
+	head, err := e.checkpointRepo.FindHead(e.chain.ID)
+	if err != nil {
+		return fmt.Errorf("failed to resume %s ingestion: %w", e.chain.Name, err)
+	}
+
+	if head != nil {
+		position := &logPosition{BlockNumber: head.BlockNumber, LogIndex: maxLogIndex}
+		if resumeFrom == nil || e.isBefore(resumeFrom, position) {
+			resumeFrom = position
+		}
+	}
+
+	e.lastProcessed = resumeFrom
+
+	if e.lastProcessed == nil {
+		e.logger.Info(fmt.Sprintf("no %s ingestion checkpoint found, starting from the live head", e.chain.Name))
+		return nil
+	}
+
+	e.logger.Info(fmt.Sprintf("resuming %s ingestion from block %d log %d", e.chain.Name, e.lastProcessed.BlockNumber, e.lastProcessed.LogIndex))
+
 	return nil
 }
 
-func (e *EthereumService) connectToClient() (*ethclient.Client, error) {
-	url := fmt.Sprintf("wss://mainnet.infura.io/ws/v3/%s", e.config.Infura.Key)
-	client, err := ethclient.Dial(url)
+func (e *EthereumService) saveCheckpoint(chain *config.ChainConfig, contractAddress string, position *logPosition) {
+	checkpoint := &entities.IngestionCheckpoint{
+		ChainID:         chain.ID,
+		Chain:           chain.Name,
+		ContractAddress: contractAddress,
+		BlockNumber:     position.BlockNumber,
+		LogIndex:        position.LogIndex,
+	}
+
+	if err := e.checkpointRepo.Save(checkpoint); err != nil {
+		e.logger.Error(err)
+	}
+}
+
+func (e *EthereumService) rewindCheckpoint(chain *config.ChainConfig, contractAddress string, position *logPosition) {
+	checkpoint := &entities.IngestionCheckpoint{
+		ChainID:         chain.ID,
+		Chain:           chain.Name,
+		ContractAddress: contractAddress,
+		BlockNumber:     position.BlockNumber,
+		LogIndex:        position.LogIndex,
+	}
+
+	if err := e.checkpointRepo.Rewind(checkpoint); err != nil {
+		e.logger.Error(err)
+	}
+}
+
+func (e *EthereumService) isProcessed(vLog types.Log) bool {
+	if e.lastProcessed == nil {
+		return false
+	}
+
+	position := &logPosition{BlockNumber: vLog.BlockNumber, LogIndex: vLog.Index}
+
+	return *position == *e.lastProcessed || e.isBefore(position, e.lastProcessed)
+}
+
+func (e *EthereumService) isBefore(a *logPosition, b *logPosition) bool {
+	if a.BlockNumber != b.BlockNumber {
+		return a.BlockNumber < b.BlockNumber
+	}
+
+	return a.LogIndex < b.LogIndex
+}
+
+func nextReconnectBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+
+	return next
+}
+
+// rpcURL returns the chain's endpoint, mainnet falls back to the Infura websocket for older configs.
+func (e *EthereumService) rpcURL() string {
+	if e.chain.RPCURL != "" || e.chain.ID != config.MainnetChainID {
+		return e.chain.RPCURL
+	}
+
+	return fmt.Sprintf("wss://mainnet.infura.io/ws/v3/%s", e.config.Infura.Key)
+}
+
+func isWebsocketURL(url string) bool {
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+func (e *EthereumService) connectToClient() (IEthereumClient, error) {
+	if e.rpcURL() == "" {
+		return nil, fmt.Errorf("no rpc_url configured for chain %s", e.chain.Name)
+	}
+
+	client, err := ethclient.Dial(e.rpcURL())
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum client: %v", err)
+		return nil, fmt.Errorf("failed to connect to %s client: %v", e.chain.Name, err)
 	}
 
+	e.client = client
+
 	return client, nil
 }
 
+// parseABI registers the V3 event as SwapV3 so both Swap events fit in one ABI, logs are told
+// apart by their topic hash in retrieveEventData. Sync is parsed to track V2 reserves.
 func (e *EthereumService) parseABI() (abi.ABI, error) {
 	swapEventAbi := `
 	[
@@ -136,6 +911,74 @@ func (e *EthereumService) parseABI() (abi.ABI, error) {
 			],
 			"name": "Swap",
 			"type": "event"
+		},
+		{
+			"anonymous": false,
+			"inputs": [
+			{
+				"indexed": true,
+				"internalType": "address",
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"internalType": "address",
+				"name": "recipient",
+				"type": "address"
+			},
+			{
+				"indexed": false,
+				"internalType": "int256",
+				"name": "amount0",
+				"type": "int256"
+			},
+			{
+				"indexed": false,
+				"internalType": "int256",
+				"name": "amount1",
+				"type": "int256"
+			},
+			{
+				"indexed": false,
+				"internalType": "uint160",
+				"name": "sqrtPriceX96",
+				"type": "uint160"
+			},
+			{
+				"indexed": false,
+				"internalType": "uint128",
+				"name": "liquidity",
+				"type": "uint128"
+			},
+			{
+				"indexed": false,
+				"internalType": "int24",
+				"name": "tick",
+				"type": "int24"
+			}
+			],
+			"name": "SwapV3",
+			"type": "event"
+		},
+		{
+			"anonymous": false,
+			"inputs": [
+			{
+				"indexed": false,
+				"internalType": "uint112",
+				"name": "reserve0",
+				"type": "uint112"
+			},
+			{
+				"indexed": false,
+				"internalType": "uint112",
+				"name": "reserve1",
+				"type": "uint112"
+			}
+			],
+			"name": "Sync",
+			"type": "event"
 		}
 	]
 `
@@ -148,16 +991,20 @@ func (e *EthereumService) parseABI() (abi.ABI, error) {
 	return parsedABI, nil
 }
 
-func (e *EthereumService) subscribeToSwapEvent(client IEthereumClient) (<-chan types.Log, ethereum.Subscription, error) {
-	contractAddressHex := "0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"
-	contractAddress := common.HexToAddress(contractAddressHex)
-	eventSignature := "Swap(address,uint256,uint256,uint256,uint256,address)"
-	eventSignatureHash := crypto.Keccak256Hash([]byte(eventSignature))
+func (e *EthereumService) swapFilterQuery() ethereum.FilterQuery {
+	contractAddresses := []common.Address{}
+	for _, pool := range e.chain.Pools {
+		contractAddresses = append(contractAddresses, common.HexToAddress(pool.Address))
+	}
 
-	query := ethereum.FilterQuery{
-		Addresses: []common.Address{contractAddress},
-		Topics:    [][]common.Hash{{eventSignatureHash}},
+	return ethereum.FilterQuery{
+		Addresses: contractAddresses,
+		Topics:    [][]common.Hash{{swapV2EventSignatureHash, swapV3EventSignatureHash, syncEventSignatureHash}},
 	}
+}
+
+func (e *EthereumService) subscribeToSwapEvent(client IEthereumClient) (<-chan types.Log, ethereum.Subscription, error) {
+	query := e.swapFilterQuery()
 
 	logsCh := make(chan types.Log)
 	sub, err := client.SubscribeFilterLogs(context.Background(), query, logsCh)
@@ -171,55 +1018,365 @@ func (e *EthereumService) subscribeToSwapEvent(client IEthereumClient) (<-chan t
 func (e *EthereumService) retrieveEventData(vLog types.Log, parsedABI IABI) (*models.SwapEvent, error) {
 	event := models.SwapEvent{}
 
-	err := parsedABI.UnpackIntoInterface(&event, "Swap", vLog.Data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unpack log: %v", err)
+	if len(vLog.Topics) > 0 && vLog.Topics[0] == swapV3EventSignatureHash {
+		v3Event := swapV3Event{}
+		if err := parsedABI.UnpackIntoInterface(&v3Event, "SwapV3", vLog.Data); err != nil {
+			return nil, fmt.Errorf("failed to unpack log: %v", err)
+		}
+
+		// V3 amounts are signed from the pool's point of view: positive flows in, negative flows out
+		event.Amount0In, event.Amount0Out = splitSignedAmount(v3Event.Amount0)
+		event.Amount1In, event.Amount1Out = splitSignedAmount(v3Event.Amount1)
+	} else {
+		err := parsedABI.UnpackIntoInterface(&event, "Swap", vLog.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack log: %v", err)
+		}
 	}
 
+	event.TxHash = vLog.TxHash.Hex()
+	event.LogIndex = vLog.Index
+	event.BlockNumber = vLog.BlockNumber
+	event.PoolAddress = vLog.Address.Hex()
 	event.SenderAddress = vLog.Topics[1].Hex()[26:]
 
 	return &event, nil
 }
 
+// decodeLog decodes a swap log and credits it to the trader selected by the pool's attribution mode.
+func (e *EthereumService) decodeLog(vLog types.Log, parsedABI IABI) (*models.SwapEvent, error) {
+	event, err := e.retrieveEventData(vLog, parsedABI)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := e.findPool(event.PoolAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	event.ChainID = e.chain.ID
+
+	switch pool.Attribution {
+	case "", attributionSender:
+	case attributionRecipient:
+		// V2 "to" and V3 "recipient" are both the second indexed topic
+		if len(vLog.Topics) < 3 {
+			return nil, fmt.Errorf("swap %s:%d has no recipient topic", event.TxHash, event.LogIndex)
+		}
+
+		event.SenderAddress = vLog.Topics[2].Hex()[26:]
+	case attributionOrigin:
+		origin, err := e.transactionOrigin(vLog)
+		if err != nil {
+			return nil, err
+		}
+
+		event.SenderAddress = strings.ToLower(origin.Hex()[2:])
+	default:
+		return nil, fmt.Errorf("unknown attribution %q for pool %s", pool.Attribution, pool.Address)
+	}
+
+	return event, nil
+}
+
+// transactionOrigin returns the account that signed the transaction that emitted vLog.
+func (e *EthereumService) transactionOrigin(vLog types.Log) (common.Address, error) {
+	if origin, ok := e.originCache.Get(vLog.TxHash); ok {
+		return origin, nil
+	}
+
+	if e.client == nil {
+		return common.Address{}, fmt.Errorf("no client to look up transaction %s", vLog.TxHash.Hex())
+	}
+
+	tx, _, err := e.client.TransactionByHash(context.Background(), vLog.TxHash)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get transaction %s: %v", vLog.TxHash.Hex(), err)
+	}
+
+	origin, err := e.client.TransactionSender(context.Background(), tx, vLog.BlockHash, vLog.TxIndex)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get sender of transaction %s: %v", vLog.TxHash.Hex(), err)
+	}
+
+	e.originCache.Add(vLog.TxHash, origin)
+
+	return origin, nil
+}
+
+// blockTimestamp returns the time of the block that contains vLog.
+func (e *EthereumService) blockTimestamp(vLog types.Log) (time.Time, error) {
+	return e.blockTime(vLog.BlockNumber)
+}
+
+// blockTime returns the time of a block.
+func (e *EthereumService) blockTime(blockNumber uint64) (time.Time, error) {
+	if e.blockTimeCache != nil {
+		if timestamp, ok := e.blockTimeCache.Get(blockNumber); ok {
+			return timestamp, nil
+		}
+	}
+
+	if e.client == nil {
+		return time.Time{}, fmt.Errorf("no client to look up the time of block %d", blockNumber)
+	}
+
+	header, err := e.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get header of block %d: %v", blockNumber, err)
+	}
+
+	timestamp := time.Unix(int64(header.Time), 0).UTC()
+	e.cacheBlockTime(blockNumber, timestamp)
+
+	return timestamp, nil
+}
+
+func (e *EthereumService) cacheBlockTime(blockNumber uint64, timestamp time.Time) {
+	if e.blockTimeCache == nil {
+		e.blockTimeCache = lru.NewCache[uint64, time.Time](blockTimeCacheSize)
+	}
+
+	e.blockTimeCache.Add(blockNumber, timestamp)
+}
+
 func (e *EthereumService) processSwapEvent(event *models.SwapEvent) error {
+	credit, err := e.valueSwapEvent(event)
+	if err != nil {
+		return err
+	}
+
+	return credit()
+}
+
+// valueSwapEvent values a swap at the current pool price and returns the work that stores and
+// credits it, which may run later on a pipeline worker.
+func (e *EthereumService) valueSwapEvent(event *models.SwapEvent) (func() error, error) {
+	pool, err := e.findPool(event.PoolAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	volume, price, err := e.swapVolume(event, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error { return e.creditSwapEvent(event, pool, volume, price) }, nil
+}
+
+func (e *EthereumService) creditSwapEvent(event *models.SwapEvent, pool *config.PoolConfig, volume decimal.Decimal, price decimal.Decimal) error {
 	senderAddress := event.SenderAddress
-	e.logger.Info("Sender: %s", senderAddress)
+	e.logger.Info(fmt.Sprintf("Sender: %s, Pool: %s/%s %s", senderAddress, pool.Token0.Symbol, pool.Token1.Symbol, event.PoolAddress))
+
+	poolAddress := common.HexToAddress(event.PoolAddress).Hex()
+
+	// swaps outside every campaign are neither stored nor credited
+	tasks, err := e.campaignService.FindSharePoolTasksAt(event.ChainID, poolAddress, event.BlockTimestamp)
+	if errors.Is(err, errSwapOutsideCampaign) {
+		e.logger.Info(fmt.Sprintf("swap %s:%d rejected: %v", event.TxHash, event.LogIndex, err))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-	// Convert amounts to float for easier logging
-	amountInUSDC := new(big.Float).SetInt(event.Amount0In)
-	amountInUSDC.Quo(amountInUSDC, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(usdcDecimals), nil)))
-	e.logger.Info("Amount0In (USDC): %s", amountInUSDC.String())
+	// only credit volume for swaps seen for the first time, replays and reconnect overlaps are skipped
+	created, err := e.swapEventRepo.CreateIfNotExists(&entities.SwapEvent{
+		ChainID:         event.ChainID,
+		TxHash:          event.TxHash,
+		LogIndex:        event.LogIndex,
+		BlockNumber:     event.BlockNumber,
+		BlockTimestamp:  &event.BlockTimestamp,
+		ContractAddress: poolAddress,
+		SenderAddress:   senderAddress,
+		Amount0In:       event.Amount0In.String(),
+		Amount1In:       event.Amount1In.String(),
+		Amount0Out:      event.Amount0Out.String(),
+		Amount1Out:      event.Amount1Out.String(),
+		PriceUSD:        &price,
+		VolumeUSD:       &volume,
+		TaskIDs:         taskIDs(tasks),
+	})
+	if err != nil {
+		return err
+	}
 
-	amountOutUSDC := new(big.Float).SetInt(event.Amount0Out)
-	amountOutUSDC.Quo(amountOutUSDC, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(usdcDecimals), nil)))
-	e.logger.Info("Amount0Out (USDC): %s", amountOutUSDC.String())
+	if !created {
+		e.logger.Info(fmt.Sprintf("swap %s:%d already processed, skipping", event.TxHash, event.LogIndex))
+		return nil
+	}
 
-	amountInWETH := new(big.Float).SetInt(event.Amount1In)
-	amountInWETH.Quo(amountInWETH, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(wethDecimals), nil)))
-	e.logger.Info("Amount1In (WETH): %s", amountInWETH.String())
+	// Convert amounts to whole tokens for easier logging
+	e.logger.Info(fmt.Sprintf("Amount0In (%s): %s", pool.Token0.Symbol, toTokenAmount(event.Amount0In, pool.Token0.Decimals)))
+	e.logger.Info(fmt.Sprintf("Amount0Out (%s): %s", pool.Token0.Symbol, toTokenAmount(event.Amount0Out, pool.Token0.Decimals)))
+	e.logger.Info(fmt.Sprintf("Amount1In (%s): %s", pool.Token1.Symbol, toTokenAmount(event.Amount1In, pool.Token1.Decimals)))
+	e.logger.Info(fmt.Sprintf("Amount1Out (%s): %s", pool.Token1.Symbol, toTokenAmount(event.Amount1Out, pool.Token1.Decimals)))
+	e.logger.Info(fmt.Sprintf("Price (USD): %s, Volume (USD): %s", price, volume))
 
-	amountOutWETH := new(big.Float).SetInt(event.Amount1Out)
-	amountOutWETH.Quo(amountOutWETH, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(wethDecimals), nil)))
-	e.logger.Info("Amount1Out (WETH): %s", amountOutWETH.String())
+	for i, task := range tasks {
+		if _, err := e.campaignService.RecordUSDCSwapTotalAmount(task, event.SenderAddress, volume, event.BlockTimestamp); err != nil {
+			// a swap is only kept once its volume is recorded for every campaign, otherwise a retry would
+			// skip it as a duplicate. A failed task records nothing, the ones credited before it are
+			// reverted and the retry credits them all again.
+			for _, credited := range tasks[:i] {
+				if _, revertErr := e.campaignService.RevertUSDCSwapTotalAmount(credited, event.SenderAddress, volume, event.BlockTimestamp); revertErr != nil {
+					e.logger.Error(revertErr)
+				}
+			}
 
-	// Record the campaign data asynchronously
-	amountInUSDCFloat64, _ := amountInUSDC.Float64()
-	amountOutUSDCFloat64, _ := amountOutUSDC.Float64()
+			if _, deleteErr := e.swapEventRepo.DeleteByChainTxHashAndLogIndex(event.ChainID, event.TxHash, event.LogIndex); deleteErr != nil {
+				e.logger.Error(deleteErr)
+			}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+			return err
+		}
+	}
 
-	go func() {
-		defer wg.Done()
-		e.campaignService.RecordUSDCSwapTotalAmount(event.SenderAddress, amountInUSDCFloat64)
-	}()
+	return nil
+}
 
-	go func() {
-		defer wg.Done()
-		e.campaignService.RecordUSDCSwapTotalAmount(event.SenderAddress, amountOutUSDCFloat64)
-	}()
+func taskIDs(tasks []*entities.Task) []int64 {
+	ids := []int64{}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
 
-	wg.Wait()
+	return ids
+}
+
+// creditKey returns the key the credit of a swap log was queued with, without looking anything up
+// on chain. A transaction no longer in the origin cache was credited long ago, any key will do.
+func (e *EthereumService) creditKey(vLog types.Log) string {
+	pool, err := e.findPool(vLog.Address.Hex())
+	if err != nil {
+		return vLog.Address.Hex()
+	}
+
+	switch pool.Attribution {
+	case attributionRecipient:
+		if len(vLog.Topics) > 2 {
+			return vLog.Topics[2].Hex()[26:]
+		}
+	case attributionOrigin:
+		if e.originCache != nil {
+			if origin, ok := e.originCache.Get(vLog.TxHash); ok {
+				return strings.ToLower(origin.Hex()[2:])
+			}
+		}
+	default:
+		if len(vLog.Topics) > 1 {
+			return vLog.Topics[1].Hex()[26:]
+		}
+	}
+
+	return vLog.Address.Hex()
+}
+
+// revertSwapLog reverses the credit of a swap whose log was removed by a chain reorganization. The
+// volume, trader and tasks stored when the swap was credited are reverted, the pool price and the
+// campaigns may have changed since.
+func (e *EthereumService) revertSwapLog(vLog types.Log) error {
+	deleted, err := e.swapEventRepo.DeleteByChainTxHashAndLogIndex(e.chain.ID, vLog.TxHash.Hex(), vLog.Index)
+	if err != nil {
+		return err
+	}
+
+	// the swap was never credited
+	if deleted == nil {
+		return nil
+	}
+
+	e.logger.Warn(fmt.Sprintf("swap %s:%d removed by reorg, reverting credit for %s", deleted.TxHash, deleted.LogIndex, deleted.SenderAddress))
+
+	volume, err := e.creditedVolume(deleted)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := e.creditedTasks(deleted)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if _, err := e.campaignService.RevertUSDCSwapTotalAmount(task, deleted.SenderAddress, volume, creditedAt(deleted)); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+// creditedVolume returns the volume a swap was credited with, swaps stored before volumes were
+// recorded are valued again from their stored amounts.
+func (e *EthereumService) creditedVolume(swapEvent *entities.SwapEvent) (decimal.Decimal, error) {
+	if swapEvent.VolumeUSD != nil {
+		return *swapEvent.VolumeUSD, nil
+	}
+
+	pool, err := e.findPool(swapEvent.ContractAddress)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	event := &models.SwapEvent{PoolAddress: swapEvent.ContractAddress}
+	amounts := []**big.Int{&event.Amount0In, &event.Amount1In, &event.Amount0Out, &event.Amount1Out}
+	for i, stored := range []string{swapEvent.Amount0In, swapEvent.Amount1In, swapEvent.Amount0Out, swapEvent.Amount1Out} {
+		amount, ok := new(big.Int).SetString(stored, 10)
+		if !ok {
+			return decimal.Decimal{}, fmt.Errorf("swap %s:%d has an invalid amount %q", swapEvent.TxHash, swapEvent.LogIndex, stored)
+		}
+
+		*amounts[i] = amount
+	}
+
+	volume, _, err := e.swapVolume(event, pool)
+	return volume, err
+}
+
+// creditedTasks returns the tasks a swap was credited to. Swaps stored before their tasks were
+// recorded were credited to the tasks counting them when they were credited, a campaign starts when
+// it is created so it never counts swaps from before.
+func (e *EthereumService) creditedTasks(swapEvent *entities.SwapEvent) ([]*entities.Task, error) {
+	if len(swapEvent.TaskIDs) > 0 {
+		return e.campaignService.FindSharePoolTasksByIDs(swapEvent.TaskIDs)
+	}
+
+	return e.campaignService.FindSharePoolTasksAt(swapEvent.ChainID, common.HexToAddress(swapEvent.ContractAddress).Hex(), creditedAt(swapEvent))
+}
+
+// creditedAt returns the time that decided the period a stored swap was credited to. Swaps stored
+// before block times were recorded were credited to the period running when they were stored.
+func creditedAt(swapEvent *entities.SwapEvent) time.Time {
+	if swapEvent.BlockTimestamp != nil {
+		return *swapEvent.BlockTimestamp
+	}
+
+	return swapEvent.CreatedAt
+}
+
+func (e *EthereumService) findPool(address string) (*config.PoolConfig, error) {
+	for i := range e.chain.Pools {
+		if strings.EqualFold(e.chain.Pools[i].Address, address) {
+			return &e.chain.Pools[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("pool %s is not configured on chain %s", address, e.chain.Name)
+}
+
+// splitSignedAmount turns a signed V3 amount into the V2 style in and out amounts.
+func splitSignedAmount(amount *big.Int) (*big.Int, *big.Int) {
+	if amount.Sign() >= 0 {
+		return new(big.Int).Set(amount), big.NewInt(0)
+	}
+
+	return big.NewInt(0), new(big.Int).Neg(amount)
+}
+
+// toTokenAmount formats a raw token amount in whole tokens, without rounding.
+func toTokenAmount(amount *big.Int, decimals int64) string {
+	return decimal.TokenAmount(amount, decimals).FloatString(int(decimals))
+}
@@ -1,18 +1,42 @@
 package services
 
 import (
+	"database/sql"
 	"fmt"
 	"math/big"
 	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
 	"trading-ace/mocks"
 	"trading-ace/models"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+const testPoolAddress = "0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"
+
+func newTestPools() []config.PoolConfig {
+	return []config.PoolConfig{
+		{
+			Address: testPoolAddress,
+			Token0:  config.TokenConfig{Symbol: "USDC", Decimals: 6},
+			Token1:  config.TokenConfig{Symbol: "WETH", Decimals: 18},
+			Quote:   quoteToken0,
+		},
+	}
+}
+
+func newTestChain() *config.ChainConfig {
+	return &config.ChainConfig{ID: config.MainnetChainID, Name: "ethereum", Pools: newTestPools()}
+}
+
 func TestParseABI(t *testing.T) {
 	e := &EthereumService{}
 
@@ -25,6 +49,10 @@ func TestParseABI(t *testing.T) {
 	parsedEvent, exists := parsedABI.Events["Swap"]
 	assert.True(t, exists, "Expected 'Swap' event to be found in the ABI")
 	assert.Equal(t, 6, len(parsedEvent.Inputs), "Expected 6 inputs in 'Swap' event")
+
+	parsedV3Event, exists := parsedABI.Events["SwapV3"]
+	assert.True(t, exists, "Expected 'SwapV3' event to be found in the ABI")
+	assert.Equal(t, 7, len(parsedV3Event.Inputs), "Expected 7 inputs in 'SwapV3' event")
 }
 
 func TestSubscribeToSwapEvent(t *testing.T) {
@@ -36,7 +64,7 @@ func TestSubscribeToSwapEvent(t *testing.T) {
 	mockClient.On("SubscribeFilterLogs", mock.Anything, mock.Anything, mock.Anything).Return(mockSubscription, nil)
 
 	// 創建 EthereumService 實例
-	e := &EthereumService{}
+	e := &EthereumService{chain: newTestChain()}
 
 	// 呼叫 subscribeToSwapEvent 方法
 	logsCh, sub, err := e.subscribeToSwapEvent(mockClient)
@@ -93,6 +121,43 @@ func TestRetrieveEventData(t *testing.T) {
 	mockABI.On("UnpackIntoInterface", &models.SwapEvent{}, "Swap", vLog.Data).Return(fmt.Errorf("unpacking error"))
 }
 
+func TestRetrieveEventDataV3(t *testing.T) {
+	e := &EthereumService{}
+
+	parsedABI, err := e.parseABI()
+	assert.NoError(t, err)
+
+	// 2,000 USDC into the pool, 0.5 WETH out of it
+	data, err := parsedABI.Events["SwapV3"].Inputs.NonIndexed().Pack(
+		big.NewInt(2000000000),
+		new(big.Int).Neg(big.NewInt(500000000000000000)),
+		big.NewInt(1),
+		big.NewInt(1),
+		big.NewInt(-200000),
+	)
+	assert.NoError(t, err)
+
+	vLog := types.Log{
+		Address: common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640"),
+		Topics: []common.Hash{
+			swapV3EventSignatureHash,
+			common.HexToHash("0x00000000000000000000000068b3465833fb72a70ecdf485e0e4c7bd8665fc45"),
+			common.HexToHash("0x000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045"),
+		},
+		Data: data,
+	}
+
+	event, err := e.retrieveEventData(vLog, parsedABI)
+
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(2000000000), event.Amount0In)
+	assert.Equal(t, big.NewInt(0), event.Amount0Out)
+	assert.Equal(t, big.NewInt(0), event.Amount1In)
+	assert.Equal(t, big.NewInt(500000000000000000), event.Amount1Out)
+	assert.Equal(t, "68b3465833fb72a70ecdf485e0e4c7bd8665fc45", event.SenderAddress)
+	assert.Equal(t, vLog.Address.Hex(), event.PoolAddress)
+}
+
 func TestProcessSwapEvent(t *testing.T) {
 	// Set up mocks
 	mockLogger := new(mocks.MockLogger)
@@ -101,21 +166,43 @@ func TestProcessSwapEvent(t *testing.T) {
 	// Mock logger behavior
 	mockLogger.On("Info", mock.Anything).Return()
 
+	// the period is picked by the block time, not by the time the swap is processed
+	blockTime := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+
+	// two campaigns count the pool, each credits the swap to its own period
+	tasks := []*entities.Task{{ID: 7, Period: 2}, {ID: 9, Period: 1}}
+
 	// Mock RecordUSDCSwapTotalAmount behavior
-	mockCampaignService.On("RecordUSDCSwapTotalAmount", "0xSenderAddress", mock.Anything).Return(100.0, nil)
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", tasks[0], "0xSenderAddress", mock.Anything, blockTime).Return(decimal.NewFromInt(100), nil)
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", tasks[1], "0xSenderAddress", mock.Anything, blockTime).Return(decimal.NewFromInt(100), nil)
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, common.HexToAddress(testPoolAddress).Hex(), blockTime).Return(tasks, nil)
+
+	// the swap is stored under the share pool tasks of its block
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+	mockSwapEventRepo.On("CreateIfNotExists", mock.MatchedBy(func(swapEvent *entities.SwapEvent) bool {
+		return swapEvent.ChainID == config.MainnetChainID && assert.ObjectsAreEqual([]int64{7, 9}, swapEvent.TaskIDs) && swapEvent.BlockTimestamp.Equal(blockTime)
+	})).Return(true, nil)
 
 	// Create EthereumService instance
 	e := &EthereumService{
 		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
 		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
 	}
 
 	event := &models.SwapEvent{
-		SenderAddress: "0xSenderAddress",
-		Amount0In:     big.NewInt(10),
-		Amount0Out:    big.NewInt(10),
-		Amount1In:     big.NewInt(10),
-		Amount1Out:    big.NewInt(10),
+		ChainID:        config.MainnetChainID,
+		TxHash:         "0xTxHash",
+		LogIndex:       1,
+		BlockTimestamp: blockTime,
+		PoolAddress:    testPoolAddress,
+		SenderAddress:  "0xSenderAddress",
+		Amount0In:      big.NewInt(10),
+		Amount0Out:     big.NewInt(10),
+		Amount1In:      big.NewInt(10),
+		Amount1Out:     big.NewInt(10),
 	}
 
 	// Test processSwapEvent
@@ -127,5 +214,733 @@ func TestProcessSwapEvent(t *testing.T) {
 	mockCampaignService.AssertExpectations(t)
 
 	// Additional assertions for verifying specific behaviors
-	mockCampaignService.AssertCalled(t, "RecordUSDCSwapTotalAmount", "0xSenderAddress", mock.Anything)
+	mockCampaignService.AssertNumberOfCalls(t, "RecordUSDCSwapTotalAmount", 2)
+}
+
+func TestProcessSwapEventRevertsPartialCredit(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+
+	blockTime := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	tasks := []*entities.Task{{ID: 7, Period: 2}, {ID: 9, Period: 1}}
+
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, mock.Anything, blockTime).Return(tasks, nil)
+	mockSwapEventRepo.On("CreateIfNotExists", mock.Anything).Return(true, nil)
+
+	// the second campaign fails to record the volume
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", tasks[0], "0xSenderAddress", mock.Anything, blockTime).Return(decimal.NewFromInt(100), nil)
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", tasks[1], "0xSenderAddress", mock.Anything, blockTime).Return(decimal.Decimal{}, fmt.Errorf("%w: redis: connection refused", errSwapNotRecorded))
+
+	// the first campaign is reverted and the swap dropped, so a retry credits both again
+	mockCampaignService.On("RevertUSDCSwapTotalAmount", tasks[0], "0xSenderAddress", mock.Anything, blockTime).Return(decimal.Decimal{}, nil).Once()
+	mockSwapEventRepo.On("DeleteByChainTxHashAndLogIndex", config.MainnetChainID, "0xTxHash", uint(1)).Return(&entities.SwapEvent{}, nil).Once()
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+	}
+
+	err := e.processSwapEvent(&models.SwapEvent{
+		ChainID:        config.MainnetChainID,
+		TxHash:         "0xTxHash",
+		LogIndex:       1,
+		BlockTimestamp: blockTime,
+		PoolAddress:    testPoolAddress,
+		SenderAddress:  "0xSenderAddress",
+		Amount0In:      big.NewInt(10),
+		Amount0Out:     big.NewInt(10),
+		Amount1In:      big.NewInt(10),
+		Amount1Out:     big.NewInt(10),
+	})
+
+	assert.ErrorIs(t, err, errSwapNotRecorded)
+	mockCampaignService.AssertExpectations(t)
+	mockSwapEventRepo.AssertExpectations(t)
+}
+
+func TestProcessSwapEventSkipsDuplicates(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockCampaignService.On("FindSharePoolTasksAt", mock.Anything, mock.Anything, mock.Anything).Return([]*entities.Task{{ID: 1, Period: 1}}, nil)
+	mockSwapEventRepo.On("CreateIfNotExists", mock.MatchedBy(func(swapEvent *entities.SwapEvent) bool {
+		return swapEvent.TxHash == "0xTxHash" && swapEvent.LogIndex == 3 && swapEvent.Amount0In == "10"
+	})).Return(false, nil)
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+	}
+
+	event := &models.SwapEvent{
+		ChainID:       config.MainnetChainID,
+		TxHash:        "0xTxHash",
+		LogIndex:      3,
+		PoolAddress:   testPoolAddress,
+		SenderAddress: "0xSenderAddress",
+		Amount0In:     big.NewInt(10),
+		Amount0Out:    big.NewInt(0),
+		Amount1In:     big.NewInt(0),
+		Amount1Out:    big.NewInt(10),
+	}
+
+	err := e.processSwapEvent(event)
+
+	assert.NoError(t, err)
+	mockSwapEventRepo.AssertExpectations(t)
+	mockCampaignService.AssertNotCalled(t, "RecordUSDCSwapTotalAmount", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessSwapEventRejectsSwapsOutsideCampaign(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+
+	// e.g. a backfilled swap from before the campaign started
+	blockTime := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, mock.Anything, blockTime).Return(([]*entities.Task)(nil), fmt.Errorf("%w: 2024-10-01T00:00:00Z", errSwapOutsideCampaign))
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+	}
+
+	event := &models.SwapEvent{
+		ChainID:        config.MainnetChainID,
+		TxHash:         "0xTxHash",
+		LogIndex:       3,
+		BlockTimestamp: blockTime,
+		PoolAddress:    testPoolAddress,
+		SenderAddress:  "0xSenderAddress",
+		Amount0In:      big.NewInt(10),
+		Amount0Out:     big.NewInt(0),
+		Amount1In:      big.NewInt(0),
+		Amount1Out:     big.NewInt(10),
+	}
+
+	// the swap is neither stored nor credited, and not reported as a failure
+	err := e.processSwapEvent(event)
+
+	assert.NoError(t, err)
+	mockSwapEventRepo.AssertNotCalled(t, "CreateIfNotExists", mock.Anything)
+	mockCampaignService.AssertNotCalled(t, "RecordUSDCSwapTotalAmount", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConsumeSwapEventsBackfillsSinceLastProcessed(t *testing.T) {
+	mockClient := new(mocks.MockEthereumClient)
+	mockSubscription := new(mocks.MockEthereumSubscription)
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+
+	errCh := make(chan error, 1)
+	errCh <- fmt.Errorf("websocket closed")
+
+	mockClient.On("SubscribeFilterLogs", mock.Anything, mock.Anything, mock.Anything).Return(mockSubscription, nil)
+	mockSubscription.On("Err").Return((<-chan error)(errCh))
+	mockSubscription.On("Unsubscribe").Return()
+
+	// the first log was already processed before the disconnect, only the second one is new
+	backfilled := []types.Log{
+		{BlockNumber: 100, Index: 2, Data: []byte{0x01}},
+		{BlockNumber: 101, Index: 0, Data: []byte{0x02}},
+	}
+	// the gap is fetched in chunks up to the head
+	mockClient.On("BlockNumber", mock.Anything).Return(uint64(101), nil)
+	mockClient.On("FilterLogs", mock.Anything, mock.MatchedBy(func(query ethereum.FilterQuery) bool {
+		return query.FromBlock.Uint64() == 100 && query.ToBlock.Uint64() == 100
+	})).Return(backfilled[:1], nil)
+	mockClient.On("FilterLogs", mock.Anything, mock.MatchedBy(func(query ethereum.FilterQuery) bool {
+		return query.FromBlock.Uint64() == 101 && query.ToBlock.Uint64() == 101
+	})).Return(backfilled[1:], nil)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", []byte{0x02}).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	e := &EthereumService{
+		logger:         mockLogger,
+		config:         &config.Config{Backfill: config.BackfillConfig{ChunkSize: 1}},
+		chain:          newTestChain(),
+		checkpointRepo: mockCheckpointRepo,
+		lastProcessed:  &logPosition{BlockNumber: 100, LogIndex: 2},
+	}
+
+	subscribed, err := e.consumeSwapEvents(mockClient, mockABI)
+
+	assert.True(t, subscribed, "expected subscription to be established")
+	assert.EqualError(t, err, "websocket closed")
+	assert.Equal(t, &logPosition{BlockNumber: 101, LogIndex: 0}, e.lastProcessed)
+	mockCheckpointRepo.AssertNumberOfCalls(t, "Save", 1)
+
+	mockClient.AssertExpectations(t)
+	mockABI.AssertNumberOfCalls(t, "UnpackIntoInterface", 1)
+	mockSubscription.AssertCalled(t, "Unsubscribe")
+}
+
+func TestConsumeSwapEventsStartsAtTheHead(t *testing.T) {
+	mockClient := new(mocks.MockEthereumClient)
+	mockSubscription := new(mocks.MockEthereumSubscription)
+	mockABI := new(mocks.MockABI)
+
+	errCh := make(chan error, 2)
+	errCh <- fmt.Errorf("websocket closed")
+
+	mockClient.On("SubscribeFilterLogs", mock.Anything, mock.Anything, mock.Anything).Return(mockSubscription, nil)
+	mockSubscription.On("Err").Return((<-chan error)(errCh))
+	mockSubscription.On("Unsubscribe").Return()
+	mockClient.On("BlockNumber", mock.Anything).Return(uint64(200), nil).Once()
+
+	e := &EthereumService{
+		logger: new(mocks.MockLogger),
+		config: &config.Config{},
+		chain:  newTestChain(),
+	}
+
+	// nothing was processed yet, the head at subscribe time becomes the position
+	_, err := e.consumeSwapEvents(mockClient, mockABI)
+
+	assert.EqualError(t, err, "websocket closed")
+	assert.Equal(t, &logPosition{BlockNumber: 200, LogIndex: maxLogIndex}, e.lastProcessed)
+
+	// the reconnect backfills the swaps emitted during the outage from it
+	errCh <- fmt.Errorf("websocket closed")
+	mockClient.On("BlockNumber", mock.Anything).Return(uint64(205), nil).Once()
+	mockClient.On("FilterLogs", mock.Anything, mock.MatchedBy(func(query ethereum.FilterQuery) bool {
+		return query.FromBlock.Uint64() == 200 && query.ToBlock.Uint64() == 205
+	})).Return([]types.Log{}, nil).Once()
+
+	_, err = e.consumeSwapEvents(mockClient, mockABI)
+
+	assert.EqualError(t, err, "websocket closed")
+	mockClient.AssertExpectations(t)
+}
+
+func TestIsProcessed(t *testing.T) {
+	e := &EthereumService{}
+	assert.False(t, e.isProcessed(types.Log{BlockNumber: 1}), "expected nothing to be processed without a position")
+
+	e.lastProcessed = &logPosition{BlockNumber: 10, LogIndex: 5}
+	assert.True(t, e.isProcessed(types.Log{BlockNumber: 9, Index: 7}))
+	assert.True(t, e.isProcessed(types.Log{BlockNumber: 10, Index: 5}))
+	assert.False(t, e.isProcessed(types.Log{BlockNumber: 10, Index: 6}))
+	assert.False(t, e.isProcessed(types.Log{BlockNumber: 11, Index: 0}))
+}
+
+func TestNextReconnectBackoff(t *testing.T) {
+	assert.Equal(t, 2*time.Second, nextReconnectBackoff(time.Second))
+	assert.Equal(t, maxReconnectBackoff, nextReconnectBackoff(40*time.Second))
+}
+
+func TestBackfillRange(t *testing.T) {
+	mockClient := new(mocks.MockEthereumClient)
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+
+	rangeQuery := func(from, to uint64) interface{} {
+		return mock.MatchedBy(func(query ethereum.FilterQuery) bool {
+			return query.FromBlock.Uint64() == from && query.ToBlock.Uint64() == to
+		})
+	}
+
+	// the latest block is 112, with 3 confirmations the range ends at 109.
+	// the provider rejects the first chunk, so the range is retried with half the chunk size
+	mockClient.On("BlockNumber", mock.Anything).Return(uint64(112), nil)
+	mockClient.On("FilterLogs", mock.Anything, rangeQuery(100, 109)).Return([]types.Log{}, fmt.Errorf("query returned more than 10000 results"))
+	mockClient.On("FilterLogs", mock.Anything, rangeQuery(100, 104)).Return([]types.Log{{BlockNumber: 101, Data: []byte{0x01}}}, nil)
+	mockClient.On("FilterLogs", mock.Anything, rangeQuery(105, 109)).Return([]types.Log{{BlockNumber: 107, Data: []byte{0x02}}}, nil)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", mock.Anything).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+	mockLogger.On("Warn", mock.Anything).Return()
+	mockLogger.On("Info", mock.Anything).Return()
+
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	chain := newTestChain()
+	chain.Confirmations = 3
+
+	e := &EthereumService{
+		logger: mockLogger,
+		config: &config.Config{
+			Backfill: config.BackfillConfig{ChunkSize: 10},
+		},
+		chain:          chain,
+		checkpointRepo: mockCheckpointRepo,
+	}
+
+	err := e.backfillRange(mockClient, mockABI, 100, 0)
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+	mockABI.AssertNumberOfCalls(t, "UnpackIntoInterface", 2)
+	mockLogger.AssertNumberOfCalls(t, "Info", 2)
+	assert.Equal(t, &logPosition{BlockNumber: 107}, e.lastProcessed)
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+
+	chain := newTestChain()
+	chain.Pools = append(chain.Pools, config.PoolConfig{Address: "0x0d4a11d5EEaaC28EC3F61d100daF4d40471f1852"})
+	e := &EthereumService{
+		logger:         mockLogger,
+		chain:          chain,
+		checkpointRepo: mockCheckpointRepo,
+	}
+
+	// no checkpoint stored yet
+	mockCheckpointRepo.On("FindByChainAndContract", config.MainnetChainID, mock.Anything).
+		Return((*entities.IngestionCheckpoint)(nil), fmt.Errorf("ingestion checkpoint not found: %w", sql.ErrNoRows)).Twice()
+	mockCheckpointRepo.On("FindHead", config.MainnetChainID).Return((*entities.IngestionHead)(nil), nil).Once()
+
+	assert.NoError(t, e.resumeFromCheckpoint())
+	assert.Nil(t, e.lastProcessed)
+
+	// the pool that is furthest behind decides where ingestion resumes
+	mockCheckpointRepo.On("FindByChainAndContract", config.MainnetChainID, testPoolAddress).
+		Return(&entities.IngestionCheckpoint{BlockNumber: 200, LogIndex: 3}, nil).Once()
+	mockCheckpointRepo.On("FindByChainAndContract", config.MainnetChainID, chain.Pools[1].Address).
+		Return(&entities.IngestionCheckpoint{BlockNumber: 198, LogIndex: 9}, nil).Once()
+	mockCheckpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockNumber: 150}, nil).Once()
+
+	assert.NoError(t, e.resumeFromCheckpoint())
+	assert.Equal(t, &logPosition{BlockNumber: 198, LogIndex: 9}, e.lastProcessed)
+
+	// a quiet pool does not hold ingestion back once the safe head of the chain is past its checkpoint
+	mockCheckpointRepo.On("FindByChainAndContract", config.MainnetChainID, testPoolAddress).
+		Return(&entities.IngestionCheckpoint{BlockNumber: 5000, LogIndex: 3}, nil).Once()
+	mockCheckpointRepo.On("FindByChainAndContract", config.MainnetChainID, chain.Pools[1].Address).
+		Return(&entities.IngestionCheckpoint{BlockNumber: 198, LogIndex: 9}, nil).Once()
+	mockCheckpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockNumber: 4990}, nil).Once()
+
+	assert.NoError(t, e.resumeFromCheckpoint())
+	assert.Equal(t, &logPosition{BlockNumber: 4990, LogIndex: maxLogIndex}, e.lastProcessed)
+
+	// a database error is not taken for a missing checkpoint, the position is kept
+	mockCheckpointRepo.On("FindByChainAndContract", config.MainnetChainID, testPoolAddress).
+		Return((*entities.IngestionCheckpoint)(nil), fmt.Errorf("failed to find ingestion checkpoint: %w", sql.ErrConnDone)).Once()
+
+	assert.Error(t, e.resumeFromCheckpoint())
+	assert.Equal(t, &logPosition{BlockNumber: 4990, LogIndex: maxLogIndex}, e.lastProcessed)
+
+	mockCheckpointRepo.AssertExpectations(t)
+}
+
+func TestReleaseConfirmedLogs(t *testing.T) {
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", mock.Anything).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	chain := newTestChain()
+	chain.Confirmations = 5
+
+	e := &EthereumService{
+		logger:         mockLogger,
+		chain:          chain,
+		checkpointRepo: mockCheckpointRepo,
+	}
+
+	e.handleLog(types.Log{BlockNumber: 100, Index: 0}, mockABI)
+	e.handleLog(types.Log{BlockNumber: 103, Index: 0}, mockABI)
+
+	// nothing is credited before it has enough confirmations
+	mockABI.AssertNotCalled(t, "UnpackIntoInterface", mock.Anything, mock.Anything, mock.Anything)
+	assert.Len(t, e.pendingLogs, 2)
+
+	e.releaseConfirmedLogs(106, mockABI)
+
+	mockABI.AssertNumberOfCalls(t, "UnpackIntoInterface", 1)
+	assert.Equal(t, []types.Log{{BlockNumber: 103, Index: 0}}, e.pendingLogs)
+	assert.Equal(t, &logPosition{BlockNumber: 100, LogIndex: 0}, e.lastProcessed)
+}
+
+func TestRollbackLog(t *testing.T) {
+	txHash := common.HexToHash("0x01")
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	chain := newTestChain()
+	chain.Confirmations = 5
+	// the transaction of a removed log cannot be looked up, the revert must not need it
+	chain.Pools[0].Attribution = attributionOrigin
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           chain,
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+		checkpointRepo:  mockCheckpointRepo,
+		lastProcessed:   &logPosition{BlockNumber: 92, LogIndex: 4},
+	}
+
+	// a pending log is simply discarded
+	e.handleLog(types.Log{TxHash: txHash, BlockNumber: 100, Index: 1}, mockABI)
+	e.handleLog(types.Log{TxHash: txHash, BlockNumber: 100, Index: 1, Removed: true}, mockABI)
+
+	assert.Empty(t, e.pendingLogs)
+	mockSwapEventRepo.AssertNotCalled(t, "DeleteByChainTxHashAndLogIndex", mock.Anything, mock.Anything, mock.Anything)
+	mockCheckpointRepo.AssertNotCalled(t, "Rewind", mock.Anything)
+	assert.Equal(t, &logPosition{BlockNumber: 92, LogIndex: 4}, e.lastProcessed)
+
+	// an already credited log gets its credit reversed from the stored swap: the volume recorded at
+	// credit time is taken back from the trader and the tasks it was credited to
+	recordedVolume := decimal.RequireFromString("2.5")
+	blockTime := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	mockSwapEventRepo.On("DeleteByChainTxHashAndLogIndex", config.MainnetChainID, txHash.Hex(), uint(2)).Return(&entities.SwapEvent{
+		ChainID:         config.MainnetChainID,
+		TxHash:          txHash.Hex(),
+		LogIndex:        2,
+		ContractAddress: common.HexToAddress(testPoolAddress).Hex(),
+		SenderAddress:   "origin",
+		VolumeUSD:       &recordedVolume,
+		BlockTimestamp:  &blockTime,
+		TaskIDs:         []int64{7},
+	}, nil)
+	mockLogger.On("Warn", mock.Anything).Return()
+	task := &entities.Task{ID: 7, Period: 2}
+	mockCampaignService.On("FindSharePoolTasksByIDs", []int64{7}).Return([]*entities.Task{task}, nil)
+	mockCampaignService.On("RevertUSDCSwapTotalAmount", task, "origin", recordedVolume, blockTime).Return(decimal.Decimal{}, nil).Once()
+
+	// the position and the pool's checkpoint move back before the removed block
+	mockCheckpointRepo.On("Rewind", &entities.IngestionCheckpoint{
+		ChainID:         config.MainnetChainID,
+		Chain:           chain.Name,
+		ContractAddress: common.HexToAddress(testPoolAddress).Hex(),
+		BlockNumber:     89,
+		LogIndex:        maxLogIndex,
+	}).Return(nil).Once()
+
+	e.handleLog(types.Log{
+		Address:     common.HexToAddress(testPoolAddress),
+		TxHash:      txHash,
+		BlockNumber: 90,
+		Index:       2,
+		Removed:     true,
+		Topics:      []common.Hash{{}, common.HexToHash("0xSenderAddress")},
+	}, mockABI)
+
+	mockSwapEventRepo.AssertExpectations(t)
+	mockCampaignService.AssertExpectations(t)
+	mockCheckpointRepo.AssertExpectations(t)
+	mockABI.AssertNotCalled(t, "UnpackIntoInterface", mock.Anything, mock.Anything, mock.Anything)
+
+	// the logs of the replacement block are processed, those at lower positions included
+	assert.Equal(t, &logPosition{BlockNumber: 89, LogIndex: maxLogIndex}, e.lastProcessed)
+	assert.False(t, e.isProcessed(types.Log{BlockNumber: 90, Index: 0}))
+}
+
+func TestRevertLegacySwapLog(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+
+	mockLogger.On("Warn", mock.Anything).Return()
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+	}
+
+	// a swap stored before volumes and tasks were recorded is valued again from its stored amounts
+	// and taken back from the tasks counting it when it was stored
+	txHash := common.HexToHash("0x02")
+	storedAt := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	mockSwapEventRepo.On("DeleteByChainTxHashAndLogIndex", config.MainnetChainID, txHash.Hex(), uint(3)).Return(&entities.SwapEvent{
+		ChainID:         config.MainnetChainID,
+		TxHash:          txHash.Hex(),
+		LogIndex:        3,
+		ContractAddress: common.HexToAddress(testPoolAddress).Hex(),
+		SenderAddress:   "sender",
+		Amount0In:       "2000000",
+		Amount1In:       "0",
+		Amount0Out:      "0",
+		Amount1Out:      "1000000000000000",
+		CreatedAt:       storedAt,
+	}, nil)
+	task := &entities.Task{ID: 7, Period: 2}
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, common.HexToAddress(testPoolAddress).Hex(), storedAt).Return([]*entities.Task{task}, nil)
+	mockCampaignService.On("RevertUSDCSwapTotalAmount", task, "sender", decimal.NewFromInt(2), storedAt).Return(decimal.Decimal{}, nil).Once()
+
+	err := e.revertSwapLog(types.Log{Address: common.HexToAddress(testPoolAddress), TxHash: txHash, Index: 3})
+
+	assert.NoError(t, err)
+	mockCampaignService.AssertExpectations(t)
+}
+
+func TestProcessSwapEventQuoteToken1(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockSwapEventRepo.On("CreateIfNotExists", mock.MatchedBy(func(swapEvent *entities.SwapEvent) bool {
+		return swapEvent.ContractAddress == "0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB11"
+	})).Return(true, nil)
+
+	// WETH/USDT style pool where the stablecoin is token1, the swap is credited once
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", mock.Anything, "0xSenderAddress", decimal.NewFromInt(3), mock.Anything).Return(decimal.NewFromInt(3), nil).Once()
+	mockCampaignService.On("FindSharePoolTasksAt", mock.Anything, mock.Anything, mock.Anything).Return([]*entities.Task{{ID: 1, Period: 1}}, nil)
+
+	e := &EthereumService{
+		logger: mockLogger,
+		config: &config.Config{},
+		chain: &config.ChainConfig{ID: config.MainnetChainID, Pools: []config.PoolConfig{
+			{
+				Address: "0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB11",
+				Token0:  config.TokenConfig{Symbol: "WETH", Decimals: 18},
+				Token1:  config.TokenConfig{Symbol: "USDT", Decimals: 6},
+				Quote:   quoteToken1,
+			},
+		}},
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+	}
+
+	event := &models.SwapEvent{
+		ChainID:       config.MainnetChainID,
+		PoolAddress:   "0xa478c2975ab1ea89e8196811f51a7b7ade33eb11",
+		SenderAddress: "0xSenderAddress",
+		Amount0In:     big.NewInt(0),
+		Amount0Out:    big.NewInt(1000000000000000),
+		Amount1In:     big.NewInt(3000000),
+		Amount1Out:    big.NewInt(0),
+	}
+
+	err := e.processSwapEvent(event)
+
+	assert.NoError(t, err)
+	mockCampaignService.AssertExpectations(t)
+
+	// unknown pools are rejected
+	event.PoolAddress = "0x0000000000000000000000000000000000000001"
+	assert.Error(t, e.processSwapEvent(event))
+}
+
+func TestPollSwapEvents(t *testing.T) {
+	mockClient := new(mocks.MockEthereumClient)
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	// head is 120, with 5 confirmations blocks 110 to 115 are safe to process
+	mockClient.On("BlockNumber", mock.Anything).Return(uint64(120), nil).Once()
+	mockClient.On("BlockNumber", mock.Anything).Return(uint64(0), fmt.Errorf("connection refused")).Once()
+	mockClient.On("FilterLogs", mock.Anything, mock.MatchedBy(func(query ethereum.FilterQuery) bool {
+		return query.FromBlock.Uint64() == 110 && query.ToBlock.Uint64() == 115
+	})).Return([]types.Log{
+		{BlockNumber: 110, Index: 2, Data: []byte{0x01}},
+		{BlockNumber: 112, Index: 0, Data: []byte{0x02}},
+	}, nil)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", []byte{0x02}).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	chain := newTestChain()
+	chain.Confirmations = 5
+	chain.PollInterval = time.Millisecond
+
+	e := &EthereumService{
+		logger:         mockLogger,
+		config:         &config.Config{},
+		chain:          chain,
+		checkpointRepo: mockCheckpointRepo,
+		lastProcessed:  &logPosition{BlockNumber: 110, LogIndex: 2},
+	}
+
+	// once the logs up to the safe head are handed on, the chain is ingested up to its block time
+	safeHeadTime := time.Date(2024, 12, 12, 12, 0, 0, 0, time.UTC)
+	e.cacheBlockTime(115, safeHeadTime)
+	mockCheckpointRepo.On("SaveHead", &entities.IngestionHead{
+		ChainID:        config.MainnetChainID,
+		Chain:          chain.Name,
+		BlockNumber:    115,
+		BlockTimestamp: safeHeadTime,
+	}).Return(nil).Once()
+
+	reachable, err := e.pollSwapEvents(mockClient, mockABI)
+
+	assert.True(t, reachable)
+	assert.Error(t, err)
+	assert.Equal(t, &logPosition{BlockNumber: 112, LogIndex: 0}, e.lastProcessed)
+	mockCheckpointRepo.AssertExpectations(t)
+	mockClient.AssertExpectations(t)
+	mockABI.AssertNumberOfCalls(t, "UnpackIntoInterface", 1)
+}
+
+func TestRPCURL(t *testing.T) {
+	e := &EthereumService{
+		config: &config.Config{Infura: config.InfuraConfig{Key: "key"}},
+		chain:  newTestChain(),
+	}
+	assert.Equal(t, "wss://mainnet.infura.io/ws/v3/key", e.rpcURL())
+	assert.True(t, isWebsocketURL(e.rpcURL()))
+
+	e.chain.RPCURL = "http://localhost:8545"
+	assert.Equal(t, "http://localhost:8545", e.rpcURL())
+	assert.False(t, isWebsocketURL(e.rpcURL()))
+
+	// the Infura fallback is mainnet only
+	e.chain = &config.ChainConfig{ID: 42161, Name: "arbitrum"}
+	assert.Equal(t, "", e.rpcURL())
+
+	_, err := e.connectToClient()
+	assert.EqualError(t, err, "no rpc_url configured for chain arbitrum")
+}
+
+func TestEthereumServiceChains(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+
+	// the legacy single chain config runs as mainnet
+	e := NewEthereumService(mockLogger, &config.Config{Pools: newTestPools()}, nil, nil, nil, nil, nil).(*EthereumService)
+	assert.NoError(t, e.validateChains())
+	assert.Len(t, e.chains, 1)
+
+	chain, err := e.findChain(config.MainnetChainID)
+	assert.NoError(t, err)
+	assert.Equal(t, "ethereum", chain.chain.Name)
+	assert.Equal(t, e.pipeline, chain.pipeline)
+
+	_, err = e.findChain(42161)
+	assert.EqualError(t, err, "chain 42161 is not configured")
+
+	e = NewEthereumService(mockLogger, &config.Config{Chains: []config.ChainConfig{
+		{ID: config.MainnetChainID, Pools: newTestPools()},
+		{ID: 42161, Name: "arbitrum", Pools: newTestPools()},
+	}}, nil, nil, nil, nil, nil).(*EthereumService)
+	assert.NoError(t, e.validateChains())
+
+	// every chain keeps its own caches
+	assert.NotSame(t, e.chains[0].blockTimeCache, e.chains[1].blockTimeCache)
+	assert.Equal(t, "chain-1", e.chains[0].chain.Name)
+
+	e = NewEthereumService(mockLogger, &config.Config{Chains: []config.ChainConfig{
+		{ID: 42161, Name: "arbitrum", Pools: newTestPools()},
+		{ID: 42161, Name: "arbitrum-2", Pools: newTestPools()},
+	}}, nil, nil, nil, nil, nil).(*EthereumService)
+	assert.EqualError(t, e.validateChains(), "chain id 42161 is configured twice")
+
+	e = NewEthereumService(mockLogger, &config.Config{Chains: []config.ChainConfig{
+		{ID: 8453, Name: "base"},
+	}}, nil, nil, nil, nil, nil).(*EthereumService)
+	assert.EqualError(t, e.validateChains(), "no pools configured for chain base")
+
+	e = NewEthereumService(mockLogger, &config.Config{Chains: []config.ChainConfig{
+		{Name: "base", Pools: newTestPools()},
+	}}, nil, nil, nil, nil, nil).(*EthereumService)
+	assert.EqualError(t, e.validateChains(), "chain base has no chain id")
+}
+
+func TestDecodeLogAttribution(t *testing.T) {
+	mockABI := new(mocks.MockABI)
+	mockClient := new(mocks.MockEthereumClient)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", mock.Anything).Return(nil)
+
+	router := common.HexToHash("0x0000000000000000000000007a250d5630b4cf539739df2c5dacb4c659f2488d")
+	recipient := common.HexToHash("0x000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045")
+	vLog := types.Log{
+		Address: common.HexToAddress(testPoolAddress),
+		Topics:  []common.Hash{swapV2EventSignatureHash, router, recipient},
+		TxHash:  common.HexToHash("0x01"),
+		TxIndex: 4,
+	}
+
+	chain := newTestChain()
+	pools := chain.Pools
+	e := &EthereumService{
+		chain:       chain,
+		client:      mockClient,
+		originCache: lru.NewCache[common.Hash, common.Address](10),
+	}
+
+	// sender is the default
+	event, err := e.decodeLog(vLog, mockABI)
+	assert.NoError(t, err)
+	assert.Equal(t, "7a250d5630b4cf539739df2c5dacb4c659f2488d", event.SenderAddress)
+
+	pools[0].Attribution = attributionRecipient
+	event, err = e.decodeLog(vLog, mockABI)
+	assert.NoError(t, err)
+	assert.Equal(t, "d8da6bf26964af9d7eed9e03e53415d37aa96045", event.SenderAddress)
+
+	// the transaction sender is looked up once and then served from the cache
+	tx := types.NewTx(&types.LegacyTx{})
+	origin := common.HexToAddress("0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B")
+	mockClient.On("TransactionByHash", mock.Anything, vLog.TxHash).Return(tx, false, nil).Once()
+	mockClient.On("TransactionSender", mock.Anything, tx, vLog.BlockHash, uint(4)).Return(origin, nil).Once()
+
+	pools[0].Attribution = attributionOrigin
+	for i := 0; i < 2; i++ {
+		event, err = e.decodeLog(vLog, mockABI)
+		assert.NoError(t, err)
+		assert.Equal(t, "ab5801a7d398351b8be11c439e05c5b3259aec9b", event.SenderAddress)
+	}
+
+	mockClient.AssertExpectations(t)
+
+	pools[0].Attribution = "unknown"
+	_, err = e.decodeLog(vLog, mockABI)
+	assert.Error(t, err)
+}
+
+func TestBlockTimestamp(t *testing.T) {
+	mockClient := new(mocks.MockEthereumClient)
+
+	e := &EthereumService{}
+
+	// without a client there is nothing to look up
+	_, err := e.blockTimestamp(types.Log{BlockNumber: 100})
+	assert.Error(t, err)
+
+	e.client = mockClient
+
+	// the header is fetched once per block and then served from the cache
+	mockClient.On("HeaderByNumber", mock.Anything, big.NewInt(100)).Return(&types.Header{Time: 1733227200}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		timestamp, err := e.blockTimestamp(types.Log{BlockNumber: 100, Index: uint(i)})
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC), timestamp)
+	}
+
+	// without the block time the swap cannot be assigned to a period
+	mockClient.On("HeaderByNumber", mock.Anything, big.NewInt(101)).Return((*types.Header)(nil), fmt.Errorf("header not found")).Once()
+
+	_, err = e.blockTimestamp(types.Log{BlockNumber: 101})
+	assert.Error(t, err)
+	mockClient.AssertExpectations(t)
 }
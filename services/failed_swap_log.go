@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"trading-ace/entities"
+	"trading-ace/models"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// stages a swap log can fail at
+const failedStageDecode string = "decode"
+const failedStageCredit string = "credit"
+
+// deadLetter stores a swap log that failed to decode or credit together with the raw log, so it can
+// be retried once the cause is fixed instead of its volume being lost.
+func (e *EthereumService) deadLetter(vLog types.Log, stage string, blockTimestamp *time.Time, cause error) {
+	if e.failedSwapLogRepo == nil {
+		return
+	}
+
+	payload, err := json.Marshal(vLog)
+	if err != nil {
+		e.logger.Error(fmt.Errorf("failed to encode failed swap log %s:%d: %w", vLog.TxHash.Hex(), vLog.Index, err))
+		return
+	}
+
+	failedLog := &entities.FailedSwapLog{
+		ChainID:         e.chain.ID,
+		TxHash:          vLog.TxHash.Hex(),
+		LogIndex:        vLog.Index,
+		BlockNumber:     vLog.BlockNumber,
+		BlockTimestamp:  blockTimestamp,
+		ContractAddress: vLog.Address.Hex(),
+		Payload:         string(payload),
+		Stage:           stage,
+		Error:           cause.Error(),
+	}
+
+	if err := e.failedSwapLogRepo.Record(failedLog); err != nil {
+		e.logger.Error(err)
+	}
+}
+
+// deadLetterOnFailure wraps the credit of a decoded swap so a failed credit is stored for retrying.
+func (e *EthereumService) deadLetterOnFailure(vLog types.Log, event *models.SwapEvent, credit func() error) func() error {
+	return func() error {
+		err := credit()
+		if err != nil {
+			e.deadLetter(vLog, failedStageCredit, &event.BlockTimestamp, err)
+		}
+
+		return err
+	}
+}
+
+func (e *EthereumService) GetFailedSwapLogs(page int, pageSize int) ([]*entities.FailedSwapLog, int, error) {
+	return e.failedSwapLogRepo.List(pageSize, (page-1)*pageSize)
+}
+
+// RetryFailedSwapLog runs a failed log through decoding and crediting again. The log is removed
+// once it is credited, or found to be credited already, and counts one more attempt otherwise.
+// The retry runs apart from the live ingestion loop: it has its own client and caches, and values
+// the swap at its own execution price as the pool price at the time of the swap is gone.
+func (e *EthereumService) RetryFailedSwapLog(id int64) error {
+	failedLog, err := e.failedSwapLogRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	chain, err := e.findChain(failedLog.ChainID)
+	if err != nil {
+		return err
+	}
+
+	var vLog types.Log
+	if err := json.Unmarshal([]byte(failedLog.Payload), &vLog); err != nil {
+		return fmt.Errorf("failed to decode payload of failed swap log %d: %w", id, err)
+	}
+
+	parsedABI, err := e.parseABI()
+	if err != nil {
+		return err
+	}
+
+	retrier := e.newChainService(*chain.chain)
+	if failedLog.BlockTimestamp != nil {
+		retrier.cacheBlockTime(vLog.BlockNumber, *failedLog.BlockTimestamp)
+	}
+
+	// without a client the retry still succeeds when no lookup is needed
+	client, err := retrier.connectToClient()
+	if err != nil {
+		e.logger.Warn(fmt.Sprintf("retrying failed swap log %d without a client: %v", id, err))
+	} else {
+		defer client.Close()
+	}
+
+	if err := retrier.resolvePools(client); err != nil {
+		return fmt.Errorf("retry of failed swap log %d failed: %w", id, err)
+	}
+
+	event, credit, err := retrier.prepareSwapLog(vLog, parsedABI)
+	if err != nil {
+		retrier.deadLetter(vLog, failedStageDecode, failedLog.BlockTimestamp, err)
+		return fmt.Errorf("retry of failed swap log %d failed: %w", id, err)
+	}
+
+	if err := credit(); err != nil {
+		retrier.deadLetter(vLog, failedStageCredit, &event.BlockTimestamp, err)
+		return fmt.Errorf("retry of failed swap log %d failed: %w", id, err)
+	}
+
+	e.logger.Info(fmt.Sprintf("failed swap log %d (%s:%d) credited on retry", id, failedLog.TxHash, failedLog.LogIndex))
+
+	return e.failedSwapLogRepo.Delete(id)
+}
+
+// DiscardFailedSwapLog drops a failed log for good, its swap is never credited.
+func (e *EthereumService) DiscardFailedSwapLog(id int64) error {
+	failedLog, err := e.failedSwapLogRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	e.logger.Warn(fmt.Sprintf("discarding failed swap log %d (%s:%d): %s", id, failedLog.TxHash, failedLog.LogIndex, failedLog.Error))
+
+	return e.failedSwapLogRepo.Delete(id)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
+	"trading-ace/mocks"
+	"trading-ace/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProcessLogDeadLettersUndecodableSwaps(t *testing.T) {
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+	mockFailedLogRepo := new(mocks.MockFailedSwapLogRepository)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", mock.Anything).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	vLog := types.Log{
+		Address:     common.HexToAddress(testPoolAddress),
+		Topics:      []common.Hash{swapV2EventSignatureHash, common.HexToHash("0x01")},
+		Data:        []byte{0x01},
+		BlockNumber: 100,
+		TxHash:      common.HexToHash("0xabc"),
+		Index:       3,
+	}
+
+	mockFailedLogRepo.On("Record", mock.MatchedBy(func(failedLog *entities.FailedSwapLog) bool {
+		var payload types.Log
+		if err := json.Unmarshal([]byte(failedLog.Payload), &payload); err != nil {
+			return false
+		}
+
+		return failedLog.ChainID == config.MainnetChainID && failedLog.TxHash == vLog.TxHash.Hex() && failedLog.LogIndex == 3 &&
+			failedLog.BlockNumber == 100 && failedLog.BlockTimestamp == nil && failedLog.Stage == failedStageDecode &&
+			failedLog.Error == "failed to unpack log: unpacking error" && payload.Index == 3 && payload.Data[0] == 0x01
+	})).Return(nil).Once()
+
+	e := &EthereumService{
+		logger:            mockLogger,
+		config:            &config.Config{},
+		chain:             newTestChain(),
+		checkpointRepo:    mockCheckpointRepo,
+		failedSwapLogRepo: mockFailedLogRepo,
+	}
+
+	e.processLog(vLog, mockABI)
+
+	// the failed log does not hold ingestion up
+	mockFailedLogRepo.AssertExpectations(t)
+	mockCheckpointRepo.AssertNumberOfCalls(t, "Save", 1)
+	assert.Equal(t, &logPosition{BlockNumber: 100, LogIndex: 3}, e.lastProcessed)
+}
+
+func TestCreditSwapEventDropsFailedSwaps(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+
+	blockTime := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, mock.Anything, blockTime).Return([]*entities.Task{{ID: 7, Period: 2}}, nil)
+	mockSwapEventRepo.On("CreateIfNotExists", mock.Anything).Return(true, nil)
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+	}
+
+	event := &models.SwapEvent{
+		ChainID:        config.MainnetChainID,
+		TxHash:         "0xTxHash",
+		LogIndex:       1,
+		BlockTimestamp: blockTime,
+		PoolAddress:    testPoolAddress,
+		SenderAddress:  "0xSenderAddress",
+		Amount0In:      big.NewInt(3000000000),
+		Amount0Out:     big.NewInt(0),
+		Amount1In:      big.NewInt(0),
+		Amount1Out:     big.NewInt(1e18),
+	}
+
+	// the volume never reached Redis, the stored swap is removed so a retry credits it
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", mock.Anything, "0xSenderAddress", mock.Anything, blockTime).
+		Return(decimal.Decimal{}, fmt.Errorf("%w: redis: connection refused", errSwapNotRecorded)).Once()
+	mockSwapEventRepo.On("DeleteByChainTxHashAndLogIndex", config.MainnetChainID, "0xTxHash", uint(1)).Return(&entities.SwapEvent{ID: 1}, nil).Once()
+
+	assert.ErrorIs(t, e.processSwapEvent(event), errSwapNotRecorded)
+	mockSwapEventRepo.AssertExpectations(t)
+
+	// any other failure is dropped the same way, so the retry does not skip the swap as a duplicate
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", mock.Anything, "0xSenderAddress", mock.Anything, blockTime).
+		Return(decimal.Decimal{}, fmt.Errorf("failed to find onboarding task")).Once()
+	mockSwapEventRepo.On("DeleteByChainTxHashAndLogIndex", config.MainnetChainID, "0xTxHash", uint(1)).Return(&entities.SwapEvent{ID: 1}, nil).Once()
+
+	assert.Error(t, e.processSwapEvent(event))
+	mockSwapEventRepo.AssertNumberOfCalls(t, "DeleteByChainTxHashAndLogIndex", 2)
+}
+
+func TestRetryFailedSwapLogOfUnknownChain(t *testing.T) {
+	mockFailedLogRepo := new(mocks.MockFailedSwapLogRepository)
+	mockFailedLogRepo.On("FindByID", int64(3)).Return(&entities.FailedSwapLog{ID: 3, ChainID: 42161, Payload: "{}"}, nil)
+
+	e := NewEthereumService(new(mocks.MockLogger), &config.Config{Pools: newTestPools()}, nil, nil, nil, mockFailedLogRepo, nil).(*EthereumService)
+
+	assert.EqualError(t, e.RetryFailedSwapLog(3), "chain 42161 is not configured")
+	mockFailedLogRepo.AssertNotCalled(t, "Record", mock.Anything)
+	mockFailedLogRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestDiscardFailedSwapLog(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockFailedLogRepo := new(mocks.MockFailedSwapLogRepository)
+
+	mockLogger.On("Warn", mock.Anything).Return()
+	mockFailedLogRepo.On("FindByID", int64(3)).Return(&entities.FailedSwapLog{ID: 3, TxHash: "0xabc", Error: "failed to unpack log"}, nil)
+	mockFailedLogRepo.On("Delete", int64(3)).Return(nil)
+	mockFailedLogRepo.On("FindByID", int64(4)).Return((*entities.FailedSwapLog)(nil), fmt.Errorf("failed swap log not found"))
+
+	e := &EthereumService{logger: mockLogger, failedSwapLogRepo: mockFailedLogRepo}
+
+	assert.NoError(t, e.DiscardFailedSwapLog(3))
+	assert.Error(t, e.DiscardFailedSwapLog(4))
+
+	mockFailedLogRepo.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestGetFailedSwapLogs(t *testing.T) {
+	mockFailedLogRepo := new(mocks.MockFailedSwapLogRepository)
+	mockFailedLogRepo.On("List", 20, 40).Return([]*entities.FailedSwapLog{{ID: 41}}, 41, nil)
+
+	e := &EthereumService{failedSwapLogRepo: mockFailedLogRepo}
+
+	failedLogs, total, err := e.GetFailedSwapLogs(3, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 41, total)
+	assert.Equal(t, int64(41), failedLogs[0].ID)
+}
@@ -0,0 +1,590 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
+	"trading-ace/helpers"
+	"trading-ace/mocks"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// The integration tests run swaps on a simulated chain through the real ingestion path, from the
+// log subscription to the credited volume, against in-memory stand-ins for Redis and Postgres.
+
+// integrationCampaignStart lies ahead of the wall clock, so the simulated chains mine their blocks
+// at fixed times.
+var integrationCampaignStart = time.Date(2100, 11, 1, 0, 0, 0, 0, time.UTC)
+
+type ingestionHarness struct {
+	t     *testing.T
+	chain *simulatedChain
+
+	logger          *mocks.MockLogger
+	redisHelper     *memoryRedisHelper
+	taskRepo        *memoryTaskRepository
+	taskHistoryRepo *memoryTaskHistoryRepository
+	swapEventRepo   *memorySwapEventRepository
+	checkpointRepo  *memoryCheckpointRepository
+	failedLogRepo   *memoryFailedSwapLogRepository
+	tokenRepo       *memoryTokenRepository
+	campaignRepo    *memoryCampaignRepository
+	campaignService ICampaignService
+
+	// share pool tasks by period
+	sharePoolTasks map[int]*entities.Task
+	onboardingTask *entities.Task
+}
+
+// newIngestionHarness seeds a campaign of four weekly periods starting at integrationCampaignStart.
+func newIngestionHarness(t *testing.T, chain *simulatedChain) *ingestionHarness {
+	h := &ingestionHarness{
+		t:               t,
+		chain:           chain,
+		logger:          new(mocks.MockLogger),
+		redisHelper:     newMemoryRedisHelper(),
+		taskRepo:        &memoryTaskRepository{},
+		taskHistoryRepo: &memoryTaskHistoryRepository{},
+		checkpointRepo:  &memoryCheckpointRepository{},
+		failedLogRepo:   &memoryFailedSwapLogRepository{},
+		tokenRepo:       &memoryTokenRepository{},
+		campaignRepo:    &memoryCampaignRepository{},
+		sharePoolTasks:  map[int]*entities.Task{},
+	}
+	h.swapEventRepo = &memorySwapEventRepository{tasks: h.taskRepo}
+
+	h.logger.On("Info", mock.Anything).Return()
+	h.logger.On("Warn", mock.Anything).Return()
+	h.logger.On("Error", mock.Anything).Return()
+
+	onboardingEnd := integrationCampaignStart.Add(28 * 24 * time.Hour)
+	onboardingTask, err := h.taskRepo.Create(&entities.Task{
+		Name:         OnboardingTaskStr,
+		Points:       OnboardingTaskPoints,
+		TargetAmount: OnboardingTaskTargetAmount,
+		StartedAt:    &integrationCampaignStart,
+		EndAt:        &onboardingEnd,
+		Period:       1,
+	})
+	assert.NoError(t, err)
+	h.onboardingTask = onboardingTask
+
+	for _, task := range newSharePoolTasks(integrationCampaignStart) {
+		task.Points = SharePoolTaskPoints
+
+		created, err := h.taskRepo.Create(task)
+		assert.NoError(t, err)
+		h.sharePoolTasks[created.Period] = created
+	}
+
+	h.campaignService = NewCampaignService(&config.Config{}, h.logger, h.taskHistoryRepo, h.taskRepo, h.redisHelper, h.swapEventRepo, h.campaignRepo, nil, nil, helpers.NewRedisLocker(h.redisHelper))
+
+	return h
+}
+
+// ingestedChain is a simulated chain and the config the service ingests it with.
+type ingestedChain struct {
+	config config.ChainConfig
+	client *simulatedChain
+}
+
+// newService returns an ethereum service reading the harness chain as mainnet, sharing the harness stores.
+func (h *ingestionHarness) newService(pools []config.PoolConfig) *EthereumService {
+	return h.newMultiChainService(ingestedChain{
+		// only used by retries, which connect on their own: http clients connect on the first request,
+		// and the retried swaps need no lookups
+		config: config.ChainConfig{ID: config.MainnetChainID, Name: "ethereum", RPCURL: "http://127.0.0.1:1", Pools: pools},
+		client: h.chain,
+	})
+}
+
+// newMultiChainService returns an ethereum service reading every given chain, sharing the harness stores.
+func (h *ingestionHarness) newMultiChainService(chains ...ingestedChain) *EthereumService {
+	cfg := &config.Config{Pipeline: config.PipelineConfig{Workers: 2, QueueSize: 10}}
+	clients := map[int64]*simulatedChain{}
+	for _, chain := range chains {
+		cfg.Chains = append(cfg.Chains, chain.config)
+		clients[chain.config.ID] = chain.client
+	}
+
+	e := NewEthereumService(
+		h.logger,
+		cfg,
+		h.campaignService,
+		h.checkpointRepo,
+		h.swapEventRepo,
+		h.failedLogRepo,
+		NewTokenRegistry(h.logger, h.tokenRepo, h.redisHelper),
+	).(*EthereumService)
+
+	// set by connectToClient for a real node
+	for _, chain := range e.chains {
+		chain.client = clients[chain.chain.ID]
+	}
+
+	return e
+}
+
+// start resumes every chain from its stored checkpoints and consumes their logs until stop is called.
+func (h *ingestionHarness) start(e *EthereumService) func() {
+	parsedABI, err := e.parseABI()
+	assert.NoError(h.t, err)
+
+	done := make(chan error, len(e.chains))
+	var wg sync.WaitGroup
+	for _, chain := range e.chains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := chain.resumeFromCheckpoint(); err != nil {
+				done <- err
+				return
+			}
+
+			if err := chain.resolvePools(chain.client); err != nil {
+				done <- err
+				return
+			}
+
+			_, err := chain.consumeSwapEvents(chain.client, parsedABI)
+			done <- err
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(e.sourceDone)
+	}()
+
+	for _, chain := range e.chains {
+		chain.client.(*simulatedChain).waitForSubscriber()
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		assert.NoError(h.t, e.Shutdown(ctx))
+		for range e.chains {
+			assert.ErrorIs(h.t, <-done, errSourceStopped)
+		}
+	}
+}
+
+// volume returns the period volume of an address, in whole USD.
+func (h *ingestionHarness) volume(period int, address common.Address) string {
+	h.redisHelper.mu.Lock()
+	defer h.redisHelper.mu.Unlock()
+
+	units, ok := new(big.Int).SetString(h.redisHelper.hashes[fmt.Sprintf("%s_%d", SharePoolTaskStr, period)][storedAddress(address)], 10)
+	if !ok {
+		return "0"
+	}
+
+	return decimal.New(units).String()
+}
+
+// storedAddress formats an address the way swaps are credited to it.
+func storedAddress(address common.Address) string {
+	return strings.ToLower(address.Hex()[2:])
+}
+
+func newUSDCWETHPool(pair common.Address, attribution string) []config.PoolConfig {
+	return []config.PoolConfig{
+		{
+			Address:     pair.Hex(),
+			Token0:      config.TokenConfig{Symbol: "USDC", Decimals: 6},
+			Token1:      config.TokenConfig{Symbol: "WETH", Decimals: 18},
+			Quote:       quoteToken0,
+			Attribution: attribution,
+		},
+	}
+}
+
+func usdc(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1e6))
+}
+
+func weth(milli int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(milli), big.NewInt(1e15))
+}
+
+func TestIngestionCreditsSimulatedSwaps(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	alice := newSimulatedAccount(t)
+	bob := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, alice, bob)
+
+	pair := chain.deployPair(deployer)
+
+	h := newIngestionHarness(t, chain)
+	e := h.newService(newUSDCWETHPool(pair, attributionSender))
+	stop := h.start(e)
+
+	// alice buys 1 WETH for 3,000 USDC, bob buys for 500 USDC and sells 0.5 WETH for 1,000 USDC
+	chain.swap(alice, pair, alice, usdc(3000), big.NewInt(0), big.NewInt(0), weth(1000))
+	chain.swap(bob, pair, bob, usdc(500), big.NewInt(0), big.NewInt(0), weth(166))
+	bobTx := chain.swap(bob, pair, bob, big.NewInt(0), weth(500), usdc(1000), big.NewInt(0))
+
+	stop()
+
+	// every swap is stored once, dated by its block and filed under the first period, the pair was
+	// deployed in block 1
+	swapEvents := h.swapEventRepo.all()
+	assert.Len(t, swapEvents, 3)
+	for i, swapEvent := range swapEvents {
+		assert.Equal(t, uint64(i+2), swapEvent.BlockNumber)
+		assert.Equal(t, chain.genesis.Add(time.Duration(i+2)*simulatedBlockTime), *swapEvent.BlockTimestamp)
+		assert.Equal(t, []int64{h.sharePoolTasks[1].ID}, swapEvent.TaskIDs)
+		assert.Equal(t, pair.Hex(), swapEvent.ContractAddress)
+	}
+
+	assert.Equal(t, storedAddress(alice), swapEvents[0].SenderAddress)
+	assert.Equal(t, "3000000000", swapEvents[0].Amount0In)
+	assert.Equal(t, "1000000000000000000", swapEvents[0].Amount1Out)
+	assert.Equal(t, bobTx.Hex(), swapEvents[2].TxHash)
+	assert.Equal(t, "1000", swapEvents[2].VolumeUSD.String())
+
+	// the stablecoin legs are credited exactly
+	assert.Equal(t, "3000", h.volume(1, alice))
+	assert.Equal(t, "1500", h.volume(1, bob))
+	assert.Equal(t, int64(4500000000), h.redisHelper.counter["SharePoolTask_1_total"])
+
+	// both traders passed the onboarding target
+	for _, trader := range []common.Address{alice, bob} {
+		history, err := h.taskHistoryRepo.FindByAddressAndTaskId(storedAddress(trader), h.onboardingTask.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, OnboardingTaskPoints, history.RewardPoints)
+	}
+
+	// the checkpoint is at the last credited log
+	checkpoint, err := h.checkpointRepo.FindByChainAndContract(config.MainnetChainID, pair.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), checkpoint.BlockNumber)
+
+	// and the swaps are listed for the trader
+	activities, total, err := h.campaignService.GetSwapActivities(bob.Hex(), 0, 1, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, bobTx.Hex(), activities[0].SwapEvent.TxHash)
+
+	h.logger.AssertNotCalled(t, "Error", mock.Anything)
+}
+
+func TestIngestionAttribution(t *testing.T) {
+	tests := []struct {
+		attribution string
+		credited    func(router, trader, recipient common.Address) common.Address
+	}{
+		{attributionSender, func(router, trader, recipient common.Address) common.Address { return router }},
+		{attributionRecipient, func(router, trader, recipient common.Address) common.Address { return recipient }},
+		{attributionOrigin, func(router, trader, recipient common.Address) common.Address { return trader }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.attribution, func(t *testing.T) {
+			deployer := newSimulatedAccount(t)
+			trader := newSimulatedAccount(t)
+			recipient := newSimulatedAccount(t)
+			chain := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+
+			pair := chain.deployPair(deployer)
+			router := chain.deployRouter(deployer, pair)
+
+			h := newIngestionHarness(t, chain)
+			e := h.newService(newUSDCWETHPool(pair, tt.attribution))
+			stop := h.start(e)
+
+			// the trader swaps through the router and sends the WETH to another account
+			chain.swap(trader, router, recipient, usdc(2000), big.NewInt(0), big.NewInt(0), weth(600))
+
+			stop()
+
+			credited := tt.credited(router, trader, recipient)
+			assert.Equal(t, "2000", h.volume(1, credited))
+			assert.Len(t, h.redisHelper.hashes["SharePoolTask_1"], 1)
+			h.logger.AssertNotCalled(t, "Error", mock.Anything)
+		})
+	}
+}
+
+func TestIngestionResumesWithoutCreditingTwice(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+
+	pair := chain.deployPair(deployer)
+	pools := newUSDCWETHPool(pair, attributionSender)
+
+	h := newIngestionHarness(t, chain)
+
+	stop := h.start(h.newService(pools))
+	chain.swap(trader, pair, trader, usdc(100), big.NewInt(0), big.NewInt(0), weth(30))
+	chain.swap(trader, pair, trader, usdc(200), big.NewInt(0), big.NewInt(0), weth(60))
+	stop()
+
+	// swapped while the service was down
+	chain.swap(trader, pair, trader, usdc(400), big.NewInt(0), big.NewInt(0), weth(120))
+
+	// a restarted service backfills from the checkpoint, the overlap is not credited again
+	stop = h.start(h.newService(pools))
+	chain.swap(trader, pair, trader, usdc(800), big.NewInt(0), big.NewInt(0), weth(240))
+	stop()
+
+	assert.Len(t, h.swapEventRepo.all(), 4)
+	assert.Equal(t, "1500", h.volume(1, trader))
+	h.logger.AssertNotCalled(t, "Error", mock.Anything)
+}
+
+func TestIngestionRejectsSwapsBeforeCampaign(t *testing.T) {
+	// the pair is deployed in block 1, the swaps of blocks 2 and 3 are mined before the campaign
+	// starts and the one of block 4 as it starts
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, integrationCampaignStart.Add(-4*simulatedBlockTime), deployer, trader)
+
+	pair := chain.deployPair(deployer)
+
+	h := newIngestionHarness(t, chain)
+	stop := h.start(h.newService(newUSDCWETHPool(pair, attributionSender)))
+
+	chain.swap(trader, pair, trader, usdc(5000), big.NewInt(0), big.NewInt(0), weth(1500))
+	chain.swap(trader, pair, trader, usdc(5000), big.NewInt(0), big.NewInt(0), weth(1500))
+	chain.swap(trader, pair, trader, usdc(300), big.NewInt(0), big.NewInt(0), weth(90))
+
+	stop()
+
+	// only the swap within the campaign is stored and credited, it does not reach the onboarding target
+	swapEvents := h.swapEventRepo.all()
+	assert.Len(t, swapEvents, 1)
+	assert.Equal(t, uint64(4), swapEvents[0].BlockNumber)
+	assert.Equal(t, "300", h.volume(1, trader))
+
+	_, err := h.taskHistoryRepo.FindByAddressAndTaskId(storedAddress(trader), h.onboardingTask.ID)
+	assert.Error(t, err)
+	h.logger.AssertNotCalled(t, "Error", mock.Anything)
+}
+
+func TestIngestionCreditsConcurrentCampaigns(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+
+	pair := chain.deployPair(deployer)
+
+	h := newIngestionHarness(t, chain)
+
+	// besides the seeded campaign, one counts the pair, one only another pool
+	campaignEnd := integrationCampaignStart.Add(7 * 24 * time.Hour)
+	campaignTasks := map[int64]*entities.Task{}
+	for _, pool := range []string{pair.Hex(), "0x397FF1542f962076d0BFE58eA045FfA2d347ACa0"} {
+		campaign, err := h.campaignRepo.Create(&entities.Campaign{
+			Name:      pool,
+			StartedAt: integrationCampaignStart,
+			EndAt:     campaignEnd,
+			Pools:     []*entities.CampaignPool{{ChainID: config.MainnetChainID, PoolAddress: pool}},
+		})
+		assert.NoError(t, err)
+
+		task, err := h.taskRepo.Create(&entities.Task{
+			CampaignID: &campaign.ID,
+			Name:       SharePoolTaskStr,
+			Points:     SharePoolTaskPoints,
+			StartedAt:  &integrationCampaignStart,
+			EndAt:      &campaignEnd,
+			Period:     1,
+		})
+		assert.NoError(t, err)
+		campaignTasks[campaign.ID] = task
+	}
+
+	stop := h.start(h.newService(newUSDCWETHPool(pair, attributionSender)))
+
+	chain.swap(trader, pair, trader, usdc(700), big.NewInt(0), big.NewInt(0), weth(200))
+
+	stop()
+
+	// the swap is stored once and credited to the seeded campaign and the one counting the pair
+	swapEvents := h.swapEventRepo.all()
+	assert.Len(t, swapEvents, 1)
+	assert.Equal(t, []int64{h.sharePoolTasks[1].ID, campaignTasks[1].ID}, swapEvents[0].TaskIDs)
+
+	assert.Equal(t, "700", h.volume(1, trader))
+	assert.Equal(t, "700000000", h.redisHelper.hashes["campaign_1_SharePoolTask_1"][storedAddress(trader)])
+	assert.NotContains(t, h.redisHelper.hashes, "campaign_2_SharePoolTask_1")
+
+	// each campaign lists the swaps it credited
+	activities, total, err := h.campaignService.GetSwapActivities(trader.Hex(), 1, 0, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, swapEvents[0].TxHash, activities[0].SwapEvent.TxHash)
+
+	_, total, err = h.campaignService.GetSwapActivities(trader.Hex(), 2, 0, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+
+	h.logger.AssertNotCalled(t, "Error", mock.Anything)
+}
+
+func TestIngestionAcrossChains(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	mainnet := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+	arbitrum := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+
+	// the same deployer gets the same pair address on both chains
+	pair := mainnet.deployPair(deployer)
+	assert.Equal(t, pair, arbitrum.deployPair(deployer))
+	pools := newUSDCWETHPool(pair, attributionSender)
+
+	h := newIngestionHarness(t, mainnet)
+	arbitrumID := int64(42161)
+
+	e := h.newMultiChainService(
+		ingestedChain{config: config.ChainConfig{ID: config.MainnetChainID, Name: "ethereum", Pools: pools}, client: mainnet},
+		ingestedChain{config: config.ChainConfig{ID: arbitrumID, Name: "arbitrum", Pools: pools}, client: arbitrum},
+	)
+	stop := h.start(e)
+
+	mainnetTx := mainnet.swap(trader, pair, trader, usdc(700), big.NewInt(0), big.NewInt(0), weth(200))
+	arbitrum.swap(trader, pair, trader, usdc(300), big.NewInt(0), big.NewInt(0), weth(90))
+	arbitrum.swap(trader, pair, trader, usdc(100), big.NewInt(0), big.NewInt(0), weth(30))
+
+	stop()
+
+	// the swaps are stored per chain, even at the same log position
+	swapEvents := h.swapEventRepo.all()
+	assert.Len(t, swapEvents, 3)
+	assert.Equal(t, config.MainnetChainID, swapEvents[0].ChainID)
+	assert.Equal(t, mainnetTx.Hex(), swapEvents[0].TxHash)
+	assert.Equal(t, arbitrumID, swapEvents[1].ChainID)
+	assert.Equal(t, swapEvents[0].BlockNumber, swapEvents[1].BlockNumber)
+	assert.Equal(t, arbitrumID, swapEvents[2].ChainID)
+	for _, swapEvent := range swapEvents {
+		assert.Equal(t, []int64{h.sharePoolTasks[1].ID}, swapEvent.TaskIDs)
+	}
+
+	// every chain checkpoints the pair on its own
+	checkpoint, err := h.checkpointRepo.FindByChainAndContract(config.MainnetChainID, pair.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), checkpoint.BlockNumber)
+
+	checkpoint, err = h.checkpointRepo.FindByChainAndContract(arbitrumID, pair.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), checkpoint.BlockNumber)
+	assert.Equal(t, "arbitrum", checkpoint.Chain)
+
+	// the campaign wide task adds the chains up
+	assert.Equal(t, "1100", h.volume(1, trader))
+	assert.Equal(t, int64(1100000000), h.redisHelper.counter["SharePoolTask_1_total"])
+	h.logger.AssertNotCalled(t, "Error", mock.Anything)
+}
+
+func TestIngestionRetriesFailedSwaps(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+
+	pair := chain.deployPair(deployer)
+
+	h := newIngestionHarness(t, chain)
+	e := h.newService(newUSDCWETHPool(pair, attributionSender))
+	stop := h.start(e)
+
+	// Redis is down while the second swap is credited
+	chain.swap(trader, pair, trader, usdc(100), big.NewInt(0), big.NewInt(0), weth(30))
+	assert.Eventually(t, func() bool { return h.volume(1, trader) == "100" }, 5*time.Second, time.Millisecond)
+
+	h.redisHelper.fail(fmt.Errorf("redis: connection refused"))
+	failedTx := chain.swap(trader, pair, trader, usdc(200), big.NewInt(0), big.NewInt(0), weth(60))
+	assert.Eventually(t, func() bool {
+		_, total, _ := h.failedLogRepo.List(10, 0)
+		return total == 1
+	}, 5*time.Second, time.Millisecond)
+
+	h.redisHelper.fail(nil)
+	chain.swap(trader, pair, trader, usdc(400), big.NewInt(0), big.NewInt(0), weth(120))
+
+	stop()
+
+	// ingestion went on past the failed swap, which is kept with its error and raw log
+	assert.Equal(t, "500", h.volume(1, trader))
+
+	failedLogs, _, err := e.GetFailedSwapLogs(1, 20)
+	assert.NoError(t, err)
+	assert.Len(t, failedLogs, 1)
+	assert.Equal(t, failedTx.Hex(), failedLogs[0].TxHash)
+	assert.Equal(t, failedStageCredit, failedLogs[0].Stage)
+	assert.Equal(t, "swap volume not recorded: redis: connection refused", failedLogs[0].Error)
+	assert.Equal(t, 1, failedLogs[0].Attempts)
+	assert.Equal(t, chain.blockTime(failedTx), *failedLogs[0].BlockTimestamp)
+
+	// the swap is not kept while its volume is missing
+	assert.Len(t, h.swapEventRepo.all(), 2)
+
+	// a retry while Redis is still down counts one more attempt
+	h.redisHelper.fail(fmt.Errorf("redis: connection refused"))
+	assert.Error(t, e.RetryFailedSwapLog(failedLogs[0].ID))
+
+	failedLog, err := h.failedLogRepo.FindByID(failedLogs[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, failedLog.Attempts)
+
+	// once Redis is back the swap is credited and leaves the queue
+	h.redisHelper.fail(nil)
+	assert.NoError(t, e.RetryFailedSwapLog(failedLogs[0].ID))
+
+	assert.Equal(t, "700", h.volume(1, trader))
+	assert.Len(t, h.swapEventRepo.all(), 3)
+
+	_, total, err := e.GetFailedSwapLogs(1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func TestIngestionNormalizesAmountsWithTokenRegistry(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, integrationCampaignStart.Add(time.Hour), deployer, trader)
+
+	pair := chain.deployPairWithTokens(deployer, common.HexToAddress(testWETHAddress), common.HexToAddress(testUSDCAddress))
+
+	h := newIngestionHarness(t, chain)
+
+	// WETH is token0 of this pool, the seeded tokens are checked on chain and registered on first sight
+	seeded := []config.PoolConfig{
+		{
+			Address: pair.Hex(),
+			Token0:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+			Token1:  config.TokenConfig{Address: testUSDCAddress, Symbol: "USDC", Decimals: 6},
+			Quote:   "USDC",
+		},
+	}
+
+	stop := h.start(h.newService(seeded))
+	chain.swap(trader, pair, trader, weth(1000), big.NewInt(0), big.NewInt(0), usdc(3000))
+	stop()
+
+	assert.Len(t, h.tokenRepo.tokens, 2)
+	assert.Len(t, h.tokenRepo.pools, 1)
+
+	// a restarted service knows the pool by its address alone
+	stop = h.start(h.newService([]config.PoolConfig{{Address: pair.Hex(), Quote: "USDC"}}))
+	chain.swap(trader, pair, trader, big.NewInt(0), usdc(1500), weth(500), big.NewInt(0))
+	stop()
+
+	assert.Equal(t, "4500", h.volume(1, trader))
+	h.logger.AssertNotCalled(t, "Error", mock.Anything)
+	h.logger.AssertNotCalled(t, "Warn", mock.Anything)
+}
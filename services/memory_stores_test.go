@@ -0,0 +1,849 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"trading-ace/entities"
+	"trading-ace/models"
+	"trading-ace/repositories"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// In-memory stand-ins for Redis and the Postgres repositories, used by the integration tests. They
+// keep the semantics the services rely on, such as the swap dedupe and the forward only checkpoints.
+
+type memoryRedisHelper struct {
+	mu      sync.Mutex
+	values  map[string]string
+	hashes  map[string]map[string]string
+	sorted  map[string]map[string]float64
+	counter map[string]int64
+	// returned by the volume updates while set, to simulate an outage
+	failure error
+}
+
+func newMemoryRedisHelper() *memoryRedisHelper {
+	return &memoryRedisHelper{
+		values:  map[string]string{},
+		hashes:  map[string]map[string]string{},
+		sorted:  map[string]map[string]float64{},
+		counter: map[string]int64{},
+	}
+}
+
+func (r *memoryRedisHelper) Set(key string, value string, expiration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.values[key] = value
+
+	return nil
+}
+
+func (r *memoryRedisHelper) Get(key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if value, ok := r.values[key]; ok {
+		return value, nil
+	}
+
+	if value, ok := r.counter[key]; ok {
+		return strconv.FormatInt(value, 10), nil
+	}
+
+	return "", fmt.Errorf("key %s does not exist", key)
+}
+
+func (r *memoryRedisHelper) Delete(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.values, key)
+	delete(r.hashes, key)
+	delete(r.sorted, key)
+	delete(r.counter, key)
+
+	return nil
+}
+
+func (r *memoryRedisHelper) IncrFloat(key string, value float64) error {
+	return fmt.Errorf("IncrFloat is not supported")
+}
+
+func (r *memoryRedisHelper) HSet(key string, field string, value interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hash(key)[field] = fmt.Sprint(value)
+
+	return nil
+}
+
+func (r *memoryRedisHelper) HGet(key string, field string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.hashes[key][field]
+	if !ok {
+		return "", fmt.Errorf("field %s does not exist in key %s", field, key)
+	}
+
+	return value, nil
+}
+
+func (r *memoryRedisHelper) HGetAll(key string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := map[string]string{}
+	for field, value := range r.hashes[key] {
+		result[field] = value
+	}
+
+	return result, nil
+}
+
+func (r *memoryRedisHelper) HIncrFloat(key string, field string, value float64) error {
+	return fmt.Errorf("HIncrFloat is not supported")
+}
+
+func (r *memoryRedisHelper) HIncrByWithTotal(key string, field string, totalKey string, value int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failure != nil {
+		return 0, r.failure
+	}
+
+	hash := r.hash(key)
+
+	current, _ := strconv.ParseInt(hash[field], 10, 64)
+	current += value
+	hash[field] = strconv.FormatInt(current, 10)
+
+	r.counter[totalKey] += value
+
+	return current, nil
+}
+
+func (r *memoryRedisHelper) ScaleToIntegers(key string, totalKey string, markerKey string, factor int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.values[markerKey]; ok {
+		return false, nil
+	}
+
+	hash := r.hash(key)
+	for field, value := range hash {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false, err
+		}
+
+		hash[field] = strconv.FormatFloat(math.Round(amount*float64(factor)), 'f', 0, 64)
+	}
+
+	if _, ok := r.counter[totalKey]; ok {
+		r.counter[totalKey] *= factor
+	}
+	r.values[markerKey] = "1"
+
+	return true, nil
+}
+
+func (r *memoryRedisHelper) ZAdd(key string, members ...*redis.Z) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sorted[key] == nil {
+		r.sorted[key] = map[string]float64{}
+	}
+
+	for _, member := range members {
+		r.sorted[key][fmt.Sprint(member.Member)] = member.Score
+	}
+
+	return nil
+}
+
+func (r *memoryRedisHelper) ZRange(key string, start, stop int64) ([]string, error) {
+	members, _, err := r.ZRangeWithScores(key, start, stop)
+	return members, err
+}
+
+func (r *memoryRedisHelper) ZRangeWithScores(key string, start, stop int64) ([]string, []float64, error) {
+	return r.zrange(key, start, stop, false)
+}
+
+func (r *memoryRedisHelper) ZRevRange(key string, start, stop int64) ([]string, error) {
+	members, _, err := r.ZRevRangeWithScores(key, start, stop)
+	return members, err
+}
+
+func (r *memoryRedisHelper) ZRevRangeWithScores(key string, start, stop int64) ([]string, []float64, error) {
+	return r.zrange(key, start, stop, true)
+}
+
+func (r *memoryRedisHelper) SetTTL(key string, expiration time.Duration) error {
+	return nil
+}
+
+func (r *memoryRedisHelper) Incr(key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counter[key]++
+
+	return r.counter[key], nil
+}
+
+func (r *memoryRedisHelper) SetNX(key string, value string, expiration time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.values[key]; ok {
+		return false, nil
+	}
+
+	r.values[key] = value
+
+	return true, nil
+}
+
+func (r *memoryRedisHelper) ExpireIfValue(key string, value string, expiration time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.values[key] == value, nil
+}
+
+func (r *memoryRedisHelper) DeleteIfValue(key string, value string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.values[key] != value {
+		return false, nil
+	}
+
+	delete(r.values, key)
+
+	return true, nil
+}
+
+func (r *memoryRedisHelper) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failure = err
+}
+
+func (r *memoryRedisHelper) hash(key string) map[string]string {
+	if r.hashes[key] == nil {
+		r.hashes[key] = map[string]string{}
+	}
+
+	return r.hashes[key]
+}
+
+func (r *memoryRedisHelper) zrange(key string, start, stop int64, reverse bool) ([]string, []float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []string{}
+	for member := range r.sorted[key] {
+		members = append(members, member)
+	}
+
+	scores := r.sorted[key]
+	sort.Slice(members, func(i, j int) bool {
+		if scores[members[i]] != scores[members[j]] {
+			return (scores[members[i]] < scores[members[j]]) != reverse
+		}
+
+		return (members[i] < members[j]) != reverse
+	})
+
+	// negative indexes count from the end, like in Redis
+	size := int64(len(members))
+	if start < 0 {
+		start += size
+	}
+	if stop < 0 {
+		stop += size
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= size {
+		stop = size - 1
+	}
+
+	resultMembers := []string{}
+	resultScores := []float64{}
+	for i := start; i <= stop; i++ {
+		resultMembers = append(resultMembers, members[i])
+		resultScores = append(resultScores, scores[members[i]])
+	}
+
+	return resultMembers, resultScores, nil
+}
+
+type memorySwapEventRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	events []*entities.SwapEvent
+	tasks  *memoryTaskRepository
+}
+
+func (r *memorySwapEventRepository) CreateIfNotExists(swapEvent *entities.SwapEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.events {
+		if existing.ChainID == swapEvent.ChainID && existing.TxHash == swapEvent.TxHash && existing.LogIndex == swapEvent.LogIndex {
+			return false, nil
+		}
+	}
+
+	r.nextID++
+	stored := *swapEvent
+	stored.ID = r.nextID
+	stored.CreatedAt = time.Now().UTC()
+	r.events = append(r.events, &stored)
+
+	return true, nil
+}
+
+func (r *memorySwapEventRepository) DeleteByChainTxHashAndLogIndex(chainID int64, txHash string, logIndex uint) (*entities.SwapEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.events {
+		if existing.ChainID == chainID && existing.TxHash == txHash && existing.LogIndex == logIndex {
+			r.events = append(r.events[:i], r.events[i+1:]...)
+			return existing, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *memorySwapEventRepository) GetByAddress(address string, campaignID *int64, period int, limit int, offset int) ([]*models.SwapActivity, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := []*models.SwapActivity{}
+	for _, swapEvent := range r.events {
+		if swapEvent.SenderAddress != address {
+			continue
+		}
+
+		// swaps credited to no task are listed as those of no campaign
+		tasks := []*entities.Task{nil}
+		if len(swapEvent.TaskIDs) > 0 {
+			tasks = []*entities.Task{}
+			for _, taskID := range swapEvent.TaskIDs {
+				if task, err := r.tasks.FindById(taskID); err == nil {
+					tasks = append(tasks, task)
+				}
+			}
+		}
+
+		for _, task := range tasks {
+			var taskCampaignID *int64
+			var taskPeriod *int
+			if task != nil {
+				taskCampaignID = task.CampaignID
+				taskPeriod = &task.Period
+			}
+
+			if !isSameCampaign(taskCampaignID, campaignID) {
+				continue
+			}
+
+			if period != 0 && (taskPeriod == nil || *taskPeriod != period) {
+				continue
+			}
+
+			matches = append(matches, &models.SwapActivity{SwapEvent: swapEvent, TaskPeriod: taskPeriod})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i].SwapEvent, matches[j].SwapEvent
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber > b.BlockNumber
+		}
+
+		return a.LogIndex > b.LogIndex
+	})
+
+	if offset >= len(matches) {
+		return []*models.SwapActivity{}, 0, nil
+	}
+
+	end := offset + limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	return matches[offset:end], len(matches), nil
+}
+
+// all returns the stored swaps in log order per chain, whichever worker stored them first.
+func (r *memorySwapEventRepository) all() []*entities.SwapEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := append([]*entities.SwapEvent{}, r.events...)
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].ChainID != events[j].ChainID {
+			return events[i].ChainID < events[j].ChainID
+		}
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+
+	return events
+}
+
+type memoryTaskRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	tasks  []*entities.Task
+}
+
+// WithTx returns the repository itself, the in-memory stores write at once.
+func (r *memoryTaskRepository) WithTx(tx *sql.Tx) repositories.ITaskRepository {
+	return r
+}
+
+func (r *memoryTaskRepository) Create(task *entities.Task) (*entities.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	stored := *task
+	stored.ID = r.nextID
+	r.tasks = append(r.tasks, &stored)
+
+	return &stored, nil
+}
+
+func (r *memoryTaskRepository) FindById(id int64) (*entities.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, task := range r.tasks {
+		if task.ID == id {
+			return task, nil
+		}
+	}
+
+	return nil, fmt.Errorf("task not found")
+}
+
+func (r *memoryTaskRepository) FindByName(name string) (*entities.Task, error) {
+	tasks, _ := r.GetByName(name)
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("task not found")
+	}
+
+	return tasks[0], nil
+}
+
+func (r *memoryTaskRepository) GetByName(name string) ([]*entities.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := []*entities.Task{}
+	for _, task := range r.tasks {
+		if task.Name == name {
+			results = append(results, task)
+		}
+	}
+
+	return results, nil
+}
+
+func (r *memoryTaskRepository) IsExistedByName(name string) (bool, error) {
+	tasks, _ := r.GetByName(name)
+	return len(tasks) > 0, nil
+}
+
+func (r *memoryTaskRepository) GetByAddressAndNamesIncludingTaskHistories(address string, campaignID int64, names []string) ([]*models.TaskWithTaskHistory, error) {
+	return nil, fmt.Errorf("GetByAddressAndNamesIncludingTaskHistories is not supported")
+}
+
+type memoryTaskHistoryRepository struct {
+	mu        sync.Mutex
+	nextID    int64
+	histories []*entities.TaskHistory
+}
+
+// WithTx returns the repository itself, the in-memory stores write at once.
+func (r *memoryTaskHistoryRepository) WithTx(tx *sql.Tx) repositories.ITaskHistoryRepository {
+	return r
+}
+
+func (r *memoryTaskHistoryRepository) Create(taskHistory *entities.TaskHistory) (*entities.TaskHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	stored := *taskHistory
+	stored.ID = r.nextID
+	r.histories = append(r.histories, &stored)
+
+	return &stored, nil
+}
+
+func (r *memoryTaskHistoryRepository) FindByID(id int64) (*entities.TaskHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, history := range r.histories {
+		if history.ID == id {
+			return history, nil
+		}
+	}
+
+	return nil, fmt.Errorf("task record not found: %w", sql.ErrNoRows)
+}
+
+func (r *memoryTaskHistoryRepository) FindByAddressAndTaskId(address string, taskId int64) (*entities.TaskHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, history := range r.histories {
+		if history.Address == address && history.TaskID == taskId {
+			return history, nil
+		}
+	}
+
+	return nil, fmt.Errorf("task record not found: %w", sql.ErrNoRows)
+}
+
+func (r *memoryTaskHistoryRepository) GetByAddressIncludingTasks(address string, campaignID int64) ([]*models.TaskTaskHistoryPair, error) {
+	return nil, fmt.Errorf("GetByAddressIncludingTasks is not supported")
+}
+
+func (r *memoryTaskHistoryRepository) Delete(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, history := range r.histories {
+		if history.ID == id {
+			r.histories = append(r.histories[:i], r.histories[i+1:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+type memoryCampaignRepository struct {
+	mu        sync.Mutex
+	nextID    int64
+	campaigns []*entities.Campaign
+}
+
+// WithTx returns the repository itself, the in-memory stores write at once.
+func (r *memoryCampaignRepository) WithTx(tx *sql.Tx) repositories.ICampaignRepository {
+	return r
+}
+
+func (r *memoryCampaignRepository) Create(campaign *entities.Campaign) (*entities.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	stored := *campaign
+	stored.ID = r.nextID
+	stored.Pools = []*entities.CampaignPool{}
+	for _, pool := range campaign.Pools {
+		stored.Pools = append(stored.Pools, &entities.CampaignPool{CampaignID: stored.ID, ChainID: pool.ChainID, PoolAddress: pool.PoolAddress})
+	}
+	r.campaigns = append(r.campaigns, &stored)
+
+	return &stored, nil
+}
+
+func (r *memoryCampaignRepository) FindByID(id int64) (*entities.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, campaign := range r.campaigns {
+		if campaign.ID == id {
+			return campaign, nil
+		}
+	}
+
+	return nil, fmt.Errorf("campaign not found: %w", sql.ErrNoRows)
+}
+
+func (r *memoryCampaignRepository) FindLatest() (*entities.Campaign, error) {
+	campaigns, _ := r.List()
+	if len(campaigns) == 0 {
+		return nil, fmt.Errorf("campaign not found: %w", sql.ErrNoRows)
+	}
+
+	return campaigns[0], nil
+}
+
+// List returns the campaigns the latest first, like the Postgres repository.
+func (r *memoryCampaignRepository) List() ([]*entities.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaigns := append([]*entities.Campaign{}, r.campaigns...)
+	sort.Slice(campaigns, func(i, j int) bool {
+		if !campaigns[i].StartedAt.Equal(campaigns[j].StartedAt) {
+			return campaigns[i].StartedAt.After(campaigns[j].StartedAt)
+		}
+
+		return campaigns[i].ID > campaigns[j].ID
+	})
+
+	return campaigns, nil
+}
+
+type memoryCheckpointRepository struct {
+	mu          sync.Mutex
+	checkpoints map[string]*entities.IngestionCheckpoint
+	heads       map[int64]*entities.IngestionHead
+}
+
+func (r *memoryCheckpointRepository) FindByChainAndContract(chainID int64, contractAddress string) (*entities.IngestionCheckpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	checkpoint, ok := r.checkpoints[fmt.Sprintf("%d/%s", chainID, contractAddress)]
+	if !ok {
+		return nil, fmt.Errorf("ingestion checkpoint not found: %w", sql.ErrNoRows)
+	}
+
+	copied := *checkpoint
+	return &copied, nil
+}
+
+func (r *memoryCheckpointRepository) Save(checkpoint *entities.IngestionCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.checkpoints == nil {
+		r.checkpoints = map[string]*entities.IngestionCheckpoint{}
+	}
+
+	key := fmt.Sprintf("%d/%s", checkpoint.ChainID, checkpoint.ContractAddress)
+
+	// checkpoints only move forward
+	if existing, ok := r.checkpoints[key]; ok {
+		if existing.BlockNumber > checkpoint.BlockNumber || (existing.BlockNumber == checkpoint.BlockNumber && existing.LogIndex >= checkpoint.LogIndex) {
+			return nil
+		}
+	}
+
+	copied := *checkpoint
+	r.checkpoints[key] = &copied
+
+	return nil
+}
+
+func (r *memoryCheckpointRepository) Rewind(checkpoint *entities.IngestionCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.checkpoints[fmt.Sprintf("%d/%s", checkpoint.ChainID, checkpoint.ContractAddress)]
+	if !ok {
+		return nil
+	}
+
+	if existing.BlockNumber > checkpoint.BlockNumber || (existing.BlockNumber == checkpoint.BlockNumber && existing.LogIndex > checkpoint.LogIndex) {
+		existing.BlockNumber, existing.LogIndex = checkpoint.BlockNumber, checkpoint.LogIndex
+	}
+
+	return nil
+}
+
+func (r *memoryCheckpointRepository) SaveHead(head *entities.IngestionHead) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.heads == nil {
+		r.heads = map[int64]*entities.IngestionHead{}
+	}
+
+	// heads only move forward
+	if existing, ok := r.heads[head.ChainID]; ok && existing.BlockNumber >= head.BlockNumber {
+		return nil
+	}
+
+	copied := *head
+	r.heads[head.ChainID] = &copied
+
+	return nil
+}
+
+func (r *memoryCheckpointRepository) FindHead(chainID int64) (*entities.IngestionHead, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	head, ok := r.heads[chainID]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *head
+	return &copied, nil
+}
+
+type memoryFailedSwapLogRepository struct {
+	mu         sync.Mutex
+	nextID     int64
+	failedLogs []*entities.FailedSwapLog
+}
+
+func (r *memoryFailedSwapLogRepository) Record(failedLog *entities.FailedSwapLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.failedLogs {
+		if existing.ChainID == failedLog.ChainID && existing.TxHash == failedLog.TxHash && existing.LogIndex == failedLog.LogIndex {
+			existing.Stage = failedLog.Stage
+			existing.Error = failedLog.Error
+			existing.Attempts++
+			return nil
+		}
+	}
+
+	r.nextID++
+	stored := *failedLog
+	stored.ID = r.nextID
+	stored.Attempts = 1
+	r.failedLogs = append(r.failedLogs, &stored)
+
+	return nil
+}
+
+func (r *memoryFailedSwapLogRepository) FindByID(id int64) (*entities.FailedSwapLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, failedLog := range r.failedLogs {
+		if failedLog.ID == id {
+			copied := *failedLog
+			return &copied, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed swap log not found")
+}
+
+func (r *memoryFailedSwapLogRepository) List(limit int, offset int) ([]*entities.FailedSwapLog, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offset >= len(r.failedLogs) {
+		return []*entities.FailedSwapLog{}, 0, nil
+	}
+
+	end := offset + limit
+	if end > len(r.failedLogs) {
+		end = len(r.failedLogs)
+	}
+
+	return append([]*entities.FailedSwapLog{}, r.failedLogs[offset:end]...), len(r.failedLogs), nil
+}
+
+func (r *memoryFailedSwapLogRepository) Delete(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, failedLog := range r.failedLogs {
+		if failedLog.ID == id {
+			r.failedLogs = append(r.failedLogs[:i], r.failedLogs[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed swap log not found")
+}
+
+type memoryTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*entities.Token
+	pools  []*entities.Pool
+}
+
+func (r *memoryTokenRepository) FindToken(chainID int64, address string) (*entities.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.ChainID == chainID && token.Address == address {
+			copied := *token
+			return &copied, nil
+		}
+	}
+
+	return nil, fmt.Errorf("token not found")
+}
+
+func (r *memoryTokenRepository) CreateToken(token *entities.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.ChainID == token.ChainID && existing.Address == token.Address {
+			return nil
+		}
+	}
+
+	stored := *token
+	stored.ID = int64(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, &stored)
+
+	return nil
+}
+
+func (r *memoryTokenRepository) FindPool(chainID int64, address string) (*entities.Pool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pool := range r.pools {
+		if pool.ChainID == chainID && pool.Address == address {
+			copied := *pool
+			return &copied, nil
+		}
+	}
+
+	return nil, fmt.Errorf("pool not found")
+}
+
+func (r *memoryTokenRepository) CreatePool(pool *entities.Pool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.pools {
+		if existing.ChainID == pool.ChainID && existing.Address == pool.Address {
+			return nil
+		}
+	}
+
+	stored := *pool
+	stored.ID = int64(len(r.pools) + 1)
+	r.pools = append(r.pools, &stored)
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"trading-ace/decimal"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var syncEventSignatureHash = crypto.Keccak256Hash([]byte("Sync(uint112,uint112)"))
+
+// syncEvent holds the reserves a Uniswap V2 pair reports after every balance change
+type syncEvent struct {
+	Reserve0 *big.Int
+	Reserve1 *big.Int
+}
+
+func isSyncLog(vLog types.Log) bool {
+	return len(vLog.Topics) > 0 && vLog.Topics[0] == syncEventSignatureHash
+}
+
+// updatePoolPrice stores the USD price of the pool's non-stablecoin token implied by the reserves
+// of a Sync log. A pair emits Sync right before Swap, so a swap is valued at its post-trade price.
+func (e *EthereumService) updatePoolPrice(vLog types.Log, parsedABI IABI) error {
+	pool, err := e.findPool(vLog.Address.Hex())
+	if err != nil {
+		return err
+	}
+
+	event := syncEvent{}
+	if err := parsedABI.UnpackIntoInterface(&event, "Sync", vLog.Data); err != nil {
+		return fmt.Errorf("failed to unpack sync log: %v", err)
+	}
+
+	reserve0 := decimal.TokenAmount(event.Reserve0, pool.Token0.Decimals)
+	reserve1 := decimal.TokenAmount(event.Reserve1, pool.Token1.Decimals)
+
+	quoteReserve, baseReserve := reserve0, reserve1
+	if pool.Quote == quoteToken1 {
+		quoteReserve, baseReserve = reserve1, reserve0
+	}
+
+	// an empty pair has no price, keep the last known one
+	if baseReserve.Sign() == 0 {
+		return nil
+	}
+
+	if e.poolPrices == nil {
+		e.poolPrices = map[common.Address]*big.Rat{}
+	}
+	e.poolPrices[vLog.Address] = new(big.Rat).Quo(quoteReserve, baseReserve)
+
+	return nil
+}
+
+// poolPrice returns the exact last reserve based price of the pool, or nil before its first Sync log.
+func (e *EthereumService) poolPrice(poolAddress string) *big.Rat {
+	return e.poolPrices[common.HexToAddress(poolAddress)]
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"math/big"
+	"testing"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
+	"trading-ace/mocks"
+	"trading-ace/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newSyncLog(t *testing.T, reserve0 *big.Int, reserve1 *big.Int) types.Log {
+	parsedABI, err := (&EthereumService{}).parseABI()
+	assert.NoError(t, err)
+
+	data, err := parsedABI.Events["Sync"].Inputs.Pack(reserve0, reserve1)
+	assert.NoError(t, err)
+
+	return types.Log{
+		Address:     common.HexToAddress(testPoolAddress),
+		Topics:      []common.Hash{syncEventSignatureHash},
+		Data:        data,
+		BlockNumber: 100,
+		Index:       3,
+	}
+}
+
+func TestUpdatePoolPrice(t *testing.T) {
+	e := &EthereumService{config: &config.Config{}, chain: newTestChain()}
+	parsedABI, err := e.parseABI()
+	assert.NoError(t, err)
+
+	assert.Nil(t, e.poolPrice(testPoolAddress))
+
+	// 30,000,000 USDC against 10,000 WETH
+	vLog := newSyncLog(t, big.NewInt(30000000000000), new(big.Int).Mul(big.NewInt(10000), big.NewInt(1e18)))
+	assert.NoError(t, e.updatePoolPrice(vLog, parsedABI))
+
+	assert.Equal(t, "3000", e.poolPrice(testPoolAddress).RatString())
+
+	// an empty pair keeps the last known price
+	vLog = newSyncLog(t, big.NewInt(0), big.NewInt(0))
+	assert.NoError(t, e.updatePoolPrice(vLog, parsedABI))
+
+	assert.Equal(t, "3000", e.poolPrice(testPoolAddress).RatString())
+
+	// logs of unknown pools are rejected
+	vLog.Address = common.HexToAddress("0x01")
+	assert.Error(t, e.updatePoolPrice(vLog, parsedABI))
+}
+
+func TestProcessLogValuesSwapAtReservePrice(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{Volume: config.VolumeConfig{Accounting: volumeAccountingBoth}},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+		checkpointRepo:  mockCheckpointRepo,
+	}
+	parsedABI, err := e.parseABI()
+	assert.NoError(t, err)
+
+	// the Sync log only moves the price
+	e.processLog(newSyncLog(t, big.NewInt(20000000000000), new(big.Int).Mul(big.NewInt(10000), big.NewInt(1e18))), parsedABI)
+	mockSwapEventRepo.AssertNotCalled(t, "CreateIfNotExists", mock.Anything)
+
+	// 1 WETH in, 1 WETH out: no USDC moves, both WETH legs are valued at 2,000 USD
+	event := &models.SwapEvent{
+		ChainID:       config.MainnetChainID,
+		TxHash:        "0xabc",
+		LogIndex:      4,
+		PoolAddress:   testPoolAddress,
+		SenderAddress: "0xSenderAddress",
+		Amount0In:     big.NewInt(0),
+		Amount0Out:    big.NewInt(0),
+		Amount1In:     big.NewInt(1e18),
+		Amount1Out:    big.NewInt(1e18),
+	}
+
+	mockSwapEventRepo.On("CreateIfNotExists", mock.MatchedBy(func(swapEvent *entities.SwapEvent) bool {
+		return swapEvent.PriceUSD.String() == "2000" && swapEvent.VolumeUSD.String() == "4000"
+	})).Return(true, nil)
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", mock.Anything, "0xSenderAddress", decimal.NewFromInt(4000), mock.Anything).Return(decimal.NewFromInt(4000), nil).Once()
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, mock.Anything, mock.Anything).Return([]*entities.Task{{ID: 1, Period: 1}}, nil)
+
+	assert.NoError(t, e.processSwapEvent(event))
+
+	mockSwapEventRepo.AssertExpectations(t)
+	mockCampaignService.AssertExpectations(t)
+}
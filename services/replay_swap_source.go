@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// average mainnet block time, used to space out replayed logs
+const replayBlockTime = 12 * time.Second
+
+// replaySwapSource replays recorded logs from a JSONL file, one eth_getLogs style log per line.
+// The gap between two logs is their block distance times the block time divided by speed,
+// a speed of 0 replays as fast as possible.
+type replaySwapSource struct {
+	service *EthereumService
+	path    string
+	speed   float64
+}
+
+// replayLogTimestamp is the block time some nodes add to eth_getLogs results
+type replayLogTimestamp struct {
+	BlockTimestamp *hexutil.Uint64 `json:"blockTimestamp"`
+}
+
+func (r *replaySwapSource) Run(parsedABI IABI) error {
+	// replays run offline, pools the registry has not seen fall back to their configured tokens
+	if err := r.service.resolvePools(nil); err != nil {
+		return err
+	}
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to open replay file: %v", err)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var previous *types.Log
+	count := 0
+	for line := 1; scanner.Scan(); line++ {
+		if r.service.isStopping() {
+			return nil
+		}
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var vLog types.Log
+		if err := json.Unmarshal(scanner.Bytes(), &vLog); err != nil {
+			return fmt.Errorf("failed to decode replay log on line %d: %v", line, err)
+		}
+
+		// replays have no node to look block times up, they are read from the log's blockTimestamp
+		var recorded replayLogTimestamp
+		if err := json.Unmarshal(scanner.Bytes(), &recorded); err == nil && recorded.BlockTimestamp != nil {
+			r.service.cacheBlockTime(vLog.BlockNumber, time.Unix(int64(*recorded.BlockTimestamp), 0).UTC())
+		}
+
+		if previous != nil {
+			select {
+			case <-r.service.stopCh:
+				return nil
+			case <-time.After(r.delay(previous.BlockNumber, vLog.BlockNumber)):
+			}
+		}
+
+		// recorded logs are in block order, the blocks before this log are fully replayed
+		if previous != nil && vLog.BlockNumber > previous.BlockNumber {
+			r.service.reachHead(previous.BlockNumber)
+		}
+
+		// recorded logs are final, they skip the confirmation buffer
+		if vLog.Removed {
+			r.service.rollbackLog(vLog)
+		} else {
+			r.service.processLog(vLog, parsedABI)
+		}
+
+		previous = &vLog
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read replay file: %v", err)
+	}
+
+	if previous != nil {
+		r.service.reachHead(previous.BlockNumber)
+	}
+
+	r.service.logger.Info(fmt.Sprintf("replayed %d logs from %s", count, r.path))
+
+	return nil
+}
+
+func (r *replaySwapSource) delay(fromBlock uint64, toBlock uint64) time.Duration {
+	if r.speed <= 0 || toBlock <= fromBlock {
+		return 0
+	}
+
+	return time.Duration(float64(toBlock-fromBlock) * float64(replayBlockTime) / r.speed)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/entities"
+	"trading-ace/mocks"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func writeReplayFile(t *testing.T, logs []types.Log) string {
+	path := filepath.Join(t.TempDir(), "swaps.jsonl")
+
+	content := []byte{}
+	for _, vLog := range logs {
+		line, err := json.Marshal(vLog)
+		assert.NoError(t, err)
+
+		content = append(content, line...)
+		content = append(content, '\n')
+	}
+
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+
+	return path
+}
+
+func TestReplaySwapSourceRun(t *testing.T) {
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", mock.Anything).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+	mockLogger.On("Info", mock.Anything).Return()
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	pool := common.HexToAddress(testPoolAddress)
+	path := writeReplayFile(t, []types.Log{
+		{Address: pool, Topics: []common.Hash{swapV2EventSignatureHash}, Data: []byte{0x01}, BlockNumber: 100, Index: 1},
+		{Address: pool, Topics: []common.Hash{swapV2EventSignatureHash}, Data: []byte{0x02}, BlockNumber: 101, Index: 0},
+	})
+
+	chain := newTestChain()
+	chain.Confirmations = 12
+	chain.ReplayFile = path
+
+	e := &EthereumService{
+		logger: mockLogger,
+		config: &config.Config{
+			Source: config.SourceConfig{Type: replaySourceType},
+		},
+		chain:          chain,
+		checkpointRepo: mockCheckpointRepo,
+	}
+
+	err := e.newSwapSource().Run(mockABI)
+
+	assert.NoError(t, err)
+	mockABI.AssertNumberOfCalls(t, "UnpackIntoInterface", 2)
+	assert.Equal(t, &logPosition{BlockNumber: 101, LogIndex: 0}, e.lastProcessed)
+
+	// a missing file is reported
+	e.chain.ReplayFile = filepath.Join(t.TempDir(), "missing.jsonl")
+	assert.Error(t, e.newSwapSource().Run(mockABI))
+}
+
+func TestReplaySwapSourceReadsBlockTimestamps(t *testing.T) {
+	mockABI := new(mocks.MockABI)
+	mockLogger := new(mocks.MockLogger)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	mockABI.On("UnpackIntoInterface", mock.Anything, "Swap", mock.Anything).Return(fmt.Errorf("unpacking error"))
+	mockLogger.On("Error", mock.Anything).Return()
+	mockLogger.On("Info", mock.Anything).Return()
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil)
+
+	vLog := types.Log{Address: common.HexToAddress(testPoolAddress), Topics: []common.Hash{swapV2EventSignatureHash}, BlockNumber: 100}
+	line, err := json.Marshal(vLog)
+	assert.NoError(t, err)
+
+	// the block time is recorded next to the log fields
+	line = append(line[:len(line)-1], []byte(`,"blockTimestamp":"0x674ef2c0"}`)...)
+
+	path := filepath.Join(t.TempDir(), "swaps.jsonl")
+	assert.NoError(t, os.WriteFile(path, append(line, '\n'), 0644))
+
+	e := &EthereumService{
+		logger: mockLogger,
+		config: &config.Config{
+			Source: config.SourceConfig{Type: replaySourceType},
+		},
+		chain:          &config.ChainConfig{ID: config.MainnetChainID, Pools: newTestPools(), ReplayFile: path},
+		checkpointRepo: mockCheckpointRepo,
+	}
+
+	// the last replayed block is ingested, dated by its recorded time
+	mockCheckpointRepo.On("SaveHead", &entities.IngestionHead{
+		ChainID:        config.MainnetChainID,
+		BlockNumber:    100,
+		BlockTimestamp: time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC),
+	}).Return(nil).Once()
+
+	assert.NoError(t, e.newSwapSource().Run(mockABI))
+
+	// no client is needed to date the replayed swaps
+	timestamp, err := e.blockTimestamp(types.Log{BlockNumber: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC), timestamp)
+	mockCheckpointRepo.AssertExpectations(t)
+}
+
+func TestReplaySwapSourceDelay(t *testing.T) {
+	r := &replaySwapSource{speed: 12}
+	assert.Equal(t, 2*time.Second, r.delay(100, 102))
+	assert.Equal(t, time.Duration(0), r.delay(102, 102))
+
+	r.speed = 0
+	assert.Equal(t, time.Duration(0), r.delay(100, 200))
+}
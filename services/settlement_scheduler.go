@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"trading-ace/config"
+	"trading-ace/entities"
+	"trading-ace/helpers"
+	"trading-ace/logger"
+	"trading-ace/repositories"
+)
+
+type ISettlementScheduler interface {
+	Run()
+	Shutdown(ctx context.Context) error
+}
+
+// SettlementScheduler runs the settlement jobs stored when campaigns start. A period is settled once
+// the grace period after its end has passed and ingestion of its chains has credited the swaps up to
+// its end. Jobs are kept in Postgres, so the periods that ended while the service was down are settled
+// once it is back. Runs hold the settlement lock, so only one instance settles at a time.
+type SettlementScheduler struct {
+	logger            logger.ILogger
+	config            *config.Config
+	campaignService   ICampaignService
+	taskRepo          repositories.ITaskRepository
+	campaignRepo      repositories.ICampaignRepository
+	settlementJobRepo repositories.ISettlementJobRepository
+	checkpointRepo    repositories.IIngestionCheckpointRepository
+	locker            helpers.ILocker
+
+	// closed by Shutdown to stop Run
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// closed when Run returns
+	done chan struct{}
+}
+
+const defaultSettlementPollInterval = time.Minute
+const defaultSettlementMaxAttempts = 10
+
+const settlementLockName = "settlement"
+const settlementLockTTL = 30 * time.Second
+
+func NewSettlementScheduler(
+	logger logger.ILogger,
+	config *config.Config,
+	campaignService ICampaignService,
+	taskRepo repositories.ITaskRepository,
+	campaignRepo repositories.ICampaignRepository,
+	settlementJobRepo repositories.ISettlementJobRepository,
+	checkpointRepo repositories.IIngestionCheckpointRepository,
+	locker helpers.ILocker,
+) ISettlementScheduler {
+	return &SettlementScheduler{
+		logger:            logger,
+		config:            config,
+		campaignService:   campaignService,
+		taskRepo:          taskRepo,
+		campaignRepo:      campaignRepo,
+		settlementJobRepo: settlementJobRepo,
+		checkpointRepo:    checkpointRepo,
+		locker:            locker,
+		stopCh:            make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+}
+
+// Run settles the jobs due on startup, overdue ones included, then wakes up when the next job falls
+// due, or after the poll interval at the latest, until Shutdown is called.
+func (s *SettlementScheduler) Run() {
+	defer close(s.done)
+
+	for {
+		s.settleDueJobs(time.Now().UTC())
+
+		timer := time.NewTimer(s.nextRunIn(time.Now().UTC()))
+
+		select {
+		case <-s.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// nextRunIn returns the time until the next job falls due, capped by the poll interval. Jobs due
+// already, such as those waiting for ingestion, are looked at again after the poll interval.
+func (s *SettlementScheduler) nextRunIn(now time.Time) time.Duration {
+	wait := s.config.Settlement.PollInterval
+	if wait == 0 {
+		wait = defaultSettlementPollInterval
+	}
+
+	gracePeriod := s.config.Settlement.GracePeriod
+
+	next, err := s.settlementJobRepo.FindNextDueAt(now.Add(-gracePeriod))
+	if err != nil {
+		s.logger.Error(err)
+		return wait
+	}
+
+	if next != nil {
+		if untilDue := next.Add(gracePeriod).Sub(now); untilDue > 0 && untilDue < wait {
+			wait = untilDue
+		}
+	}
+
+	return wait
+}
+
+// Shutdown stops Run and waits for the settlement in progress to finish.
+func (s *SettlementScheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("settlement scheduler did not stop: %w", ctx.Err())
+	}
+}
+
+// settleDueJobs runs the jobs whose grace period has passed at the given time, the earliest first.
+// Once a period of a campaign fails or waits for ingestion, its later periods wait for the next run
+// so they are never settled out of order. The run is skipped while another instance holds the
+// settlement lock, and each job is claimed with the fencing token of the lease before it is settled.
+func (s *SettlementScheduler) settleDueJobs(at time.Time) {
+	lease, err := s.locker.Acquire(settlementLockName, settlementLockTTL)
+	if errors.Is(err, helpers.ErrLockHeld) {
+		return
+	}
+
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	defer func() {
+		if err := lease.Release(); err != nil {
+			s.logger.Error(err)
+		}
+	}()
+
+	jobs, err := s.settlementJobRepo.FindDue(at.Add(-s.config.Settlement.GracePeriod))
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("failed to find due settlements: %v", err))
+		return
+	}
+
+	maxAttempts := s.config.Settlement.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultSettlementMaxAttempts
+	}
+
+	// campaigns with a failed or waiting period in this run, tasks created before campaigns were stored under 0
+	blocked := map[int64]bool{}
+	for _, job := range jobs {
+		if s.isStopping() {
+			return
+		}
+
+		if isLost(lease) {
+			s.logger.Warn("lost the settlement lock, the remaining periods are left to its next holder")
+			return
+		}
+
+		// the campaign of the job is not known yet, the run ends so its later periods keep their order
+		claimed, err := s.settlementJobRepo.Claim(job.ID, lease.Token())
+		if err != nil {
+			s.logger.Error(err)
+			return
+		}
+
+		if !claimed {
+			s.logger.Warn(fmt.Sprintf("settlement job %d was claimed by a newer holder of the settlement lock", job.ID))
+			return
+		}
+
+		task, err := s.taskRepo.FindById(job.TaskID)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("failed to find task %d to settle: %v", job.TaskID, err))
+			s.recordFailure(job.ID, lease.Token(), err, maxAttempts)
+			continue
+		}
+
+		var campaignID int64
+		if task.CampaignID != nil {
+			campaignID = *task.CampaignID
+		}
+
+		if blocked[campaignID] {
+			continue
+		}
+
+		chain, err := s.ingestionBehind(task)
+		if err != nil {
+			s.logger.Error(err)
+			blocked[campaignID] = true
+			continue
+		}
+
+		if chain != nil {
+			s.logger.Warn(fmt.Sprintf("period %d of task %d waits for %s ingestion to pass %s", task.Period, task.ID, chain.Name, task.EndAt.Format(time.RFC3339)))
+			blocked[campaignID] = true
+			continue
+		}
+
+		// waiting for ingestion may have taken long enough for the lease to expire
+		if isLost(lease) {
+			s.logger.Warn("lost the settlement lock, the remaining periods are left to its next holder")
+			return
+		}
+
+		// the rewards and the settled status are stored together under the token of the lease, a
+		// failure leaves the period pending
+		if err := s.campaignService.SettleSharePoolTask(task, job.ID, lease.Token()); err != nil {
+			s.logger.Error(err)
+			s.recordFailure(job.ID, lease.Token(), err, maxAttempts)
+			blocked[campaignID] = true
+			continue
+		}
+
+		s.logger.Info(fmt.Sprintf("settled period %d of task %d, due at %s", task.Period, task.ID, job.DueAt.Format(time.RFC3339)))
+	}
+}
+
+// ingestionBehind returns the first chain the task counts whose ingestion has not reached a safe head
+// past the end of the task yet, nil once every chain has. A task without a chain counts the chains of
+// its campaign's pools, every chain for a campaign without pools.
+func (s *SettlementScheduler) ingestionBehind(task *entities.Task) (*config.ChainConfig, error) {
+	if task.EndAt == nil {
+		return nil, fmt.Errorf("task %d has no end", task.ID)
+	}
+
+	counted, err := s.countedChains(task)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chain := range s.config.ChainConfigs() {
+		if counted != nil && !counted[chain.ID] {
+			continue
+		}
+
+		// the head is only saved once every log up to it is credited, so every swap before it is
+		head, err := s.checkpointRepo.FindHead(chain.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if head == nil || head.BlockTimestamp.Before(*task.EndAt) {
+			return &chain, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// countedChains returns the chains whose swaps a task counts, nil when it counts every chain.
+func (s *SettlementScheduler) countedChains(task *entities.Task) (map[int64]bool, error) {
+	if task.ChainID != nil {
+		return map[int64]bool{*task.ChainID: true}, nil
+	}
+
+	if task.CampaignID == nil {
+		return nil, nil
+	}
+
+	campaign, err := s.campaignRepo.FindByID(*task.CampaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find campaign %d of task %d: %w", *task.CampaignID, task.ID, err)
+	}
+
+	if len(campaign.Pools) == 0 {
+		return nil, nil
+	}
+
+	counted := map[int64]bool{}
+	for _, pool := range campaign.Pools {
+		counted[pool.ChainID] = true
+	}
+
+	return counted, nil
+}
+
+func (s *SettlementScheduler) recordFailure(jobID int64, fencingToken int64, cause error, maxAttempts int) {
+	if err := s.settlementJobRepo.RecordFailure(jobID, fencingToken, cause.Error(), maxAttempts); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// isStopping reports whether Shutdown was called.
+func (s *SettlementScheduler) isStopping() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// isLost reports whether the lease may have passed to another instance.
+func isLost(lease helpers.ILease) bool {
+	select {
+	case <-lease.Lost():
+		return true
+	default:
+		return false
+	}
+}
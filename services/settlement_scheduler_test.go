@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/entities"
+	"trading-ace/helpers"
+	"trading-ace/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ingestedUntil is the safe head block time mainnet ingestion has reached in the tests, past every period of
+// the campaign started at integrationCampaignStart.
+var ingestedUntil = integrationCampaignStart.Add(60 * 24 * time.Hour)
+
+// testFencingToken is the token of the first settlement lease of a test scheduler.
+const testFencingToken = int64(1)
+
+func newTestSettlementScheduler(cfg *config.Config) (*SettlementScheduler, *mocks.MockCampaignService, *mocks.MockTaskRepository, *mocks.MockSettlementJobRepository, *mocks.MockIngestionCheckpointRepository) {
+	loggerMock := new(mocks.MockLogger)
+	loggerMock.On("Info", mock.Anything).Return()
+	loggerMock.On("Warn", mock.Anything).Return()
+	loggerMock.On("Error", mock.Anything).Return()
+
+	campaignService := new(mocks.MockCampaignService)
+	taskRepo := new(mocks.MockTaskRepository)
+	campaignRepo := new(mocks.MockCampaignRepository)
+	settlementJobRepo := new(mocks.MockSettlementJobRepository)
+	checkpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	locker := helpers.NewRedisLocker(newMemoryRedisHelper())
+
+	scheduler := NewSettlementScheduler(loggerMock, cfg, campaignService, taskRepo, campaignRepo, settlementJobRepo, checkpointRepo, locker).(*SettlementScheduler)
+
+	return scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo
+}
+
+func TestSettleDueJobsRunsMissedPeriodsInOrder(t *testing.T) {
+	scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo := newTestSettlementScheduler(&config.Config{})
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+
+	// the service was down over the end of two periods
+	now := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	tasks := newSharePoolTasks(start)
+
+	settlementJobRepo.On("Claim", mock.Anything, testFencingToken).Return(true, nil)
+	settlementJobRepo.On("FindDue", now).Return([]*entities.SettlementJob{
+		{ID: 1, TaskID: tasks[0].ID, DueAt: *tasks[0].EndAt},
+		{ID: 2, TaskID: tasks[1].ID, DueAt: *tasks[1].EndAt},
+	}, nil)
+	taskRepo.On("FindById", tasks[0].ID).Return(tasks[0], nil)
+	taskRepo.On("FindById", tasks[1].ID).Return(tasks[1], nil)
+
+	settled := []int{}
+	campaignService.On("SettleSharePoolTask", mock.Anything, mock.Anything, testFencingToken).Return(nil).Run(func(args mock.Arguments) {
+		settled = append(settled, args.Get(0).(*entities.Task).Period)
+	})
+
+	scheduler.settleDueJobs(now)
+
+	assert.Equal(t, []int{1, 2}, settled)
+	campaignService.AssertCalled(t, "SettleSharePoolTask", tasks[0], int64(1), testFencingToken)
+	campaignService.AssertCalled(t, "SettleSharePoolTask", tasks[1], int64(2), testFencingToken)
+	settlementJobRepo.AssertExpectations(t)
+}
+
+func TestSettleDueJobsHoldsLaterPeriodsOfAFailedCampaign(t *testing.T) {
+	scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo := newTestSettlementScheduler(&config.Config{
+		Settlement: config.SettlementConfig{MaxAttempts: 3},
+	})
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+
+	now := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	// the first period of campaign 1 fails, campaign 2 is unaffected
+	first, second := int64(1), int64(2)
+	tasks := newSharePoolTasks(start)
+	for _, task := range tasks {
+		task.CampaignID = &first
+	}
+	other := newSharePoolTasks(start)[0]
+	other.ID = 20
+	other.CampaignID = &second
+
+	// both campaigns count every pool
+	campaignRepo := scheduler.campaignRepo.(*mocks.MockCampaignRepository)
+	campaignRepo.On("FindByID", mock.Anything).Return(&entities.Campaign{}, nil)
+
+	settlementJobRepo.On("Claim", mock.Anything, testFencingToken).Return(true, nil)
+	settlementJobRepo.On("FindDue", now).Return([]*entities.SettlementJob{
+		{ID: 1, TaskID: tasks[0].ID},
+		{ID: 3, TaskID: other.ID},
+		{ID: 2, TaskID: tasks[1].ID},
+	}, nil)
+	taskRepo.On("FindById", tasks[0].ID).Return(tasks[0], nil)
+	taskRepo.On("FindById", tasks[1].ID).Return(tasks[1], nil)
+	taskRepo.On("FindById", other.ID).Return(other, nil)
+
+	campaignService.On("SettleSharePoolTask", tasks[0], int64(1), testFencingToken).Return(errors.New("redis: connection refused")).Once()
+	campaignService.On("SettleSharePoolTask", other, int64(3), testFencingToken).Return(nil).Once()
+	settlementJobRepo.On("RecordFailure", int64(1), testFencingToken, "redis: connection refused", 3).Return(nil).Once()
+
+	scheduler.settleDueJobs(now)
+
+	// the second period waits for the first to be retried
+	campaignService.AssertNotCalled(t, "SettleSharePoolTask", tasks[1], int64(2), testFencingToken)
+	campaignService.AssertExpectations(t)
+	settlementJobRepo.AssertExpectations(t)
+}
+
+func TestSettlementSchedulerRunsOnStartup(t *testing.T) {
+	scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo := newTestSettlementScheduler(&config.Config{
+		Settlement: config.SettlementConfig{PollInterval: time.Hour},
+	})
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+	settlementJobRepo.On("FindNextDueAt", mock.Anything).Return((*time.Time)(nil), nil)
+
+	// an overdue job is settled without waiting for the poll interval
+	task := newSharePoolTasks(time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))[0]
+	settledCh := make(chan struct{})
+	settlementJobRepo.On("Claim", mock.Anything, mock.Anything).Return(true, nil)
+	settlementJobRepo.On("FindDue", mock.Anything).Return([]*entities.SettlementJob{{ID: 1, TaskID: task.ID}}, nil).Once()
+	taskRepo.On("FindById", task.ID).Return(task, nil)
+	campaignService.On("SettleSharePoolTask", task, int64(1), testFencingToken).Return(nil).Run(func(args mock.Arguments) {
+		close(settledCh)
+	})
+
+	go scheduler.Run()
+
+	select {
+	case <-settledCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the overdue job was not settled on startup")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, scheduler.Shutdown(ctx))
+	settlementJobRepo.AssertExpectations(t)
+}
+
+func TestSettleDueJobsWaitsForTheGracePeriod(t *testing.T) {
+	scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo := newTestSettlementScheduler(&config.Config{
+		Settlement: config.SettlementConfig{GracePeriod: 10 * time.Minute},
+	})
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+
+	task := newSharePoolTasks(integrationCampaignStart)[0]
+
+	// jobs are due once the grace period after the end of their period has passed
+	settlementJobRepo.On("FindDue", task.EndAt.Add(-time.Minute)).Return([]*entities.SettlementJob{}, nil).Once()
+	scheduler.settleDueJobs(task.EndAt.Add(9 * time.Minute))
+
+	settlementJobRepo.On("FindDue", *task.EndAt).Return([]*entities.SettlementJob{{ID: 1, TaskID: task.ID}}, nil).Once()
+	taskRepo.On("FindById", task.ID).Return(task, nil)
+	// every run takes the lock anew, with the next fencing token
+	settlementJobRepo.On("Claim", int64(1), testFencingToken+1).Return(true, nil).Once()
+	campaignService.On("SettleSharePoolTask", task, int64(1), testFencingToken+1).Return(nil).Once()
+	scheduler.settleDueJobs(task.EndAt.Add(10 * time.Minute))
+
+	campaignService.AssertExpectations(t)
+	settlementJobRepo.AssertExpectations(t)
+}
+
+func TestSettleDueJobsWaitsForIngestion(t *testing.T) {
+	arbitrumID := int64(42161)
+	scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo := newTestSettlementScheduler(&config.Config{
+		Chains: []config.ChainConfig{{ID: config.MainnetChainID, Name: "ethereum"}, {ID: arbitrumID, Name: "arbitrum"}},
+	})
+
+	tasks := newSharePoolTasks(integrationCampaignStart)
+	now := tasks[1].EndAt.Add(time.Hour)
+
+	settlementJobRepo.On("Claim", mock.Anything, testFencingToken).Return(true, nil)
+	settlementJobRepo.On("FindDue", now).Return([]*entities.SettlementJob{
+		{ID: 1, TaskID: tasks[0].ID},
+		{ID: 2, TaskID: tasks[1].ID},
+	}, nil)
+	taskRepo.On("FindById", tasks[0].ID).Return(tasks[0], nil)
+	taskRepo.On("FindById", tasks[1].ID).Return(tasks[1], nil)
+
+	// mainnet is past both periods, arbitrum only past the first
+	arbitrumUntil := tasks[1].EndAt.Add(-time.Second)
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+	checkpointRepo.On("FindHead", arbitrumID).Return(&entities.IngestionHead{BlockTimestamp: arbitrumUntil}, nil)
+
+	campaignService.On("SettleSharePoolTask", tasks[0], int64(1), testFencingToken).Return(nil).Once()
+
+	scheduler.settleDueJobs(now)
+
+	// the second period is neither settled nor counted as a failed attempt
+	campaignService.AssertNotCalled(t, "SettleSharePoolTask", tasks[1], int64(2), testFencingToken)
+	settlementJobRepo.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	settlementJobRepo.AssertExpectations(t)
+
+	// a task of a single chain only waits for its own chain
+	mainnetID := config.MainnetChainID
+	mainnetTask := newSharePoolTasks(integrationCampaignStart)[1]
+	mainnetTask.ChainID = &mainnetID
+
+	chain, err := scheduler.ingestionBehind(mainnetTask)
+	assert.NoError(t, err)
+	assert.Nil(t, chain)
+
+	arbitrumTask := newSharePoolTasks(integrationCampaignStart)[1]
+	arbitrumTask.ChainID = &arbitrumID
+
+	chain, err = scheduler.ingestionBehind(arbitrumTask)
+	assert.NoError(t, err)
+	assert.Equal(t, "arbitrum", chain.Name)
+}
+
+func TestSettleDueJobsWaitsForChainsWithoutCheckpoints(t *testing.T) {
+	scheduler, _, _, _, checkpointRepo := newTestSettlementScheduler(&config.Config{})
+
+	// nothing was ingested yet
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return((*entities.IngestionHead)(nil), nil)
+
+	chain, err := scheduler.ingestionBehind(newSharePoolTasks(integrationCampaignStart)[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "ethereum", chain.Name)
+}
+
+func TestSettleDueJobsWaitsForTheChainsOfTheCampaignPools(t *testing.T) {
+	arbitrumID := int64(42161)
+	scheduler, _, _, _, checkpointRepo := newTestSettlementScheduler(&config.Config{
+		Chains: []config.ChainConfig{{ID: config.MainnetChainID, Name: "ethereum"}, {ID: arbitrumID, Name: "arbitrum"}},
+	})
+	campaignRepo := scheduler.campaignRepo.(*mocks.MockCampaignRepository)
+
+	// arbitrum is past the period, mainnet has not reached a safe head yet
+	checkpointRepo.On("FindHead", arbitrumID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return((*entities.IngestionHead)(nil), nil)
+
+	arbitrumCampaign, everyPoolCampaign := int64(1), int64(2)
+	campaignRepo.On("FindByID", arbitrumCampaign).Return(&entities.Campaign{
+		ID:    arbitrumCampaign,
+		Pools: []*entities.CampaignPool{{CampaignID: arbitrumCampaign, ChainID: arbitrumID, PoolAddress: testPoolAddress}},
+	}, nil)
+	campaignRepo.On("FindByID", everyPoolCampaign).Return(&entities.Campaign{ID: everyPoolCampaign}, nil)
+
+	// a campaign that only counts an arbitrum pool does not wait for mainnet
+	task := newSharePoolTasks(integrationCampaignStart)[0]
+	task.CampaignID = &arbitrumCampaign
+
+	chain, err := scheduler.ingestionBehind(task)
+	assert.NoError(t, err)
+	assert.Nil(t, chain)
+
+	// a campaign without pools counts every chain
+	task = newSharePoolTasks(integrationCampaignStart)[0]
+	task.CampaignID = &everyPoolCampaign
+
+	chain, err = scheduler.ingestionBehind(task)
+	assert.NoError(t, err)
+	assert.Equal(t, "ethereum", chain.Name)
+}
+
+func TestSettlementSchedulerWakesUpWhenTheNextJobIsDue(t *testing.T) {
+	scheduler, _, _, settlementJobRepo, _ := newTestSettlementScheduler(&config.Config{
+		Settlement: config.SettlementConfig{PollInterval: time.Hour, GracePeriod: 5 * time.Minute},
+	})
+
+	now := time.Date(2024, 11, 8, 0, 0, 0, 0, time.UTC)
+
+	// the next period ends in 10 minutes and is settled 5 minutes later
+	dueAt := now.Add(10 * time.Minute)
+	settlementJobRepo.On("FindNextDueAt", now.Add(-5*time.Minute)).Return(&dueAt, nil).Once()
+	assert.Equal(t, 15*time.Minute, scheduler.nextRunIn(now))
+
+	// nothing due before the poll interval
+	later := now.Add(2 * time.Hour)
+	settlementJobRepo.On("FindNextDueAt", now.Add(-5*time.Minute)).Return(&later, nil).Once()
+	assert.Equal(t, time.Hour, scheduler.nextRunIn(now))
+
+	settlementJobRepo.On("FindNextDueAt", now.Add(-5*time.Minute)).Return((*time.Time)(nil), nil).Once()
+	assert.Equal(t, time.Hour, scheduler.nextRunIn(now))
+}
+
+func TestSettleDueJobsSkipsWhileAnotherInstanceSettles(t *testing.T) {
+	scheduler, campaignService, _, settlementJobRepo, _ := newTestSettlementScheduler(&config.Config{})
+
+	redisHelper := newMemoryRedisHelper()
+	scheduler.locker = helpers.NewRedisLocker(redisHelper)
+
+	other, err := scheduler.locker.Acquire(settlementLockName, settlementLockTTL)
+	assert.NoError(t, err)
+
+	scheduler.settleDueJobs(time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC))
+
+	settlementJobRepo.AssertNotCalled(t, "FindDue", mock.Anything)
+	campaignService.AssertNotCalled(t, "SettleSharePoolTask", mock.Anything, mock.Anything, mock.Anything)
+
+	// the run after the other instance released the lock settles, with a newer fencing token
+	assert.NoError(t, other.Release())
+
+	now := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	settlementJobRepo.On("FindDue", now).Return([]*entities.SettlementJob{}, nil).Once()
+	scheduler.settleDueJobs(now)
+
+	settlementJobRepo.AssertExpectations(t)
+}
+
+func TestSettleDueJobsStopsOnceANewerHolderClaimedAJob(t *testing.T) {
+	scheduler, campaignService, taskRepo, settlementJobRepo, checkpointRepo := newTestSettlementScheduler(&config.Config{})
+	checkpointRepo.On("FindHead", config.MainnetChainID).Return(&entities.IngestionHead{BlockTimestamp: ingestedUntil}, nil)
+
+	now := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	tasks := newSharePoolTasks(time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))
+
+	settlementJobRepo.On("FindDue", now).Return([]*entities.SettlementJob{
+		{ID: 1, TaskID: tasks[0].ID},
+		{ID: 2, TaskID: tasks[1].ID},
+	}, nil)
+	taskRepo.On("FindById", tasks[0].ID).Return(tasks[0], nil)
+	settlementJobRepo.On("Claim", int64(1), testFencingToken).Return(true, nil).Once()
+	campaignService.On("SettleSharePoolTask", tasks[0], int64(1), testFencingToken).Return(nil).Once()
+
+	// the lease expired meanwhile and the instance that took the lock over claimed the second job
+	settlementJobRepo.On("Claim", int64(2), testFencingToken).Return(false, nil).Once()
+
+	scheduler.settleDueJobs(now)
+
+	campaignService.AssertNotCalled(t, "SettleSharePoolTask", tasks[1], int64(2), mock.Anything)
+	settlementJobRepo.AssertExpectations(t)
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/stretchr/testify/assert"
+)
+
+// simulatedBlockTime is the time between blocks of a simulated chain whose genesis lies ahead of the
+// wall clock, the simulated beacon then dates every block one second after its parent.
+const simulatedBlockTime = time.Second
+
+// simulatedKeys holds the private keys of the accounts made by newSimulatedAccount.
+var simulatedKeys sync.Map
+
+// simulatedChain is a go-ethereum node for integration tests, run in process by the simulated
+// backend. Every transaction is mined in a block of its own, and the node is reached through
+// IEthereumClient the way the service reaches a real one, including subscriptions.
+type simulatedChain struct {
+	IEthereumClient
+
+	t       *testing.T
+	backend *simulated.Backend
+	client  simulated.Client
+	genesis time.Time
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	subs []*simulatedSubscription
+
+	subscribed chan struct{}
+}
+
+// newSimulatedChain starts a chain whose genesis block is dated genesis, and funds the accounts in
+// it. genesis has to lie ahead of the wall clock for the blocks to be simulatedBlockTime apart.
+func newSimulatedChain(t *testing.T, genesis time.Time, accounts ...common.Address) *simulatedChain {
+	alloc := types.GenesisAlloc{}
+	for _, account := range accounts {
+		alloc[account] = types.Account{Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))}
+	}
+
+	backend := simulated.NewBackend(alloc, func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+		ethConf.Genesis.Timestamp = uint64(genesis.Unix())
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	c := &simulatedChain{
+		t:          t,
+		backend:    backend,
+		client:     backend.Client(),
+		genesis:    genesis.UTC(),
+		subscribed: make(chan struct{}, 1),
+	}
+	c.IEthereumClient = c.client.(IEthereumClient)
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// deployPair deploys a minimal pair contract that emits a Uniswap V2 Swap log for every call. The
+// calldata holds the recipient followed by amount0In, amount1In, amount0Out and amount1Out as 32
+// byte words. The swap sender is the caller, like msg.sender in a real pair.
+func (c *simulatedChain) deployPair(from common.Address) common.Address {
+	return c.deploy(from, swapPairCode())
+}
+
+// deployPairWithTokens deploys a pair like deployPair that also answers token0() and token1(), as the
+// token registry checks the tokens a pool config seeds.
+func (c *simulatedChain) deployPairWithTokens(from common.Address, token0 common.Address, token1 common.Address) common.Address {
+	swapCode := swapPairCode()
+
+	// the dispatch below is 29 bytes, each answer 30
+	token0At := 29 + len(swapCode)
+	token1At := token0At + 30
+
+	code := []byte{
+		0x60, 0x00, // PUSH1 0
+		0x35,       // CALLDATALOAD
+		0x60, 0xe0, // PUSH1 224
+		0x1c,                         // SHR, the selector
+		0x80,                         // DUP1
+		0x63, 0x0d, 0xfe, 0x16, 0x81, // PUSH4 token0()
+		0x14,                                      // EQ
+		0x61, byte(token0At >> 8), byte(token0At), // PUSH2 token0 answer
+		0x57,                         // JUMPI
+		0x80,                         // DUP1
+		0x63, 0xd2, 0x12, 0x20, 0xa7, // PUSH4 token1()
+		0x14,                                      // EQ
+		0x61, byte(token1At >> 8), byte(token1At), // PUSH2 token1 answer
+		0x57, // JUMPI
+		0x50, // POP, any other call is a swap
+	}
+	code = append(code, swapCode...)
+
+	for _, token := range []common.Address{token0, token1} {
+		code = append(code, 0x5b, 0x73) // JUMPDEST, PUSH20 the token
+		code = append(code, token.Bytes()...)
+		code = append(code,
+			0x60, 0x00, // PUSH1 0
+			0x52,       // MSTORE
+			0x60, 0x20, // PUSH1 32
+			0x60, 0x00, // PUSH1 0
+			0xf3, // RETURN
+		)
+	}
+
+	return c.deploy(from, code)
+}
+
+// swapPairCode is the runtime code of deployPair.
+func swapPairCode() []byte {
+	code := []byte{
+		0x60, 0x80, // PUSH1 128, the four amounts
+		0x60, 0x20, // PUSH1 32, after the recipient
+		0x60, 0x00, // PUSH1 0
+		0x37,       // CALLDATACOPY
+		0x60, 0x00, // PUSH1 0
+		0x35, // CALLDATALOAD, the recipient as topic 2
+		0x33, // CALLER, the sender as topic 1
+		0x7f, // PUSH32 the event signature as topic 0
+	}
+	code = append(code, swapV2EventSignatureHash.Bytes()...)
+	code = append(code,
+		0x60, 0x80, // PUSH1 128
+		0x60, 0x00, // PUSH1 0
+		0xa3, // LOG3
+		0x00, // STOP
+	)
+
+	return code
+}
+
+// deployRouter deploys a contract that forwards its calldata to pair, so the pair sees the router
+// as the swap sender while the transaction origin stays the calling account.
+func (c *simulatedChain) deployRouter(from common.Address, pair common.Address) common.Address {
+	code := []byte{
+		0x36,       // CALLDATASIZE
+		0x60, 0x00, // PUSH1 0
+		0x60, 0x00, // PUSH1 0
+		0x37,       // CALLDATACOPY
+		0x60, 0x00, // PUSH1 0, return size
+		0x60, 0x00, // PUSH1 0, return offset
+		0x36,       // CALLDATASIZE, argument size
+		0x60, 0x00, // PUSH1 0, argument offset
+		0x60, 0x00, // PUSH1 0, value
+		0x73, // PUSH20 the pair
+	}
+	code = append(code, pair.Bytes()...)
+	code = append(code,
+		0x5a, // GAS
+		0xf1, // CALL
+		0x50, // POP
+		0x00, // STOP
+	)
+
+	return c.deploy(from, code)
+}
+
+// deploy creates a contract with the given runtime code in a block of its own.
+func (c *simulatedChain) deploy(from common.Address, code []byte) common.Address {
+	// copy the runtime code to memory and return it
+	initCode := []byte{
+		0x61, byte(len(code) >> 8), byte(len(code)), // PUSH2 code size
+		0x80,       // DUP1
+		0x60, 0x0c, // PUSH1 12, the size of this init code
+		0x60, 0x00, // PUSH1 0
+		0x39,       // CODECOPY
+		0x60, 0x00, // PUSH1 0
+		0xf3, // RETURN
+	}
+
+	receipt := c.send(from, nil, append(initCode, code...))
+
+	return receipt.ContractAddress
+}
+
+// swap sends a transaction from the account to the contract and mines it, it returns the transaction hash.
+func (c *simulatedChain) swap(from common.Address, contract common.Address, recipient common.Address, amount0In, amount1In, amount0Out, amount1Out *big.Int) common.Hash {
+	input := common.LeftPadBytes(recipient.Bytes(), 32)
+	for _, amount := range []*big.Int{amount0In, amount1In, amount0Out, amount1Out} {
+		input = append(input, common.LeftPadBytes(amount.Bytes(), 32)...)
+	}
+
+	return c.send(from, &contract, input).TxHash
+}
+
+// send signs a transaction of the account, mines it and waits until its logs reached every
+// subscriber, like a websocket subscription delivers them in order.
+func (c *simulatedChain) send(from common.Address, to *common.Address, input []byte) *types.Receipt {
+	ctx := context.Background()
+
+	key, ok := simulatedKeys.Load(from)
+	if !ok {
+		c.t.Fatalf("%s is not a simulated account", from.Hex())
+	}
+
+	chainID, err := c.client.ChainID(ctx)
+	assert.NoError(c.t, err)
+
+	nonce, err := c.client.PendingNonceAt(ctx, from)
+	assert.NoError(c.t, err)
+
+	tx, err := types.SignNewTx(key.(*ecdsa.PrivateKey), types.LatestSignerForChainID(chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Gas:      1000000,
+		GasPrice: big.NewInt(1e10),
+		Data:     input,
+	})
+	assert.NoError(c.t, err)
+	assert.NoError(c.t, c.client.SendTransaction(ctx, tx))
+
+	c.backend.Commit()
+
+	receipt, err := c.client.TransactionReceipt(ctx, tx.Hash())
+	assert.NoError(c.t, err)
+	assert.Equal(c.t, types.ReceiptStatusSuccessful, receipt.Status)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, sub := range c.subs {
+		want := 0
+		for _, vLog := range receipt.Logs {
+			if matchesFilter(sub.query, *vLog) {
+				want++
+			}
+		}
+
+		for sub.delivered[tx.Hash()] < want && !sub.closed {
+			c.cond.Wait()
+		}
+	}
+
+	return receipt
+}
+
+// blockTime returns the time of the block that mined the transaction.
+func (c *simulatedChain) blockTime(txHash common.Hash) time.Time {
+	receipt, err := c.client.TransactionReceipt(context.Background(), txHash)
+	assert.NoError(c.t, err)
+
+	header, err := c.client.HeaderByNumber(context.Background(), receipt.BlockNumber)
+	assert.NoError(c.t, err)
+
+	return time.Unix(int64(header.Time), 0).UTC()
+}
+
+// waitForSubscriber waits until a consumer has subscribed to the chain's logs.
+func (c *simulatedChain) waitForSubscriber() {
+	select {
+	case <-c.subscribed:
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("no subscriber on the simulated chain")
+	}
+}
+
+// SubscribeFilterLogs subscribes on the node and passes the logs on, counting them so that send
+// can wait for their delivery.
+func (c *simulatedChain) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, logsCh chan<- types.Log) (ethereum.Subscription, error) {
+	received := make(chan types.Log)
+	subscription, err := c.client.SubscribeFilterLogs(ctx, query, received)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &simulatedSubscription{
+		Subscription: subscription,
+		query:        query,
+		delivered:    map[common.Hash]int{},
+		done:         make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	c.subs = append(c.subs, sub)
+	c.mu.Unlock()
+
+	go c.forward(sub, received, logsCh)
+
+	select {
+	case c.subscribed <- struct{}{}:
+	default:
+	}
+
+	return sub, nil
+}
+
+func (c *simulatedChain) forward(sub *simulatedSubscription, received <-chan types.Log, logsCh chan<- types.Log) {
+	defer func() {
+		c.mu.Lock()
+		sub.closed = true
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	}()
+
+	for {
+		var vLog types.Log
+		select {
+		case vLog = <-received:
+		case <-sub.done:
+			return
+		}
+
+		select {
+		case logsCh <- vLog:
+		case <-sub.done:
+			return
+		}
+
+		c.mu.Lock()
+		sub.delivered[vLog.TxHash]++
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	}
+}
+
+// Close leaves the node running, it is shared by the services a test restarts and closed on cleanup.
+func (c *simulatedChain) Close() {}
+
+// matchesFilter reports whether vLog matches the addresses and topics of query.
+func matchesFilter(query ethereum.FilterQuery, vLog types.Log) bool {
+	if len(query.Addresses) > 0 && !containsHash(addressesToHashes(query.Addresses), common.BytesToHash(vLog.Address.Bytes())) {
+		return false
+	}
+
+	for i, topics := range query.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if i >= len(vLog.Topics) || !containsHash(topics, vLog.Topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func addressesToHashes(addresses []common.Address) []common.Hash {
+	hashes := []common.Hash{}
+	for _, address := range addresses {
+		hashes = append(hashes, common.BytesToHash(address.Bytes()))
+	}
+
+	return hashes
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+type simulatedSubscription struct {
+	ethereum.Subscription
+	query ethereum.FilterQuery
+
+	// guarded by the chain's mu
+	delivered map[common.Hash]int
+	closed    bool
+
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *simulatedSubscription) Unsubscribe() {
+	s.once.Do(func() { close(s.done) })
+	s.Subscription.Unsubscribe()
+}
+
+// newSimulatedAccount returns the address of a fresh externally owned account, to be funded by
+// passing it to newSimulatedChain.
+func newSimulatedAccount(t *testing.T) common.Address {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	simulatedKeys.Store(address, key)
+
+	return address
+}
+
+func TestSimulatedChainEmitsSwapLogs(t *testing.T) {
+	deployer := newSimulatedAccount(t)
+	trader := newSimulatedAccount(t)
+	chain := newSimulatedChain(t, time.Date(2100, 11, 1, 0, 0, 0, 0, time.UTC), deployer, trader)
+
+	pair := chain.deployPair(deployer)
+	router := chain.deployRouter(deployer, pair)
+
+	txHash := chain.swap(trader, router, trader, big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4))
+
+	logs, err := chain.FilterLogs(context.Background(), ethereum.FilterQuery{Addresses: []common.Address{pair}})
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+
+	// the two deployments were mined in blocks 1 and 2
+	vLog := logs[0]
+	assert.Equal(t, pair, vLog.Address)
+	assert.Equal(t, txHash, vLog.TxHash)
+	assert.Equal(t, uint64(3), vLog.BlockNumber)
+	assert.Equal(t, []common.Hash{swapV2EventSignatureHash, common.BytesToHash(router.Bytes()), common.BytesToHash(trader.Bytes())}, vLog.Topics)
+	assert.Equal(t, fmt.Sprintf("%064x%064x%064x%064x", 1, 2, 3, 4), common.Bytes2Hex(vLog.Data))
+
+	// the origin is recovered from the signed transaction
+	tx, _, err := chain.TransactionByHash(context.Background(), txHash)
+	assert.NoError(t, err)
+
+	origin, err := chain.TransactionSender(context.Background(), tx, vLog.BlockHash, vLog.TxIndex)
+	assert.NoError(t, err)
+	assert.Equal(t, trader, origin)
+
+	assert.Equal(t, chain.genesis.Add(3*simulatedBlockTime), chain.blockTime(txHash))
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"trading-ace/config"
+	"trading-ace/entities"
+	"trading-ace/logger"
+)
+
+const defaultPipelineWorkers = 4
+const defaultPipelineQueueSize = 1000
+
+// swapJob is the scoring work of one log. The log reader decodes and values the swap, a pipeline
+// worker then stores and credits it.
+type swapJob struct {
+	// jobs with the same key run in submission order on the same worker
+	key string
+	// position of the log, committed as the pool's checkpoint once this job and all jobs before it
+	// are done, nil for jobs that do not move the checkpoint
+	position *logPosition
+	// set for reorg reverts, the pool's checkpoint is moved back to position instead
+	rewind bool
+	// safe head the reader has passed, saved for the chain once all jobs before it are done
+	head     *entities.IngestionHead
+	chain    *config.ChainConfig
+	contract string
+	// nil for logs without scoring work, e.g. Sync logs
+	run func() error
+
+	seq uint64
+}
+
+// swapPipeline runs swap jobs on a fixed set of workers with bounded queues. Jobs are routed by
+// key, so the swaps of one address keep their order while different addresses run in parallel.
+// Submit blocks when the worker's queue is full, which slows the log reader down instead of
+// growing memory without bound.
+type swapPipeline struct {
+	logger   logger.ILogger
+	queues   []chan *swapJob
+	onCommit func(job *swapJob)
+	workers  sync.WaitGroup
+
+	// held for reading while submitting, for writing while closing the queues
+	intake sync.RWMutex
+	closed bool
+
+	mu        sync.Mutex
+	nextSeq   uint64
+	committed uint64
+	finished  map[uint64]*swapJob
+	// jobs in submission order waiting for onCommit, run by one worker at a time outside mu
+	ready      []*swapJob
+	committing bool
+}
+
+// newSwapPipeline starts the workers. onCommit is called in submission order, each job only once
+// it and every job submitted before it have finished.
+func newSwapPipeline(logger logger.ILogger, workers int, queueSize int, onCommit func(job *swapJob)) *swapPipeline {
+	if workers <= 0 {
+		workers = defaultPipelineWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultPipelineQueueSize
+	}
+
+	p := &swapPipeline{
+		logger:   logger,
+		queues:   make([]chan *swapJob, workers),
+		onCommit: onCommit,
+		finished: map[uint64]*swapJob{},
+	}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan *swapJob, queueSize)
+
+		p.workers.Add(1)
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+// Submit queues a job on the worker of its key, waiting while that queue is full.
+func (p *swapPipeline) Submit(job *swapJob) error {
+	p.intake.RLock()
+	defer p.intake.RUnlock()
+
+	if p.closed {
+		return fmt.Errorf("swap pipeline is closed")
+	}
+
+	p.mu.Lock()
+	p.nextSeq++
+	job.seq = p.nextSeq
+	p.mu.Unlock()
+
+	queue := p.queues[p.route(job.key)]
+	select {
+	case queue <- job:
+	default:
+		p.logger.Warn(fmt.Sprintf("swap queue is full (depth %d/%d), waiting for workers", p.Depth(), p.Capacity()))
+		queue <- job
+	}
+
+	return nil
+}
+
+// Depth returns the number of queued jobs that no worker has picked up yet.
+func (p *swapPipeline) Depth() int {
+	depth := 0
+	for _, queue := range p.queues {
+		depth += len(queue)
+	}
+
+	return depth
+}
+
+// Capacity returns the number of jobs the queues hold before Submit blocks.
+func (p *swapPipeline) Capacity() int {
+	return len(p.queues) * cap(p.queues[0])
+}
+
+// Drain stops accepting jobs and waits until the queued ones have run, or until ctx is done.
+func (p *swapPipeline) Drain(ctx context.Context) error {
+	p.intake.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.intake.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("swap pipeline drain interrupted with %d jobs queued: %w", p.Depth(), ctx.Err())
+	}
+}
+
+func (p *swapPipeline) route(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(key)))
+
+	return int(hash.Sum32() % uint32(len(p.queues)))
+}
+
+func (p *swapPipeline) work(queue <-chan *swapJob) {
+	defer p.workers.Done()
+
+	for job := range queue {
+		if job.run != nil {
+			if err := job.run(); err != nil {
+				p.logger.Error(err)
+			}
+		}
+
+		p.finish(job)
+	}
+}
+
+// finish records a finished job and commits the longest run of finished jobs in submission order.
+// onCommit does I/O, so it runs outside mu: the worker that finds no commit in progress commits the
+// ready jobs, including those other workers make ready meanwhile, while the others return to their queue.
+func (p *swapPipeline) finish(job *swapJob) {
+	p.mu.Lock()
+	p.finished[job.seq] = job
+	for {
+		next, ok := p.finished[p.committed+1]
+		if !ok {
+			break
+		}
+
+		delete(p.finished, next.seq)
+		p.committed = next.seq
+		p.ready = append(p.ready, next)
+	}
+
+	if p.committing || len(p.ready) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.committing = true
+
+	for len(p.ready) > 0 {
+		jobs := p.ready
+		p.ready = nil
+		p.mu.Unlock()
+
+		if p.onCommit != nil {
+			for _, next := range jobs {
+				p.onCommit(next)
+			}
+		}
+
+		p.mu.Lock()
+	}
+
+	p.committing = false
+	p.mu.Unlock()
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/entities"
+	"trading-ace/mocks"
+	"trading-ace/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSwapPipelineKeepsOrderPerKey(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockLogger.On("Warn", mock.Anything).Return()
+
+	var mu sync.Mutex
+	ran := map[string][]int{}
+	committed := []uint64{}
+
+	p := newSwapPipeline(mockLogger, 4, 2, func(job *swapJob) {
+		committed = append(committed, job.position.BlockNumber)
+	})
+
+	block := uint64(0)
+	for i := 0; i < 50; i++ {
+		for _, key := range []string{"0xa", "0xb", "0xc"} {
+			i, key := i, key
+			block++
+			assert.NoError(t, p.Submit(&swapJob{
+				key:      key,
+				position: &logPosition{BlockNumber: block},
+				run: func() error {
+					mu.Lock()
+					defer mu.Unlock()
+					ran[key] = append(ran[key], i)
+					return nil
+				},
+			}))
+		}
+	}
+
+	assert.NoError(t, p.Drain(context.Background()))
+
+	// every key ran its jobs in submission order
+	for _, key := range []string{"0xa", "0xb", "0xc"} {
+		assert.Len(t, ran[key], 50)
+		for i, value := range ran[key] {
+			assert.Equal(t, i, value)
+		}
+	}
+
+	// checkpoints are committed in log order, whichever worker finished first
+	assert.Len(t, committed, 150)
+	for i, value := range committed {
+		assert.Equal(t, uint64(i+1), value)
+	}
+
+	assert.Error(t, p.Submit(&swapJob{key: "0xa"}))
+}
+
+func TestSwapPipelineCommitsOutsideTheLock(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+
+	release := make(chan struct{})
+	committing := make(chan struct{})
+	committed := []uint64{}
+
+	p := newSwapPipeline(mockLogger, 2, 2, func(job *swapJob) {
+		if job.position.BlockNumber == 1 {
+			close(committing)
+			<-release
+		}
+		committed = append(committed, job.position.BlockNumber)
+	})
+
+	assert.NoError(t, p.Submit(&swapJob{key: "0xa", position: &logPosition{BlockNumber: 1}}))
+	<-committing
+
+	// a slow commit neither blocks the intake nor the other workers
+	ran := make(chan struct{})
+	submitted := make(chan struct{})
+	go func() {
+		p.Submit(&swapJob{key: "0xb", position: &logPosition{BlockNumber: 2}, run: func() error {
+			close(ran)
+			return nil
+		}})
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Fatal("expected Submit not to wait for the commit")
+	}
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("expected the job to run while the commit is in progress")
+	}
+
+	close(release)
+	assert.NoError(t, p.Drain(context.Background()))
+	assert.Equal(t, []uint64{1, 2}, committed)
+}
+
+func TestSwapPipelineBackpressureAndDepth(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockLogger.On("Warn", mock.Anything).Return()
+	mockLogger.On("Error", mock.Anything).Return()
+
+	release := make(chan struct{})
+	p := newSwapPipeline(mockLogger, 1, 2, nil)
+	assert.Equal(t, 2, p.Capacity())
+
+	blocking := func() error {
+		<-release
+		return fmt.Errorf("credit failed")
+	}
+
+	// the first job is picked up by the worker, the next two fill the queue
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, p.Submit(&swapJob{key: "0xa", run: blocking}))
+	}
+	assert.Eventually(t, func() bool { return p.Depth() == 2 }, time.Second, time.Millisecond)
+
+	// a full queue blocks the submitter
+	submitted := make(chan struct{})
+	go func() {
+		p.Submit(&swapJob{key: "0xa", run: blocking})
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+		t.Fatal("expected Submit to block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-submitted
+
+	assert.NoError(t, p.Drain(context.Background()))
+	assert.Equal(t, 0, p.Depth())
+
+	// failed jobs are logged, they do not stop the worker
+	mockLogger.AssertNumberOfCalls(t, "Error", 4)
+}
+
+func TestSwapPipelineDrainTimeout(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	p := newSwapPipeline(mockLogger, 1, 1, nil)
+	assert.NoError(t, p.Submit(&swapJob{key: "0xa", run: func() error {
+		<-release
+		return nil
+	}}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.Error(t, p.Drain(ctx))
+}
+
+func TestShutdownDrainsQueuedSwaps(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockCampaignService := new(mocks.MockCampaignService)
+	mockSwapEventRepo := new(mocks.MockSwapEventRepository)
+	mockCheckpointRepo := new(mocks.MockIngestionCheckpointRepository)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockSwapEventRepo.On("CreateIfNotExists", mock.Anything).Return(true, nil)
+	mockCampaignService.On("RecordUSDCSwapTotalAmount", mock.Anything, "0xSenderAddress", mock.Anything, mock.Anything).Return(decimal.NewFromInt(3000), nil)
+	mockCampaignService.On("FindSharePoolTasksAt", config.MainnetChainID, mock.Anything, mock.Anything).Return([]*entities.Task{{ID: 1, Period: 1}}, nil)
+
+	// the checkpoint only moves once the swap has been credited
+	mockCheckpointRepo.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		mockCampaignService.AssertNumberOfCalls(t, "RecordUSDCSwapTotalAmount", 1)
+	})
+
+	e := &EthereumService{
+		logger:          mockLogger,
+		config:          &config.Config{},
+		chain:           newTestChain(),
+		campaignService: mockCampaignService,
+		swapEventRepo:   mockSwapEventRepo,
+		checkpointRepo:  mockCheckpointRepo,
+		stopCh:          make(chan struct{}),
+		sourceDone:      make(chan struct{}),
+	}
+	e.pipeline = newSwapPipeline(mockLogger, 2, 10, e.commitJob)
+	close(e.sourceDone)
+
+	credit, err := e.valueSwapEvent(&models.SwapEvent{
+		ChainID:       config.MainnetChainID,
+		TxHash:        "0xabc",
+		PoolAddress:   testPoolAddress,
+		SenderAddress: "0xSenderAddress",
+		Amount0In:     big.NewInt(3000000000),
+		Amount0Out:    big.NewInt(0),
+		Amount1In:     big.NewInt(0),
+		Amount1Out:    big.NewInt(1e18),
+	})
+	assert.NoError(t, err)
+
+	e.dispatch(&swapJob{key: "0xSenderAddress", position: &logPosition{BlockNumber: 100}, contract: testPoolAddress, chain: e.chain, run: credit})
+
+	assert.NoError(t, e.Shutdown(context.Background()))
+	assert.True(t, e.isStopping())
+	mockCampaignService.AssertExpectations(t)
+	mockCheckpointRepo.AssertNumberOfCalls(t, "Save", 1)
+}
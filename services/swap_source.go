@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ISwapSource feeds swap logs into the EthereumService processing path.
+type ISwapSource interface {
+	Run(parsedABI IABI) error
+}
+
+const rpcSourceType string = "rpc"
+const replaySourceType string = "replay"
+
+func (e *EthereumService) newSwapSource() ISwapSource {
+	if e.config.Source.Type == replaySourceType {
+		return &replaySwapSource{
+			service: e,
+			path:    e.chain.ReplayFile,
+			speed:   e.config.Source.ReplaySpeed,
+		}
+	}
+
+	return &rpcSwapSource{service: e}
+}
+
+// rpcSwapSource reads swap logs from the chain's node. It subscribes over websockets or polls
+// over HTTP, and reconnects with backoff resuming from the last processed log.
+type rpcSwapSource struct {
+	service *EthereumService
+}
+
+func (r *rpcSwapSource) Run(parsedABI IABI) error {
+	e := r.service
+
+	resumed := false
+	backoff := minReconnectBackoff
+	for {
+		var err error
+		if !resumed {
+			// retried until the checkpoints are read, starting from the live head would skip the gap
+			err = e.resumeFromCheckpoint()
+			resumed = err == nil
+		}
+
+		var client IEthereumClient
+		if err == nil {
+			client, err = e.connectToClient()
+		}
+
+		if err == nil {
+			var subscribed bool
+			err = e.resolvePools(client)
+			if err == nil && isWebsocketURL(e.rpcURL()) {
+				subscribed, err = e.consumeSwapEvents(client, parsedABI)
+			} else if err == nil {
+				subscribed, err = e.pollSwapEvents(client, parsedABI)
+			}
+
+			client.Close()
+
+			// a config that does not match the chain does not fix itself on reconnect
+			if errors.Is(err, errPoolTokensMismatch) {
+				return err
+			}
+
+			if subscribed {
+				backoff = minReconnectBackoff
+			}
+		}
+
+		if e.isStopping() {
+			return nil
+		}
+
+		e.logger.Warn(fmt.Sprintf("%s swap subscription interrupted: %v, reconnecting in %s", e.chain.Name, err, backoff))
+
+		select {
+		case <-e.stopCh:
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff = nextReconnectBackoff(backoff)
+	}
+}
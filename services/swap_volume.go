@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"trading-ace/config"
+	"trading-ace/decimal"
+	"trading-ace/models"
+)
+
+const volumeAccountingQuote string = "quote"
+const volumeAccountingBoth string = "both"
+const volumeAccountingInput string = "input"
+
+// swapLegs holds a swap's exact amounts in whole tokens, split into the quote stablecoin and the other token.
+type swapLegs struct {
+	QuoteIn  *big.Rat
+	QuoteOut *big.Rat
+	BaseIn   *big.Rat
+	BaseOut  *big.Rat
+}
+
+func newSwapLegs(event *models.SwapEvent, pool *config.PoolConfig) *swapLegs {
+	amount0In := decimal.TokenAmount(event.Amount0In, pool.Token0.Decimals)
+	amount0Out := decimal.TokenAmount(event.Amount0Out, pool.Token0.Decimals)
+	amount1In := decimal.TokenAmount(event.Amount1In, pool.Token1.Decimals)
+	amount1Out := decimal.TokenAmount(event.Amount1Out, pool.Token1.Decimals)
+
+	if pool.Quote == quoteToken1 {
+		return &swapLegs{QuoteIn: amount1In, QuoteOut: amount1Out, BaseIn: amount0In, BaseOut: amount0Out}
+	}
+
+	return &swapLegs{QuoteIn: amount0In, QuoteOut: amount0Out, BaseIn: amount1In, BaseOut: amount1Out}
+}
+
+// swapVolume returns the USD volume credited for one swap according to volume.accounting, and the
+// USD price the non-stablecoin leg was valued at:
+//   - quote: the stablecoin leg only
+//   - both: the stablecoin leg plus the other leg priced in USD
+//   - input: only the token the trader paid in
+//
+// The other leg is priced from the pool reserves of the latest Sync log, pools without one yet
+// (V3 pools, or before the first Sync) fall back to the swap's own execution price. The volume is
+// computed exactly from the raw token amounts and only rounded once, to a decimal unit.
+func (e *EthereumService) swapVolume(event *models.SwapEvent, pool *config.PoolConfig) (decimal.Decimal, decimal.Decimal, error) {
+	legs := newSwapLegs(event, pool)
+
+	quoteLeg := new(big.Rat).Add(legs.QuoteIn, legs.QuoteOut)
+	baseLeg := new(big.Rat).Add(legs.BaseIn, legs.BaseOut)
+
+	price := e.poolPrice(event.PoolAddress)
+	if price == nil {
+		price = new(big.Rat)
+		if baseLeg.Sign() > 0 {
+			price.Quo(quoteLeg, baseLeg)
+		}
+	}
+
+	var volume *big.Rat
+	switch e.config.Volume.Accounting {
+	case "", volumeAccountingQuote:
+		volume = quoteLeg
+	case volumeAccountingBoth:
+		volume = new(big.Rat).Add(quoteLeg, new(big.Rat).Mul(baseLeg, price))
+	case volumeAccountingInput:
+		if legs.QuoteIn.Sign() > 0 {
+			volume = legs.QuoteIn
+		} else {
+			volume = new(big.Rat).Mul(legs.BaseIn, price)
+		}
+	default:
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("unknown volume accounting %q", e.config.Volume.Accounting)
+	}
+
+	return decimal.FromRat(volume), decimal.FromRat(price), nil
+}
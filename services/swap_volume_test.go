@@ -0,0 +1,59 @@
+package services
+
+import (
+	"math/big"
+	"testing"
+	"trading-ace/config"
+	"trading-ace/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwapVolume(t *testing.T) {
+	pool := &newTestPools()[0]
+
+	// 3,000 USDC in, 1 WETH out
+	event := &models.SwapEvent{
+		Amount0In:  big.NewInt(3000000000),
+		Amount0Out: big.NewInt(0),
+		Amount1In:  big.NewInt(0),
+		Amount1Out: new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+	}
+
+	tests := []struct {
+		accounting string
+		expected   string
+	}{
+		{"", "3000"},
+		{volumeAccountingQuote, "3000"},
+		{volumeAccountingBoth, "6000"},
+		{volumeAccountingInput, "3000"},
+	}
+
+	for _, tt := range tests {
+		e := &EthereumService{config: &config.Config{Volume: config.VolumeConfig{Accounting: tt.accounting}}}
+
+		volume, _, err := e.swapVolume(event, pool)
+		assert.NoError(t, err)
+
+		assert.Equal(t, tt.expected, volume.String(), "accounting %q", tt.accounting)
+	}
+
+	// WETH in, USDC out: the input side is the WETH leg priced in USD
+	reversed := &models.SwapEvent{
+		Amount0In:  big.NewInt(0),
+		Amount0Out: big.NewInt(1500000000),
+		Amount1In:  new(big.Int).Div(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil), big.NewInt(2)),
+		Amount1Out: big.NewInt(0),
+	}
+
+	e := &EthereumService{config: &config.Config{Volume: config.VolumeConfig{Accounting: volumeAccountingInput}}}
+	volume, _, err := e.swapVolume(reversed, pool)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "1500", volume.String())
+
+	e.config.Volume.Accounting = "unknown"
+	_, _, err = e.swapVolume(event, pool)
+	assert.Error(t, err)
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"trading-ace/config"
+	"trading-ace/entities"
+	"trading-ace/helpers"
+	"trading-ace/logger"
+	"trading-ace/models"
+	"trading-ace/repositories"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// IContractCaller runs read-only contract calls, it is implemented by the chain clients.
+type IContractCaller interface {
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type ITokenRegistry interface {
+	PoolTokens(chainID int64, pool config.PoolConfig, caller IContractCaller) (*models.PoolTokens, error)
+}
+
+// TokenRegistry knows the tokens of the tracked pools. They are looked up in Redis, then in
+// Postgres, then taken from the pool config or called on chain, and stored for next time.
+type TokenRegistry struct {
+	logger      logger.ILogger
+	tokenRepo   repositories.ITokenRepository
+	redisHelper helpers.IRedisHelper
+}
+
+// errPoolTokensMismatch is returned when the tokens seeded in a pool config are not the pool's tokens
+// on chain, such a config would value every swap of the pool wrong.
+var errPoolTokensMismatch = errors.New("configured pool tokens do not match the chain")
+
+// token metadata never changes, the cache only bounds memory
+const poolTokensCacheTTL = 24 * time.Hour
+
+// the parts of the pair and ERC-20 interfaces the registry calls
+const tokenRegistryABI = `[
+	{"constant":true,"inputs":[],"name":"token0","outputs":[{"name":"","type":"address"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"token1","outputs":[{"name":"","type":"address"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"}
+]`
+
+func NewTokenRegistry(logger logger.ILogger, tokenRepo repositories.ITokenRepository, redisHelper helpers.IRedisHelper) ITokenRegistry {
+	return &TokenRegistry{
+		logger:      logger,
+		tokenRepo:   tokenRepo,
+		redisHelper: redisHelper,
+	}
+}
+
+// PoolTokens returns the tokens of a pool on chainID. Pools the registry has not seen yet are seeded
+// from the pool config when it gives both tokens with their address, and resolved with caller
+// otherwise. Seeded addresses are checked against token0() and token1() of the pool when there is a
+// caller, a mismatch is returned as it is. Without a caller, a config giving the symbols and decimals
+// only is used as it is.
+func (r *TokenRegistry) PoolTokens(chainID int64, pool config.PoolConfig, caller IContractCaller) (*models.PoolTokens, error) {
+	address := common.HexToAddress(pool.Address).Hex()
+	key := fmt.Sprintf("pool_tokens_%d_%s", chainID, address)
+
+	redisData, err := r.redisHelper.Get(key)
+	if err == nil {
+		tokens := &models.PoolTokens{}
+		if err := json.Unmarshal([]byte(redisData), tokens); err == nil {
+			return tokens, nil
+		}
+	}
+
+	tokens, err := r.registeredPool(chainID, address)
+	if err != nil {
+		tokens, err = r.registerPool(chainID, address, pool, caller)
+		if errors.Is(err, errPoolTokensMismatch) {
+			return nil, err
+		}
+		if err != nil {
+			return r.configuredPool(chainID, address, pool, err)
+		}
+	}
+
+	if encoded, err := json.Marshal(tokens); err == nil {
+		r.redisHelper.Set(key, string(encoded), poolTokensCacheTTL)
+	}
+
+	return tokens, nil
+}
+
+func (r *TokenRegistry) registeredPool(chainID int64, address string) (*models.PoolTokens, error) {
+	pool, err := r.tokenRepo.FindPool(chainID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	token0, err := r.tokenRepo.FindToken(chainID, pool.Token0Address)
+	if err != nil {
+		return nil, err
+	}
+
+	token1, err := r.tokenRepo.FindToken(chainID, pool.Token1Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PoolTokens{Pool: pool, Token0: token0, Token1: token1}, nil
+}
+
+// registerPool resolves a pool the registry has not seen and stores it.
+func (r *TokenRegistry) registerPool(chainID int64, address string, poolConfig config.PoolConfig, caller IContractCaller) (*models.PoolTokens, error) {
+	var tokens *models.PoolTokens
+	if isSeed(poolConfig.Token0) && isSeed(poolConfig.Token1) {
+		tokens = &models.PoolTokens{
+			Pool:   &entities.Pool{ChainID: chainID, Address: address},
+			Token0: seedToken(chainID, poolConfig.Token0),
+			Token1: seedToken(chainID, poolConfig.Token1),
+		}
+
+		if caller != nil {
+			if err := verifyPoolTokens(chainID, address, tokens, caller); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if caller == nil {
+			return nil, fmt.Errorf("pool %s on chain %d is not registered and there is no client to resolve it", address, chainID)
+		}
+
+		resolved, err := r.resolvePool(chainID, address, poolConfig, caller)
+		if err != nil {
+			return nil, err
+		}
+		tokens = resolved
+	}
+
+	tokens.Pool.Token0Address = tokens.Token0.Address
+	tokens.Pool.Token1Address = tokens.Token1.Address
+
+	// the tokens are known now, a failed write only costs a lookup next time
+	for _, token := range []*entities.Token{tokens.Token0, tokens.Token1} {
+		if err := r.tokenRepo.CreateToken(token); err != nil {
+			r.logger.Error(err)
+		}
+	}
+	if err := r.tokenRepo.CreatePool(tokens.Pool); err != nil {
+		r.logger.Error(err)
+	}
+
+	r.logger.Info(fmt.Sprintf("registered pool %s on chain %d: %s (%d decimals) / %s (%d decimals)", address, chainID,
+		tokens.Token0.Symbol, tokens.Token0.Decimals, tokens.Token1.Symbol, tokens.Token1.Decimals))
+
+	return tokens, nil
+}
+
+// resolvePool calls token0() and token1() on the pool and resolves both tokens.
+func (r *TokenRegistry) resolvePool(chainID int64, address string, poolConfig config.PoolConfig, caller IContractCaller) (*models.PoolTokens, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(tokenRegistryABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token registry ABI: %v", err)
+	}
+
+	tokens := &models.PoolTokens{Pool: &entities.Pool{ChainID: chainID, Address: address}}
+	for i, method := range []string{"token0", "token1"} {
+		var tokenAddress common.Address
+		if err := callContract(caller, parsedABI, common.HexToAddress(address), method, &tokenAddress); err != nil {
+			return nil, err
+		}
+
+		token, err := r.resolveToken(chainID, tokenAddress, []config.TokenConfig{poolConfig.Token0, poolConfig.Token1}, caller, parsedABI)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			tokens.Token0 = token
+		} else {
+			tokens.Token1 = token
+		}
+	}
+
+	return tokens, nil
+}
+
+// verifyPoolTokens calls token0() and token1() on the pool and compares them with the seeded tokens.
+func verifyPoolTokens(chainID int64, address string, tokens *models.PoolTokens, caller IContractCaller) error {
+	parsedABI, err := abi.JSON(strings.NewReader(tokenRegistryABI))
+	if err != nil {
+		return fmt.Errorf("failed to parse token registry ABI: %v", err)
+	}
+
+	for i, method := range []string{"token0", "token1"} {
+		seeded := tokens.Token0
+		if i == 1 {
+			seeded = tokens.Token1
+		}
+
+		var tokenAddress common.Address
+		if err := callContract(caller, parsedABI, common.HexToAddress(address), method, &tokenAddress); err != nil {
+			return err
+		}
+
+		if tokenAddress.Hex() != seeded.Address {
+			return fmt.Errorf("%w: %s() of pool %s on chain %d is %s, the config gives %s (%s)", errPoolTokensMismatch,
+				method, address, chainID, tokenAddress.Hex(), seeded.Address, seeded.Symbol)
+		}
+	}
+
+	return nil
+}
+
+// resolveToken returns a registered token, a token seeded in the pool config, or calls decimals()
+// and symbol() on the token.
+func (r *TokenRegistry) resolveToken(chainID int64, address common.Address, seeds []config.TokenConfig, caller IContractCaller, parsedABI abi.ABI) (*entities.Token, error) {
+	if token, err := r.tokenRepo.FindToken(chainID, address.Hex()); err == nil {
+		return token, nil
+	}
+
+	for _, seed := range seeds {
+		if isSeed(seed) && common.HexToAddress(seed.Address) == address {
+			return seedToken(chainID, seed), nil
+		}
+	}
+
+	var decimals uint8
+	if err := callContract(caller, parsedABI, address, "decimals", &decimals); err != nil {
+		return nil, err
+	}
+
+	symbol, err := callSymbol(caller, parsedABI, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.Token{ChainID: chainID, Address: address.Hex(), Symbol: symbol, Decimals: int64(decimals)}, nil
+}
+
+// configuredPool falls back to a pool config giving the symbols and decimals without addresses,
+// such pools keep working offline but are not registered.
+func (r *TokenRegistry) configuredPool(chainID int64, address string, poolConfig config.PoolConfig, cause error) (*models.PoolTokens, error) {
+	for _, token := range []config.TokenConfig{poolConfig.Token0, poolConfig.Token1} {
+		if token.Symbol == "" || token.Decimals <= 0 {
+			return nil, fmt.Errorf("failed to resolve the tokens of pool %s on chain %d: %w", address, chainID, cause)
+		}
+	}
+
+	r.logger.Warn(fmt.Sprintf("using the configured tokens of pool %s on chain %d: %v", address, chainID, cause))
+
+	return &models.PoolTokens{
+		Pool:   &entities.Pool{ChainID: chainID, Address: address},
+		Token0: &entities.Token{ChainID: chainID, Symbol: poolConfig.Token0.Symbol, Decimals: poolConfig.Token0.Decimals},
+		Token1: &entities.Token{ChainID: chainID, Symbol: poolConfig.Token1.Symbol, Decimals: poolConfig.Token1.Decimals},
+	}, nil
+}
+
+// isSeed reports whether a token config fully describes a token. A config without decimals is
+// resolved on chain, 0 decimals would take raw amounts for whole tokens.
+func isSeed(token config.TokenConfig) bool {
+	return token.Address != "" && token.Symbol != "" && token.Decimals > 0
+}
+
+func seedToken(chainID int64, token config.TokenConfig) *entities.Token {
+	return &entities.Token{
+		ChainID:  chainID,
+		Address:  common.HexToAddress(token.Address).Hex(),
+		Symbol:   token.Symbol,
+		Decimals: token.Decimals,
+	}
+}
+
+func callContract(caller IContractCaller, parsedABI abi.ABI, contract common.Address, method string, result interface{}) error {
+	output, err := callMethod(caller, parsedABI, contract, method)
+	if err != nil {
+		return err
+	}
+
+	values, err := parsedABI.Unpack(method, output)
+	if err != nil || len(values) != 1 {
+		return fmt.Errorf("failed to decode %s() of %s: %v", method, contract.Hex(), err)
+	}
+	abi.ConvertType(values[0], result)
+
+	return nil
+}
+
+func callMethod(caller IContractCaller, parsedABI abi.ABI, contract common.Address, method string) ([]byte, error) {
+	input, err := parsedABI.Pack(method)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := caller.CallContract(context.Background(), ethereum.CallMsg{To: &contract, Data: input}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s() on %s: %v", method, contract.Hex(), err)
+	}
+
+	return output, nil
+}
+
+// callSymbol calls symbol(), some older tokens such as MKR return a bytes32 instead of a string.
+func callSymbol(caller IContractCaller, parsedABI abi.ABI, contract common.Address) (string, error) {
+	output, err := callMethod(caller, parsedABI, contract, "symbol")
+	if err != nil {
+		return "", err
+	}
+
+	if values, err := parsedABI.Unpack("symbol", output); err == nil && len(values) == 1 {
+		return values[0].(string), nil
+	}
+
+	if len(output) == 32 {
+		return strings.TrimRight(string(output), "\x00"), nil
+	}
+
+	return "", fmt.Errorf("failed to decode symbol() of %s", contract.Hex())
+}
+
+// resolvePools fills the tokens of the chain's pools from the token registry, once per service,
+// and turns a quote given as a token symbol or address into token0 or token1. The client may be
+// nil when the chain is not read from its node.
+func (e *EthereumService) resolvePools(client IEthereumClient) error {
+	if e.tokenRegistry == nil {
+		return nil
+	}
+
+	e.poolsMu.Lock()
+	defer e.poolsMu.Unlock()
+
+	if e.poolsResolved {
+		return nil
+	}
+
+	var caller IContractCaller
+	if client != nil {
+		caller = client
+	}
+
+	for i := range e.chain.Pools {
+		pool := &e.chain.Pools[i]
+
+		tokens, err := e.tokenRegistry.PoolTokens(e.chain.ID, *pool, caller)
+		if err != nil {
+			return err
+		}
+
+		quote, err := quoteSide(pool.Quote, tokens)
+		if err != nil {
+			return fmt.Errorf("pool %s on chain %s: %w", pool.Address, e.chain.Name, err)
+		}
+
+		pool.Token0 = config.TokenConfig{Address: tokens.Token0.Address, Symbol: tokens.Token0.Symbol, Decimals: tokens.Token0.Decimals}
+		pool.Token1 = config.TokenConfig{Address: tokens.Token1.Address, Symbol: tokens.Token1.Symbol, Decimals: tokens.Token1.Decimals}
+		pool.Quote = quote
+	}
+
+	e.poolsResolved = true
+
+	return nil
+}
+
+// quoteSide returns the side of the quote token of a pool.
+func quoteSide(quote string, tokens *models.PoolTokens) (string, error) {
+	switch {
+	case quote == "" || quote == quoteToken0 || quote == quoteToken1:
+		return quote, nil
+	case matchesToken(quote, tokens.Token0):
+		return quoteToken0, nil
+	case matchesToken(quote, tokens.Token1):
+		return quoteToken1, nil
+	}
+
+	return "", fmt.Errorf("quote %s is neither token of the pool (%s/%s)", quote, tokens.Token0.Symbol, tokens.Token1.Symbol)
+}
+
+func matchesToken(quote string, token *entities.Token) bool {
+	if common.IsHexAddress(quote) {
+		return token.Address != "" && common.HexToAddress(quote) == common.HexToAddress(token.Address)
+	}
+
+	return strings.EqualFold(quote, token.Symbol)
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"trading-ace/config"
+	"trading-ace/entities"
+	"trading-ace/mocks"
+	"trading-ace/models"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testWETHAddress = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+const testUSDCAddress = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+
+func testPoolTokensKey() string {
+	return fmt.Sprintf("pool_tokens_%d_%s", config.MainnetChainID, common.HexToAddress(testPoolAddress).Hex())
+}
+
+// onCall stubs a contract call on the client, matched by contract and method.
+func onCall(t *testing.T, client *mocks.MockEthereumClient, contract string, method string, values ...interface{}) {
+	parsedABI, err := abi.JSON(strings.NewReader(tokenRegistryABI))
+	assert.NoError(t, err)
+
+	output, err := parsedABI.Methods[method].Outputs.Pack(values...)
+	assert.NoError(t, err)
+
+	client.On("CallContract", mock.Anything, mock.MatchedBy(func(call ethereum.CallMsg) bool {
+		return *call.To == common.HexToAddress(contract) && bytes.Equal(call.Data, parsedABI.Methods[method].ID)
+	}), mock.Anything).Return(output, nil)
+}
+
+func TestPoolTokensFromCache(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+
+	cached, _ := json.Marshal(&models.PoolTokens{
+		Pool:   &entities.Pool{ChainID: config.MainnetChainID, Address: testPoolAddress},
+		Token0: &entities.Token{Symbol: "USDC", Decimals: 6},
+		Token1: &entities.Token{Symbol: "WETH", Decimals: 18},
+	})
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return(string(cached), nil)
+
+	registry := NewTokenRegistry(new(mocks.MockLogger), mockTokenRepo, mockRedisHelper)
+
+	tokens, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{Address: testPoolAddress}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USDC", tokens.Token0.Symbol)
+	assert.Equal(t, int64(18), tokens.Token1.Decimals)
+	mockTokenRepo.AssertNotCalled(t, "FindPool", mock.Anything, mock.Anything)
+}
+
+func TestPoolTokensFromRepository(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+
+	poolAddress := common.HexToAddress(testPoolAddress).Hex()
+
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return("", fmt.Errorf("redis: nil"))
+	mockRedisHelper.On("Set", testPoolTokensKey(), mock.Anything, poolTokensCacheTTL).Return(nil)
+	mockTokenRepo.On("FindPool", config.MainnetChainID, poolAddress).Return(&entities.Pool{
+		ChainID: config.MainnetChainID, Address: poolAddress, Token0Address: testUSDCAddress, Token1Address: testWETHAddress,
+	}, nil)
+	mockTokenRepo.On("FindToken", config.MainnetChainID, testUSDCAddress).Return(&entities.Token{Address: testUSDCAddress, Symbol: "USDC", Decimals: 6}, nil)
+	mockTokenRepo.On("FindToken", config.MainnetChainID, testWETHAddress).Return(&entities.Token{Address: testWETHAddress, Symbol: "WETH", Decimals: 18}, nil)
+
+	registry := NewTokenRegistry(new(mocks.MockLogger), mockTokenRepo, mockRedisHelper)
+
+	tokens, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{Address: testPoolAddress}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testUSDCAddress, tokens.Token0.Address)
+	assert.Equal(t, "WETH", tokens.Token1.Symbol)
+	mockRedisHelper.AssertExpectations(t)
+	mockTokenRepo.AssertNotCalled(t, "CreatePool", mock.Anything)
+}
+
+func TestPoolTokensResolvedOnChain(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+	mockLogger := new(mocks.MockLogger)
+	mockClient := new(mocks.MockEthereumClient)
+
+	poolAddress := common.HexToAddress(testPoolAddress).Hex()
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return("", fmt.Errorf("redis: nil"))
+	mockRedisHelper.On("Set", testPoolTokensKey(), mock.Anything, poolTokensCacheTTL).Return(nil)
+	mockTokenRepo.On("FindPool", config.MainnetChainID, poolAddress).Return((*entities.Pool)(nil), fmt.Errorf("pool not found"))
+	mockTokenRepo.On("FindToken", config.MainnetChainID, mock.Anything).Return((*entities.Token)(nil), fmt.Errorf("token not found"))
+	mockTokenRepo.On("CreateToken", mock.Anything).Return(nil)
+	mockTokenRepo.On("CreatePool", mock.MatchedBy(func(pool *entities.Pool) bool {
+		return pool.Address == poolAddress && pool.Token0Address == testWETHAddress && pool.Token1Address == testUSDCAddress
+	})).Return(nil).Once()
+
+	// WETH sorts first in this pool, and the USDC symbol is returned as a bytes32 like older tokens do
+	onCall(t, mockClient, testPoolAddress, "token0", common.HexToAddress(testWETHAddress))
+	onCall(t, mockClient, testPoolAddress, "token1", common.HexToAddress(testUSDCAddress))
+	onCall(t, mockClient, testWETHAddress, "decimals", uint8(18))
+	onCall(t, mockClient, testWETHAddress, "symbol", "WETH")
+	onCall(t, mockClient, testUSDCAddress, "decimals", uint8(6))
+	mockClient.On("CallContract", mock.Anything, mock.MatchedBy(func(call ethereum.CallMsg) bool {
+		return *call.To == common.HexToAddress(testUSDCAddress) && bytes.Equal(call.Data, []byte{0x95, 0xd8, 0x9b, 0x41})
+	}), mock.Anything).Return(common.RightPadBytes([]byte("USDC"), 32), nil)
+
+	registry := NewTokenRegistry(mockLogger, mockTokenRepo, mockRedisHelper)
+
+	tokens, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{Address: testPoolAddress}, mockClient)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &entities.Token{ChainID: config.MainnetChainID, Address: testWETHAddress, Symbol: "WETH", Decimals: 18}, tokens.Token0)
+	assert.Equal(t, &entities.Token{ChainID: config.MainnetChainID, Address: testUSDCAddress, Symbol: "USDC", Decimals: 6}, tokens.Token1)
+	mockTokenRepo.AssertNumberOfCalls(t, "CreateToken", 2)
+	mockTokenRepo.AssertExpectations(t)
+	mockRedisHelper.AssertExpectations(t)
+}
+
+func TestPoolTokensSeededFromConfig(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+	mockLogger := new(mocks.MockLogger)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return("", fmt.Errorf("redis: nil"))
+	mockRedisHelper.On("Set", testPoolTokensKey(), mock.Anything, poolTokensCacheTTL).Return(nil)
+	mockTokenRepo.On("FindPool", config.MainnetChainID, mock.Anything).Return((*entities.Pool)(nil), fmt.Errorf("pool not found"))
+	mockTokenRepo.On("CreateToken", mock.Anything).Return(nil)
+	mockTokenRepo.On("CreatePool", mock.Anything).Return(nil)
+
+	registry := NewTokenRegistry(mockLogger, mockTokenRepo, mockRedisHelper)
+
+	// seeded tokens need no client
+	tokens, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{
+		Address: testPoolAddress,
+		Token0:  config.TokenConfig{Address: strings.ToLower(testUSDCAddress), Symbol: "USDC", Decimals: 6},
+		Token1:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testUSDCAddress, tokens.Token0.Address)
+	assert.Equal(t, testUSDCAddress, tokens.Pool.Token0Address)
+	assert.Equal(t, int64(18), tokens.Token1.Decimals)
+	mockTokenRepo.AssertNumberOfCalls(t, "CreateToken", 2)
+	mockTokenRepo.AssertNumberOfCalls(t, "CreatePool", 1)
+}
+
+func TestPoolTokensSeedVerifiedOnChain(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+	mockLogger := new(mocks.MockLogger)
+	mockClient := new(mocks.MockEthereumClient)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return("", fmt.Errorf("redis: nil"))
+	mockRedisHelper.On("Set", testPoolTokensKey(), mock.Anything, poolTokensCacheTTL).Return(nil)
+	mockTokenRepo.On("FindPool", config.MainnetChainID, mock.Anything).Return((*entities.Pool)(nil), fmt.Errorf("pool not found"))
+	mockTokenRepo.On("CreateToken", mock.Anything).Return(nil)
+	mockTokenRepo.On("CreatePool", mock.Anything).Return(nil)
+
+	// WETH sorts first in this pool
+	onCall(t, mockClient, testPoolAddress, "token0", common.HexToAddress(testWETHAddress))
+	onCall(t, mockClient, testPoolAddress, "token1", common.HexToAddress(testUSDCAddress))
+
+	registry := NewTokenRegistry(mockLogger, mockTokenRepo, mockRedisHelper)
+
+	// the tokens are swapped in the config, the pool is not used with the configured tokens either
+	_, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{
+		Address: testPoolAddress,
+		Token0:  config.TokenConfig{Address: testUSDCAddress, Symbol: "USDC", Decimals: 6},
+		Token1:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+	}, mockClient)
+
+	assert.ErrorIs(t, err, errPoolTokensMismatch)
+	mockTokenRepo.AssertNotCalled(t, "CreatePool", mock.Anything)
+	mockRedisHelper.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+
+	tokens, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{
+		Address: testPoolAddress,
+		Token0:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+		Token1:  config.TokenConfig{Address: testUSDCAddress, Symbol: "USDC", Decimals: 6},
+	}, mockClient)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testWETHAddress, tokens.Token0.Address)
+	mockTokenRepo.AssertNumberOfCalls(t, "CreatePool", 1)
+}
+
+func TestPoolTokensSeedWithoutDecimalsResolvedOnChain(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+	mockLogger := new(mocks.MockLogger)
+	mockClient := new(mocks.MockEthereumClient)
+
+	mockLogger.On("Info", mock.Anything).Return()
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return("", fmt.Errorf("redis: nil"))
+	mockRedisHelper.On("Set", testPoolTokensKey(), mock.Anything, poolTokensCacheTTL).Return(nil)
+	mockTokenRepo.On("FindPool", config.MainnetChainID, mock.Anything).Return((*entities.Pool)(nil), fmt.Errorf("pool not found"))
+	mockTokenRepo.On("FindToken", config.MainnetChainID, mock.Anything).Return((*entities.Token)(nil), fmt.Errorf("token not found"))
+	mockTokenRepo.On("CreateToken", mock.Anything).Return(nil)
+	mockTokenRepo.On("CreatePool", mock.Anything).Return(nil)
+
+	onCall(t, mockClient, testPoolAddress, "token0", common.HexToAddress(testUSDCAddress))
+	onCall(t, mockClient, testPoolAddress, "token1", common.HexToAddress(testWETHAddress))
+	onCall(t, mockClient, testUSDCAddress, "decimals", uint8(6))
+	onCall(t, mockClient, testUSDCAddress, "symbol", "USDC")
+
+	registry := NewTokenRegistry(mockLogger, mockTokenRepo, mockRedisHelper)
+
+	// USDC is configured without its decimals, so it is not taken as 0 decimals but called on chain
+	tokens, err := registry.PoolTokens(config.MainnetChainID, config.PoolConfig{
+		Address: testPoolAddress,
+		Token0:  config.TokenConfig{Address: testUSDCAddress, Symbol: "USDC"},
+		Token1:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+	}, mockClient)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), tokens.Token0.Decimals)
+	assert.Equal(t, int64(18), tokens.Token1.Decimals)
+	mockClient.AssertNotCalled(t, "CallContract", mock.Anything, mock.MatchedBy(func(call ethereum.CallMsg) bool {
+		return *call.To == common.HexToAddress(testWETHAddress)
+	}), mock.Anything)
+
+	// without a client the pool is not used with 0 decimals either
+	_, err = registry.PoolTokens(config.MainnetChainID, config.PoolConfig{
+		Address: testPoolAddress,
+		Token0:  config.TokenConfig{Address: testUSDCAddress, Symbol: "USDC"},
+		Token1:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+	}, nil)
+
+	assert.Error(t, err)
+}
+
+func TestPoolTokensFallsBackToConfiguredTokens(t *testing.T) {
+	mockRedisHelper := new(mocks.MockRedisHelper)
+	mockTokenRepo := new(mocks.MockTokenRepository)
+	mockLogger := new(mocks.MockLogger)
+
+	mockLogger.On("Warn", mock.Anything).Return()
+	mockRedisHelper.On("Get", testPoolTokensKey()).Return("", fmt.Errorf("redis: nil"))
+	mockTokenRepo.On("FindPool", config.MainnetChainID, mock.Anything).Return((*entities.Pool)(nil), fmt.Errorf("pool not found"))
+
+	registry := NewTokenRegistry(mockLogger, mockTokenRepo, mockRedisHelper)
+
+	// symbols and decimals without addresses are used as they are, without registering them
+	tokens, err := registry.PoolTokens(config.MainnetChainID, newTestPools()[0], nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USDC", tokens.Token0.Symbol)
+	assert.Equal(t, int64(18), tokens.Token1.Decimals)
+	mockLogger.AssertNumberOfCalls(t, "Warn", 1)
+	mockRedisHelper.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+	mockTokenRepo.AssertNotCalled(t, "CreatePool", mock.Anything)
+
+	// nothing to fall back to
+	_, err = registry.PoolTokens(config.MainnetChainID, config.PoolConfig{Address: testPoolAddress}, nil)
+
+	assert.Error(t, err)
+}
+
+func TestResolvePools(t *testing.T) {
+	mockLogger := new(mocks.MockLogger)
+	mockLogger.On("Info", mock.Anything).Return()
+
+	registry := NewTokenRegistry(mockLogger, &memoryTokenRepository{}, newMemoryRedisHelper())
+
+	chain := config.ChainConfig{ID: config.MainnetChainID, Name: "ethereum", Pools: []config.PoolConfig{
+		{
+			Address: testPoolAddress,
+			Token0:  config.TokenConfig{Address: testWETHAddress, Symbol: "WETH", Decimals: 18},
+			Token1:  config.TokenConfig{Address: testUSDCAddress, Symbol: "USDC", Decimals: 6},
+			Quote:   "usdc",
+		},
+	}}
+
+	e := NewEthereumService(mockLogger, &config.Config{Chains: []config.ChainConfig{chain}}, nil, nil, nil, nil, registry).(*EthereumService)
+
+	assert.NoError(t, e.chains[0].resolvePools(nil))
+
+	// the quote is matched by symbol, the loaded config is left as it is
+	pool := e.chains[0].chain.Pools[0]
+	assert.Equal(t, quoteToken1, pool.Quote)
+	assert.Equal(t, int64(6), pool.Token1.Decimals)
+	assert.Equal(t, "usdc", chain.Pools[0].Quote)
+
+	// a quote that is neither token
+	chain.Pools[0].Quote = "DAI"
+	e = NewEthereumService(mockLogger, &config.Config{Chains: []config.ChainConfig{chain}}, nil, nil, nil, nil, registry).(*EthereumService)
+
+	assert.EqualError(t, e.chains[0].resolvePools(nil), "pool "+testPoolAddress+" on chain ethereum: quote DAI is neither token of the pool (WETH/USDC)")
+}
+
+func TestQuoteSide(t *testing.T) {
+	tokens := &models.PoolTokens{
+		Token0: &entities.Token{Address: testUSDCAddress, Symbol: "USDC"},
+		Token1: &entities.Token{Address: testWETHAddress, Symbol: "WETH"},
+	}
+
+	tests := []struct {
+		quote    string
+		expected string
+	}{
+		{"", ""},
+		{quoteToken1, quoteToken1},
+		{"USDC", quoteToken0},
+		{strings.ToLower(testWETHAddress), quoteToken1},
+	}
+
+	for _, tt := range tests {
+		side, err := quoteSide(tt.quote, tokens)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, side)
+	}
+
+	_, err := quoteSide(common.BigToAddress(big.NewInt(1)).Hex(), tokens)
+	assert.Error(t, err)
+}